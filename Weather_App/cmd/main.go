@@ -1,114 +1,262 @@
-package main
-
-import (
-	"bufio"
-	"fmt"
-	"log"
-	"os"
-	"strings"
-
-	"github.com/joho/godotenv"
-
-	"weatherapp/internal/auth"
-	"weatherapp/internal/config"
-	"weatherapp/internal/storage"
-	"weatherapp/internal/user"
-	"weatherapp/internal/weather"
-)
-
-func init() {
-
-	if err := godotenv.Load(); err != nil {
-		if err := godotenv.Load("../.env"); err != nil {
-			log.Println("No .env found; relying on environment variables")
-		}
-	}
-}
-
-func main() {
-	// Load app config
-	cfg, err := config.Load("../config/config.json")
-	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
-	}
-
-	// Initialize the chosen weather provider
-	switch strings.ToLower(cfg.WeatherProvider) {
-	case "accuweather":
-		weather.InitProvider(weather.NewAccuWeatherProvider())
-	default:
-		weather.InitProvider(weather.NewWeatherstackProvider())
-	}
-
-	// Initialize Firestore
-	storage.InitFirestore()
-
-	reader := bufio.NewReader(os.Stdin)
-	for {
-		fmt.Println("\n=== Weather CLI App ===")
-		fmt.Println("1. Register")
-		fmt.Println("2. Login")
-		fmt.Println("3. Exit")
-		fmt.Print("Enter choice: ")
-		choice, _ := reader.ReadString('\n')
-		choice = strings.TrimSpace(choice)
-
-		switch choice {
-		case "1":
-			auth.Register(reader)
-
-		case "2":
-			userID := auth.Login(reader)
-			if userID != "" {
-				user.EnsurePreferences(reader, userID)
-				dashboard(reader, userID)
-			}
-
-		case "3":
-			fmt.Println("Exiting...")
-			return
-
-		default:
-			fmt.Println("Invalid option")
-		}
-	}
-}
-
-func dashboard(reader *bufio.Reader, userID string) {
-	for {
-		fmt.Println("\n=== Dashboard ===")
-		fmt.Println("1. View My Weather")
-		fmt.Println("2. Change Preferences")
-		fmt.Println("3. View Other Locations")
-		fmt.Println("4. List Users")
-		fmt.Println("5. Logout")
-		fmt.Print("Enter choice: ")
-		choice, _ := reader.ReadString('\n')
-		choice = strings.TrimSpace(choice)
-
-		switch choice {
-		case "1":
-			u, err := storage.GetUserByID(userID)
-			if err != nil {
-				fmt.Println("Error fetching user:", err)
-				continue
-			}
-			weather.ShowWeather(*u)
-
-		case "2":
-			user.ChangePreferences(reader, userID)
-
-		case "3":
-			weather.ShowOtherLocations(reader)
-
-		case "4":
-			user.ListUsers()
-
-		case "5":
-			return
-
-		default:
-			fmt.Println("Invalid choice")
-		}
-	}
-}
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"go.opentelemetry.io/otel"
+
+	"weatherapp/internal/auth"
+	"weatherapp/internal/cli"
+	"weatherapp/internal/config"
+	"weatherapp/internal/i18n"
+	"weatherapp/internal/logging"
+	"weatherapp/internal/storage"
+	"weatherapp/internal/tracing"
+	"weatherapp/internal/tui"
+	"weatherapp/internal/user"
+	"weatherapp/internal/weather"
+)
+
+func main() {
+	envErr := godotenv.Load()
+	if envErr != nil {
+		envErr = godotenv.Load("../.env")
+	}
+	if err := logging.Setup(os.Getenv, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(cli.ExitUsage)
+	}
+	if envErr != nil {
+		slog.Info("no .env found; relying on environment variables")
+	}
+	i18n.UseEnv()
+
+	// Load the layered config: defaults, file, environment, then --set
+	global, args, err := cli.ParseGlobalFlags(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(cli.ExitUsage)
+	}
+	cfgOpts := config.Options{File: global.Config, Getenv: os.Getenv, Overrides: global.Set}
+	cfg, cfgFile, err := config.Load(cfgOpts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(cli.ExitUsage)
+	}
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Exporter, os.Stderr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(cli.ExitUsage)
+	}
+	stopTracing = func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Warn("flushing traces", "error", err)
+		}
+	}
+	defer stopTracing()
+
+	// Initialize the chosen weather provider and a matching geocoder
+	if err := weather.Configure(cfg); err != nil {
+		fatal("creating weather provider", err)
+	}
+	initStorage := func() error { return storage.InitFirestore(cfg.Storage.ProjectID) }
+
+	// Run a subcommand non-interactively when one is given
+	if len(args) > 0 {
+		app := &cli.App{
+			Stdin:         os.Stdin,
+			Stdout:        os.Stdout,
+			Stderr:        os.Stderr,
+			Getenv:        os.Getenv,
+			Config:        cfg,
+			ConfigFile:    cfgFile,
+			ConfigOptions: cfgOpts,
+			InitStorage:   initStorage,
+		}
+		exit(app.Run(args))
+	}
+
+	// Initialize Firestore
+	if err := initStorage(); err != nil {
+		fatal("initializing storage", err)
+	}
+
+	// Use the full-screen UI on a terminal unless --plain or WEATHER_PLAIN
+	// asks for the numbered menus. The UI can also hand over to the menus,
+	// for the actions it lacks, with the user still logged in.
+	reader := bufio.NewReader(os.Stdin)
+	if tui.Available(os.Stdin, os.Stdout) && !global.Plain && os.Getenv("WEATHER_PLAIN") == "" {
+		// Log lines would tear the full-screen UI, so they only go to
+		// WEATHER_LOG_FILE while it runs.
+		if err := logging.Setup(os.Getenv, io.Discard); err != nil {
+			fatal("setting up logging", err)
+		}
+		userID, err := tui.Run(cfg.RefreshInterval.Duration)
+		if err != nil {
+			fatal("running terminal UI", err)
+		}
+		if userID == "" {
+			return
+		}
+		if err := logging.Setup(os.Getenv, os.Stderr); err != nil {
+			fatal("setting up logging", err)
+		}
+		useLanguage(userID)
+		dashboard(reader, userID)
+		i18n.UseEnv()
+	}
+
+	for {
+		fmt.Println()
+		i18n.Println("=== Weather CLI App ===")
+		i18n.Println("1. Register")
+		i18n.Println("2. Login")
+		i18n.Println("3. Exit")
+		i18n.Printf("Enter choice: ")
+		choice, ok := readChoice(reader)
+		if !ok {
+			return
+		}
+
+		switch choice {
+		case "1":
+			auth.Register(reader)
+
+		case "2":
+			userID := auth.Login(reader)
+			if userID != "" {
+				user.EnsurePreferences(reader, userID)
+				useLanguage(userID)
+				dashboard(reader, userID)
+				i18n.UseEnv()
+			}
+
+		case "3":
+			i18n.Println("Exiting...")
+			return
+
+		default:
+			i18n.Println("Invalid option")
+		}
+	}
+}
+
+func dashboard(reader *bufio.Reader, userID string) {
+	for {
+		fmt.Println()
+		i18n.Println("=== Dashboard ===")
+		i18n.Println("1. View My Weather")
+		i18n.Println("2. Change Preferences")
+		i18n.Println("3. View Other Locations")
+		i18n.Println("4. List Users")
+		i18n.Println("5. Manage Alert Rules")
+		i18n.Println("6. Notification Settings")
+		i18n.Println("7. Saved Locations")
+		i18n.Println("8. Logout")
+		i18n.Printf("Enter choice: ")
+		choice, ok := readChoice(reader)
+		if !ok {
+			return
+		}
+
+		switch choice {
+		case "1":
+			viewWeather(userID)
+
+		case "2":
+			user.ChangePreferences(reader, userID)
+			useLanguage(userID)
+
+		case "3":
+			ctx := logging.Start(context.Background(), "view other location")
+			u, err := storage.GetUserByID(ctx, userID)
+			if err != nil {
+				i18n.Println("Error fetching user: %v", err)
+				continue
+			}
+			weather.ShowOtherLocations(ctx, reader, u.Preferences)
+
+		case "4":
+			user.ListUsers()
+
+		case "5":
+			user.ManageRules(reader, userID)
+
+		case "6":
+			user.ChangeNotifications(reader, userID)
+
+		case "7":
+			user.ManageLocations(reader, userID)
+
+		case "8":
+			return
+
+		default:
+			i18n.Println("Invalid choice")
+		}
+	}
+}
+
+// viewWeather shows the weather at the user's preferred location, traced
+// as one operation from loading the user to the provider's responses.
+func viewWeather(userID string) {
+	ctx, span := otel.Tracer("weatherapp/cmd").Start(logging.Start(context.Background(), "view weather"), "view weather")
+	defer span.End()
+	u, err := storage.GetUserByID(ctx, userID)
+	if err != nil {
+		i18n.Println("Error fetching user: %v", err)
+		return
+	}
+	weather.ShowWeather(ctx, *u)
+}
+
+// readChoice reads a menu choice, reporting false once input has ended.
+func readChoice(reader *bufio.Reader) (string, bool) {
+	choice, err := reader.ReadString('\n')
+	if err != nil && choice == "" {
+		if !errors.Is(err, io.EOF) {
+			slog.Error("reading input", "error", err)
+		}
+		return "", false
+	}
+	return strings.TrimSpace(choice), true
+}
+
+// useLanguage switches the prompts to the User's Language preference, or
+// back to the environment's language if they have none.
+func useLanguage(userID string) {
+	i18n.UseEnv()
+	u, err := storage.GetUserByID(context.Background(), userID)
+	if err != nil {
+		slog.Warn("loading language preference", "user", userID, "error", err)
+		return
+	}
+	i18n.Use(u.Preferences.Language)
+}
+
+// stopTracing flushes buffered spans; it must run before the app exits.
+var stopTracing = func() {}
+
+// exit flushes buffered spans and ends the process with code. os.Exit
+// skips deferred calls, so every exit goes through here.
+func exit(code int) {
+	stopTracing()
+	os.Exit(code)
+}
+
+// fatal logs what failed and exits.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	exit(cli.ExitError)
+}
@@ -1,66 +1,157 @@
-package auth
-
-import (
-	"bufio"
-	"fmt"
-	"strings"
-	"weatherapp/internal/storage"
-	"weatherapp/models"
-
-	"golang.org/x/crypto/bcrypt"
-)
-
-// Register prompts for user details and saves a new User
-func Register(reader *bufio.Reader) {
-	fmt.Print("Enter UserID: ")
-	userID, _ := reader.ReadString('\n')
-	userID = strings.TrimSpace(userID)
-
-	fmt.Print("Enter Name (Username): ")
-	name, _ := reader.ReadString('\n')
-	name = strings.TrimSpace(name)
-
-	fmt.Print("Enter Password: ")
-	password, _ := reader.ReadString('\n')
-	password = strings.TrimSpace(password)
-	hash, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-
-	user := models.User{
-		UserID:      userID,
-		Name:        name,
-		Password:    string(hash),
-		Preferences: models.Preferences{},
-	}
-
-	err := storage.SaveUser(user)
-	if err != nil {
-		fmt.Println("Error saving user:", err)
-	} else {
-		fmt.Println("User registered successfully!")
-	}
-}
-
-// Login prompts for credentials, authenticates, and returns the UserID if successful
-func Login(reader *bufio.Reader) string {
-	fmt.Print("Enter Username: ")
-	username, _ := reader.ReadString('\n')
-	username = strings.TrimSpace(username)
-
-	fmt.Print("Enter Password: ")
-	password, _ := reader.ReadString('\n')
-	password = strings.TrimSpace(password)
-
-	users := storage.LoadUsers()
-	for _, u := range users {
-		if u.Name == username {
-			err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
-			if err == nil {
-				fmt.Println("Login successful!")
-				return u.UserID
-			}
-		}
-	}
-
-	fmt.Println("Invalid credentials")
-	return ""
-}
+package auth
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"weatherapp/internal/i18n"
+	"weatherapp/internal/metrics"
+	"weatherapp/internal/storage"
+	"weatherapp/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Register prompts for user details and saves a new User
+func Register(reader *bufio.Reader) {
+	i18n.Printf("Enter UserID: ")
+	userID, _ := reader.ReadString('\n')
+	userID = strings.TrimSpace(userID)
+
+	i18n.Printf("Enter Name (Username): ")
+	name, _ := reader.ReadString('\n')
+	name = strings.TrimSpace(name)
+
+	i18n.Printf("Enter Password: ")
+	password, _ := reader.ReadString('\n')
+	password = strings.TrimSpace(password)
+
+	if err := RegisterUser(context.Background(), userID, name, password); err != nil {
+		i18n.Println("Error saving user: %v", err)
+	} else {
+		i18n.Println("User registered successfully!")
+	}
+}
+
+// Login prompts for credentials, authenticates, and returns the UserID if successful
+func Login(reader *bufio.Reader) string {
+	i18n.Printf("Enter Username: ")
+	username, _ := reader.ReadString('\n')
+	username = strings.TrimSpace(username)
+
+	i18n.Printf("Enter Password: ")
+	password, _ := reader.ReadString('\n')
+	password = strings.TrimSpace(password)
+
+	u, err := Authenticate(context.Background(), username, password)
+	if errors.Is(err, ErrInvalidCredentials) {
+		i18n.Println("Invalid credentials")
+		return ""
+	}
+	if err != nil {
+		i18n.Println("Error: %v", err)
+		return ""
+	}
+	i18n.Println("Login successful!")
+	return u.UserID
+}
+
+// ErrInvalidCredentials is returned by Authenticate for an unknown user
+// name or a wrong password.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// ErrUserExists is returned by RegisterUser when the ID or name is taken.
+var ErrUserExists = storage.ErrUserExists
+
+// Authenticate returns the User with the given name and password. Every
+// attempt is counted in the login metrics.
+func Authenticate(ctx context.Context, username, password string) (models.User, error) {
+	u, err := authenticate(ctx, username, password)
+	switch {
+	case err == nil:
+		metrics.Login(metrics.LoginSuccess)
+	case errors.Is(err, ErrInvalidCredentials):
+		metrics.Login(metrics.LoginFailure)
+	default:
+		metrics.Login(metrics.LoginError)
+	}
+	return u, err
+}
+
+func authenticate(ctx context.Context, username, password string) (models.User, error) {
+	users, err := storage.LoadUsers(ctx)
+	if err != nil {
+		return models.User{}, err
+	}
+	for _, u := range users {
+		if u.Name == username {
+			if bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) == nil {
+				return u, nil
+			}
+		}
+	}
+	return models.User{}, ErrInvalidCredentials
+}
+
+// RegisterUser validates a new account with ValidateRegistration, then
+// creates the User with a hashed password and empty Preferences. It fails
+// with ErrUserExists if the ID or name, which is used to log in, is taken.
+func RegisterUser(ctx context.Context, userID, name, password string) error {
+	if err := ValidateRegistration(userID, name, password); err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return storage.CreateUser(ctx, models.User{
+		UserID:      userID,
+		Name:        name,
+		Password:    string(hash),
+		Preferences: models.Preferences{},
+	})
+}
+
+// userIDPattern restricts user IDs to characters safe in document paths.
+var userIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// MinPasswordLength is enforced for every new account.
+const MinPasswordLength = 8
+
+// ValidateRegistration checks a new account's user ID, name and password.
+func ValidateRegistration(userID, name, password string) error {
+	if err := ValidateUserID(userID); err != nil {
+		return err
+	}
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	return ValidatePassword(password)
+}
+
+// ValidateUserID checks a new user ID is safe to use as a document path.
+func ValidateUserID(userID string) error {
+	if !userIDPattern.MatchString(userID) {
+		return errors.New("user ID must be 1-64 letters, digits, '.', '_' or '-'")
+	}
+	return nil
+}
+
+// ValidateName checks a new user name is 1-64 characters.
+func ValidateName(name string) error {
+	if name == "" || len(name) > 64 {
+		return errors.New("name must be 1-64 characters")
+	}
+	return nil
+}
+
+// ValidatePassword checks a new password is long enough.
+func ValidatePassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+	return nil
+}
@@ -1,93 +1,130 @@
-package auth
-
-import (
-	"bufio"
-	"bytes"
-	"errors"
-	"testing"
-	"weatherapp/internal/storage"
-	"weatherapp/models"
-
-	"github.com/stretchr/testify/assert"
-	"golang.org/x/crypto/bcrypt"
-)
-
-// hash: Hashes the given password using bcrypt and returns the hashed password.
-func hash(password string) string {
-	hashed, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(hashed)
-}
-
-// TestLogin: Tests the login functionality for different scenarios like successful login, invalid password, and user not found.
-func TestLogin(t *testing.T) {
-	originalLoadUsers := storage.LoadUsers
-	defer func() { storage.LoadUsers = originalLoadUsers }()
-
-	// Mocking the LoadUsers function for testing.
-	storage.LoadUsers = func() []models.User {
-		return []models.User{
-			{UserID: "1", Name: "deepak", Password: hash("123")},
-		}
-	}
-
-	// Test case: Successful login.
-	t.Run("Successful login", func(t *testing.T) {
-		input := "deepak\n123\n"
-		reader := bufio.NewReader(bytes.NewBufferString(input))
-		userID := Login(reader)
-		assert.Equal(t, "1", userID)
-	})
-
-	// Test case: Invalid password.
-	t.Run("Invalid password", func(t *testing.T) {
-		input := "deepak\nwrong\n"
-		reader := bufio.NewReader(bytes.NewBufferString(input))
-		userID := Login(reader)
-		assert.Empty(t, userID)
-	})
-
-	// Test case: User not found.
-	t.Run("User not found", func(t *testing.T) {
-		input := "dev\n123\n"
-		reader := bufio.NewReader(bytes.NewBufferString(input))
-		userID := Login(reader)
-		assert.Empty(t, userID)
-	})
-}
-
-// TestRegister: Tests the registration functionality, including successful registration and error handling while saving the user.
-func TestRegister(t *testing.T) {
-	originalSaveUser := storage.SaveUser
-	defer func() { storage.SaveUser = originalSaveUser }()
-
-	// Test case: Successful registration.
-	t.Run("Successful registration", func(t *testing.T) {
-		input := "testid\nTest User\npassword123\n"
-		reader := bufio.NewReader(bytes.NewBufferString(input))
-
-		var savedUser models.User
-		// Mocking SaveUser function to test the registration flow.
-		storage.SaveUser = func(user models.User) error {
-			savedUser = user
-			return nil
-		}
-
-		Register(reader)
-		assert.Equal(t, "testid", savedUser.UserID)
-		assert.Equal(t, "Test User", savedUser.Name)
-		assert.NotEmpty(t, savedUser.Password)
-	})
-
-	// Test case: Error saving user due to database issues.
-	t.Run("SaveUser error", func(t *testing.T) {
-		input := "testid2\nUser2\npass2\n"
-		reader := bufio.NewReader(bytes.NewBufferString(input))
-
-		// Mocking SaveUser to simulate a database error.
-		storage.SaveUser = func(user models.User) error {
-			return errors.New("db error")
-		}
-
-		Register(reader)
-	})
-}
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"weatherapp/internal/storage"
+	"weatherapp/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// hash: Hashes the given password using bcrypt and returns the hashed password.
+func hash(password string) string {
+	hashed, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hashed)
+}
+
+// TestLogin: Tests the login functionality for different scenarios like successful login, invalid password, and user not found.
+func TestLogin(t *testing.T) {
+	originalLoadUsers := storage.LoadUsers
+	defer func() { storage.LoadUsers = originalLoadUsers }()
+
+	// Mocking the LoadUsers function for testing.
+	storage.LoadUsers = func(context.Context) ([]models.User, error) {
+		return []models.User{
+			{UserID: "1", Name: "deepak", Password: hash("123")},
+		}, nil
+	}
+
+	// Test case: Successful login.
+	t.Run("Successful login", func(t *testing.T) {
+		input := "deepak\n123\n"
+		reader := bufio.NewReader(bytes.NewBufferString(input))
+		userID := Login(reader)
+		assert.Equal(t, "1", userID)
+	})
+
+	// Test case: Invalid password.
+	t.Run("Invalid password", func(t *testing.T) {
+		input := "deepak\nwrong\n"
+		reader := bufio.NewReader(bytes.NewBufferString(input))
+		userID := Login(reader)
+		assert.Empty(t, userID)
+	})
+
+	// Test case: User not found.
+	t.Run("User not found", func(t *testing.T) {
+		input := "dev\n123\n"
+		reader := bufio.NewReader(bytes.NewBufferString(input))
+		userID := Login(reader)
+		assert.Empty(t, userID)
+	})
+}
+
+// TestRegister: Tests the registration functionality, including successful registration and error handling while saving the user.
+func TestRegister(t *testing.T) {
+	originalCreateUser := storage.CreateUser
+	defer func() { storage.CreateUser = originalCreateUser }()
+
+	// Test case: Successful registration.
+	t.Run("Successful registration", func(t *testing.T) {
+		input := "testid\nTest User\npassword123\n"
+		reader := bufio.NewReader(bytes.NewBufferString(input))
+
+		var savedUser models.User
+		// Mocking storage.CreateUser to test the registration flow.
+		storage.CreateUser = func(_ context.Context, user models.User) error {
+			savedUser = user
+			return nil
+		}
+
+		Register(reader)
+		assert.Equal(t, "testid", savedUser.UserID)
+		assert.Equal(t, "Test User", savedUser.Name)
+		assert.NotEmpty(t, savedUser.Password)
+	})
+
+	// Test case: Error saving user due to database issues.
+	t.Run("CreateUser error", func(t *testing.T) {
+		input := "testid2\nUser2\npassword234\n"
+		reader := bufio.NewReader(bytes.NewBufferString(input))
+
+		// Mocking storage.CreateUser to simulate a database error.
+		storage.CreateUser = func(_ context.Context, user models.User) error {
+			return errors.New("db error")
+		}
+
+		Register(reader)
+	})
+}
+
+// TestRegisterUser checks accounts are validated before they are stored
+// and that a taken ID or name is reported as ErrUserExists.
+func TestRegisterUser(t *testing.T) {
+	originalCreateUser := storage.CreateUser
+	defer func() { storage.CreateUser = originalCreateUser }()
+	var created []models.User
+	storage.CreateUser = func(_ context.Context, u models.User) error {
+		if len(created) > 0 {
+			return storage.ErrUserExists
+		}
+		created = append(created, u)
+		return nil
+	}
+
+	assert.Error(t, RegisterUser(context.Background(), "ann/smith", "Ann", "long-enough"))
+	assert.Error(t, RegisterUser(context.Background(), "ann", "Ann", "short"))
+	assert.Empty(t, created)
+	require.NoError(t, RegisterUser(context.Background(), "ann", "Ann", "long-enough"))
+	assert.ErrorIs(t, RegisterUser(context.Background(), "ann", "Ann", "long-enough"), ErrUserExists)
+	require.Len(t, created, 1)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(created[0].Password), []byte("long-enough")))
+}
+
+// TestValidateRegistration checks the user ID, name and password rules.
+func TestValidateRegistration(t *testing.T) {
+	assert.NoError(t, ValidateRegistration("ann.smith_1", "Ann", "long-enough"))
+	assert.Error(t, ValidateUserID(""))
+	assert.Error(t, ValidateUserID("ann/smith"))
+	assert.Error(t, ValidateName(""))
+	assert.Error(t, ValidateName(strings.Repeat("a", 65)))
+	assert.Error(t, ValidatePassword("short"))
+	assert.Error(t, ValidateRegistration("ann", "Ann", "short"))
+}
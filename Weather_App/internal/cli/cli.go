@@ -0,0 +1,228 @@
+// Package cli implements the non-interactive subcommands, e.g.
+// "weather current --location Paris --unit f". The interactive menu in
+// cmd/main.go is used when no subcommand is given.
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"weatherapp/internal/config"
+	"weatherapp/internal/storage"
+	"weatherapp/models"
+)
+
+// Exit codes returned by App.Run.
+const (
+	ExitOK    = 0
+	ExitError = 1
+	ExitUsage = 2
+)
+
+// Environment variables consulted when the matching flag is not given.
+const (
+	EnvUser     = "WEATHER_USER"
+	EnvLocation = "WEATHER_LOCATION"
+	EnvUnit     = "WEATHER_UNIT"
+	EnvOutput   = "WEATHER_OUTPUT"
+	EnvPassword = "WEATHER_PASSWORD"
+)
+
+// App runs subcommands against the given streams and environment.
+type App struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	Getenv func(string) string
+	// Config holds the loaded settings; the defaults are used when nil.
+	// ConfigFile is the file it was read from, if any, and ConfigOptions
+	// the options it was loaded with, which serve and serve-digest reuse
+	// to reload it.
+	Config        *config.Config
+	ConfigFile    string
+	ConfigOptions config.Options
+	// InitStorage connects the user store. It is called by commands that
+	// read or write users, until it succeeds.
+	InitStorage func() error
+
+	storageReady bool
+	// reloader keeps the config current once serve or serve-digest
+	// watches it.
+	reloader *config.Reloader
+}
+
+// command is one subcommand; name may be two words, e.g. "weather current".
+type command struct {
+	name    string
+	summary string
+	run     func(a *App, args []string) int
+}
+
+var commands = []command{
+	{"weather current", "Show current conditions", (*App).weatherCurrent},
+	{"weather forecast", "Show a multi-day forecast", (*App).weatherForecast},
+	{"user register", "Create a user", (*App).userRegister},
+	{"user list", "List users", (*App).userList},
+	{"prefs show", "Show a user's preferences", (*App).prefsShow},
+	{"prefs set", "Change a user's preferences", (*App).prefsSet},
+	{"check-alerts", "Evaluate alert rules and notify users (for cron)", (*App).checkAlerts},
+	{"serve-digest", "Send daily digests until interrupted", (*App).serveDigest},
+	{"serve", "Serve the HTTP/JSON API until interrupted", (*App).serve},
+	{"config show", "Show the effective configuration, secrets redacted", (*App).configShow},
+}
+
+// Run executes the subcommand named by args and returns the exit code.
+func (a *App) Run(args []string) int {
+	if len(args) == 0 {
+		a.usage(a.Stderr, "")
+		return ExitUsage
+	}
+	switch args[0] {
+	case "-h", "-help", "--help", "help":
+		a.usage(a.Stdout, "")
+		return ExitOK
+	}
+	for _, c := range commands {
+		words := strings.Fields(c.name)
+		if len(args) >= len(words) && strings.Join(args[:len(words)], " ") == c.name {
+			return c.run(a, args[len(words):])
+		}
+	}
+	for _, c := range commands {
+		if strings.HasPrefix(c.name, args[0]+" ") {
+			// A command group without, or with an unknown, action.
+			if len(args) > 1 && !strings.HasPrefix(args[1], "-") {
+				fmt.Fprintf(a.Stderr, "Unknown command %q\n\n", args[0]+" "+args[1])
+			}
+			a.usage(a.Stderr, args[0])
+			return ExitUsage
+		}
+	}
+	fmt.Fprintf(a.Stderr, "Unknown command %q\n\n", args[0])
+	a.usage(a.Stderr, "")
+	return ExitUsage
+}
+
+// usage lists the subcommands, or only those in group when it is set.
+func (a *App) usage(w io.Writer, group string) {
+	fmt.Fprintln(w, "Usage: weatherapp [--config FILE] [--set KEY=VALUE]... [--plain] [command [flags]]")
+	fmt.Fprintln(w, "\nRun without a command for the full-screen terminal UI, or the numbered menus")
+	fmt.Fprintln(w, "with --plain, WEATHER_PLAIN=1 or when not on a terminal. The menus also manage")
+	fmt.Fprintln(w, "alert rules, notifications, saved locations and other locations; press m in")
+	fmt.Fprintln(w, "the terminal UI to switch to them. Settings come from the")
+	fmt.Fprintln(w, "defaults, then the config file (--config, "+config.EnvFile+" or weatherapp/config.yaml,")
+	fmt.Fprintln(w, ".toml or .json in the XDG config directories), then the environment, then --set.")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, c := range commands {
+		if group == "" || strings.HasPrefix(c.name, group+" ") {
+			fmt.Fprintf(w, "  %-18s %s\n", c.name, c.summary)
+		}
+	}
+	fmt.Fprintln(w, "\nRun \"weatherapp <command> --help\" for a command's flags.")
+}
+
+// flags creates the FlagSet for a command with a usage message naming it.
+func (a *App) flags(name, summary string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: weatherapp %s [flags]\n\n%s.\n\nFlags:\n", name, summary)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses args, reporting ok=false with the exit code to return when
+// parsing failed or --help was asked for.
+func (a *App) parse(fs *flag.FlagSet, args []string) (code int, ok bool) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK, false
+		}
+		return ExitUsage, false
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(a.Stderr, "%s: unexpected argument %q\n", fs.Name(), fs.Arg(0))
+		fs.Usage()
+		return ExitUsage, false
+	}
+	return ExitOK, true
+}
+
+// usageError reports a problem with a command's flags.
+func (a *App) usageError(fs *flag.FlagSet, format string, args ...any) int {
+	fmt.Fprintf(a.Stderr, "%s: %s\n", fs.Name(), fmt.Sprintf(format, args...))
+	return ExitUsage
+}
+
+// fail reports a runtime error for a command.
+func (a *App) fail(name string, err error) int {
+	fmt.Fprintf(a.Stderr, "%s: %v\n", name, err)
+	return ExitError
+}
+
+// config returns the settings in effect: the latest reload once the config
+// is watched, else the loaded settings or the defaults.
+func (a *App) config() *config.Config {
+	if a.reloader != nil {
+		return a.reloader.Current()
+	}
+	if a.Config == nil {
+		d := config.Defaults()
+		a.Config = &d
+	}
+	return a.Config
+}
+
+func (a *App) env(key string) string {
+	if a.Getenv == nil {
+		return ""
+	}
+	return a.Getenv(key)
+}
+
+// firstNonEmpty returns the first of values that is not empty.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func (a *App) initStorage() error {
+	if !a.storageReady && a.InitStorage != nil {
+		if err := a.InitStorage(); err != nil {
+			return err
+		}
+	}
+	a.storageReady = true
+	return nil
+}
+
+// loadUsers returns every User, connecting the store on first use.
+func (a *App) loadUsers(ctx context.Context) ([]models.User, error) {
+	if err := a.initStorage(); err != nil {
+		return nil, err
+	}
+	return storage.LoadUsers(ctx)
+}
+
+// findUser returns the User with userID.
+func (a *App) findUser(ctx context.Context, userID string) (models.User, error) {
+	users, err := a.loadUsers(ctx)
+	if err != nil {
+		return models.User{}, err
+	}
+	for _, u := range users {
+		if u.UserID == userID {
+			return u, nil
+		}
+	}
+	return models.User{}, fmt.Errorf("user %q not found", userID)
+}
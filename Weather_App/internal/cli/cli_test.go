@@ -0,0 +1,363 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"weatherapp/internal/config"
+	"weatherapp/internal/geo"
+	"weatherapp/internal/storage"
+	"weatherapp/internal/weather"
+	"weatherapp/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeProvider returns fixed data and records what it was asked for.
+type fakeProvider struct {
+	location geo.Location
+	days     int
+}
+
+func (f *fakeProvider) Current(ctx context.Context, loc geo.Location) (*weather.WeatherData, error) {
+	f.location = loc
+	return &weather.WeatherData{Description: "Sunny", Temperature: 20, WindSpeed: 16.09344}, nil
+}
+
+func (f *fakeProvider) Forecast(ctx context.Context, loc geo.Location, days int) ([]weather.WeatherData, error) {
+	f.location, f.days = loc, days
+	out := make([]weather.WeatherData, days)
+	for i := range out {
+		out[i] = weather.WeatherData{Description: "Cloudy", Temperature: 10}
+	}
+	return out, nil
+}
+
+// osloGeocoder finds Oslo and nothing else.
+type osloGeocoder struct{}
+
+func (osloGeocoder) Geocode(ctx context.Context, q string) ([]geo.Place, error) {
+	if q != "Oslo" {
+		return nil, nil
+	}
+	return []geo.Place{{Name: "Oslo", Country: "Norway", Lat: 59.91, Lon: 10.75}}, nil
+}
+
+// testApp wires an App to buffers, a fixed environment and in-memory users.
+type testApp struct {
+	*App
+	stdout, stderr *bytes.Buffer
+	provider       *fakeProvider
+	users          []models.User
+}
+
+func newTestApp(t *testing.T, env map[string]string, users ...models.User) *testApp {
+	ta := &testApp{stdout: &bytes.Buffer{}, stderr: &bytes.Buffer{}, provider: &fakeProvider{}, users: users}
+	ta.App = &App{
+		Stdin:  strings.NewReader(""),
+		Stdout: ta.stdout,
+		Stderr: ta.stderr,
+		Getenv: func(k string) string { return env[k] },
+	}
+
+	weather.InitProvider(ta.provider)
+	origLoad, origSave, origUpdate := storage.LoadUsers, storage.CreateUser, storage.UpdateUser
+	t.Cleanup(func() { storage.LoadUsers, storage.CreateUser, storage.UpdateUser = origLoad, origSave, origUpdate })
+	storage.LoadUsers = func(context.Context) ([]models.User, error) { return ta.users, nil }
+	storage.CreateUser = func(_ context.Context, u models.User) error {
+		for _, existing := range ta.users {
+			if existing.UserID == u.UserID || existing.Name == u.Name {
+				return storage.ErrUserExists
+			}
+		}
+		ta.users = append(ta.users, u)
+		return nil
+	}
+	storage.UpdateUser = func(_ context.Context, u models.User) error {
+		for i := range ta.users {
+			if ta.users[i].UserID == u.UserID {
+				ta.users[i] = u
+			}
+		}
+		return nil
+	}
+	return ta
+}
+
+// TestWeatherCurrent checks flags, unit shorthands and output formats.
+func TestWeatherCurrent(t *testing.T) {
+	a := newTestApp(t, nil)
+	code := a.Run([]string{"weather", "current", "--location", "Paris", "--unit", "f", "--output", "json"})
+	require.Equal(t, ExitOK, code, a.stderr.String())
+	assert.Equal(t, "Paris", a.provider.location.Query)
+
+	var doc struct {
+		Units struct{ Temperature, Speed string }
+		Data  []struct {
+			Description string
+			Temperature float64
+			WindSpeed   float64 `json:"wind_speed"`
+		}
+	}
+	require.NoError(t, json.Unmarshal(a.stdout.Bytes(), &doc))
+	assert.Equal(t, "fahrenheit", doc.Units.Temperature)
+	require.Len(t, doc.Data, 1)
+	assert.Equal(t, 68.0, doc.Data[0].Temperature)
+	assert.Equal(t, 10.0, doc.Data[0].WindSpeed)
+}
+
+// TestWeatherCurrent_Precedence checks flags beat user preferences, which
+// beat the environment.
+func TestWeatherCurrent_Precedence(t *testing.T) {
+	env := map[string]string{EnvUser: "u1", EnvLocation: "Oslo", EnvUnit: "si", EnvOutput: "json"}
+	u := models.User{UserID: "u1", Preferences: models.Preferences{Location: "Pune", Unit: "imperial", Output: "text"}}
+
+	a := newTestApp(t, env, u)
+	require.Equal(t, ExitOK, a.Run([]string{"weather", "current"}), a.stderr.String())
+	assert.Equal(t, "Pune", a.provider.location.Query)
+	assert.Contains(t, a.stdout.String(), "Temperature : 68°F")
+
+	a = newTestApp(t, env, u)
+	require.Equal(t, ExitOK, a.Run([]string{"weather", "current", "--location", "Lima", "--unit", "uk"}), a.stderr.String())
+	assert.Equal(t, "Lima", a.provider.location.Query)
+	assert.Contains(t, a.stdout.String(), "Temperature : 20°C")
+
+	delete(env, EnvUser)
+	a = newTestApp(t, env)
+	require.Equal(t, ExitOK, a.Run([]string{"weather", "current"}), a.stderr.String())
+	assert.Equal(t, "Oslo", a.provider.location.Query)
+	assert.Contains(t, a.stdout.String(), `"temperature": "kelvin"`)
+}
+
+// TestWeatherForecast checks --days reaches the provider and is validated.
+func TestWeatherForecast(t *testing.T) {
+	a := newTestApp(t, nil)
+	require.Equal(t, ExitOK, a.Run([]string{"weather", "forecast", "--location", "Rome", "--days", "5"}), a.stderr.String())
+	assert.Equal(t, 5, a.provider.days)
+	assert.Contains(t, a.stdout.String(), "Forecast for Rome (5 days)")
+	assert.Equal(t, 5, strings.Count(a.stdout.String(), "Cloudy"))
+
+	a = newTestApp(t, nil)
+	assert.Equal(t, ExitUsage, a.Run([]string{"weather", "forecast", "--location", "Rome", "--days", "90"}))
+	assert.Contains(t, a.stderr.String(), "--days must be between 1 and 30")
+}
+
+// TestRun_Usage covers help, unknown commands and bad flags.
+func TestRun_Usage(t *testing.T) {
+	a := newTestApp(t, nil)
+	assert.Equal(t, ExitOK, a.Run([]string{"--help"}))
+	assert.Contains(t, a.stdout.String(), "prefs set")
+
+	a = newTestApp(t, nil)
+	assert.Equal(t, ExitOK, a.Run([]string{"weather", "current", "--help"}))
+	assert.Contains(t, a.stderr.String(), "Usage: weatherapp weather current [flags]")
+
+	cases := map[string][]string{
+		"unknown command":  {"frobnicate"},
+		"unknown action":   {"user", "delete"},
+		"missing action":   {"prefs"},
+		"unknown flag":     {"weather", "current", "--colour"},
+		"missing location": {"weather", "current"},
+		"extra argument":   {"user", "list", "everyone"},
+		"bad unit":         {"weather", "current", "--location", "x", "--unit", "furlongs"},
+		"bad output":       {"weather", "current", "--location", "x", "--output", "xml"},
+	}
+	for name, args := range cases {
+		a := newTestApp(t, nil)
+		assert.Equal(t, ExitUsage, a.Run(args), name)
+		assert.NotEmpty(t, a.stderr.String(), name)
+	}
+
+	a = newTestApp(t, nil)
+	assert.Equal(t, ExitError, a.Run([]string{"weather", "current", "--user", "ghost"}))
+	assert.Contains(t, a.stderr.String(), `user "ghost" not found`)
+}
+
+// TestUserRegisterAndList covers password sources, duplicates and listing.
+func TestUserRegisterAndList(t *testing.T) {
+	a := newTestApp(t, map[string]string{EnvPassword: "s3cret-pass"})
+	require.Equal(t, ExitOK, a.Run([]string{"user", "register", "--id", "u1", "--name", "asha"}), a.stderr.String())
+	require.Len(t, a.users, 1)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(a.users[0].Password), []byte("s3cret-pass")))
+
+	a.Stdin = strings.NewReader("hunter2-pass\n")
+	require.Equal(t, ExitOK, a.Run([]string{"user", "register", "--id", "u2", "--name", "ben", "--password-stdin"}), a.stderr.String())
+	require.Len(t, a.users, 2)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(a.users[1].Password), []byte("hunter2-pass")))
+
+	assert.Equal(t, ExitError, a.Run([]string{"user", "register", "--id", "u1", "--name", "again"}))
+	assert.Contains(t, a.stderr.String(), "user already exists")
+	assert.Equal(t, ExitError, a.Run([]string{"user", "register", "--id", "u/3", "--name", "cy"}))
+	assert.Contains(t, a.stderr.String(), "user ID must be")
+
+	a.stdout.Reset()
+	require.Equal(t, ExitOK, a.Run([]string{"user", "list", "--output", "json"}))
+	var docs []userDoc
+	require.NoError(t, json.Unmarshal(a.stdout.Bytes(), &docs))
+	assert.Equal(t, []userDoc{{"u1", "asha"}, {"u2", "ben"}}, docs)
+	assert.NotContains(t, a.stdout.String(), "$2a$")
+
+	b := newTestApp(t, nil)
+	assert.Equal(t, ExitUsage, b.Run([]string{"user", "register", "--id", "u3", "--name", "cy"}))
+	assert.Contains(t, b.stderr.String(), "no password")
+}
+
+// TestPrefsSet checks only the given flags change and bad values are rejected.
+func TestPrefsSet(t *testing.T) {
+	u := models.User{UserID: "u1", Preferences: models.Preferences{
+		Location: "Paris, Texas, United States", Coords: &models.Coordinates{Lat: 33.66, Lon: -95.56},
+		Unit: "metric", Verbosity: "brief", Forecast: "day",
+	}}
+	a := newTestApp(t, map[string]string{EnvUser: "u1"}, u)
+
+	require.Equal(t, ExitOK, a.Run([]string{"prefs", "set", "--unit", "uk,speed=kn", "--forecast", "week"}), a.stderr.String())
+	p := a.users[0].Preferences
+	assert.Equal(t, "uk", p.Unit)
+	assert.Equal(t, map[string]string{"speed": "kn"}, p.UnitOverrides)
+	assert.Equal(t, "week", p.Forecast)
+	assert.Equal(t, "brief", p.Verbosity)
+	assert.NotNil(t, p.Coords)
+
+	require.Equal(t, ExitOK, a.Run([]string{"prefs", "set", "--location", "Leeds", "--language", "de-AT"}), a.stderr.String())
+	assert.Equal(t, "Leeds", a.users[0].Preferences.Location)
+	assert.Nil(t, a.users[0].Preferences.Coords)
+	assert.Equal(t, "de", a.users[0].Preferences.Language)
+
+	a.stdout.Reset()
+	require.Equal(t, ExitOK, a.Run([]string{"prefs", "show"}))
+	assert.Contains(t, a.stdout.String(), "Units     : uk, speed=kn")
+	assert.Contains(t, a.stdout.String(), "Language  : de")
+
+	for _, args := range [][]string{
+		{"prefs", "set"},
+		{"prefs", "set", "--verbosity", "loud"},
+		{"prefs", "set", "--forecast", "year"},
+		{"prefs", "set", "--output", "xml"},
+		{"prefs", "set", "--location", " "},
+		{"prefs", "set", "--language", "tlh"},
+	} {
+		assert.Equal(t, ExitUsage, a.Run(args), args)
+	}
+	assert.Equal(t, "week", a.users[0].Preferences.Forecast)
+
+	origGeocoder := geo.Active()
+	t.Cleanup(func() { geo.InitGeocoder(origGeocoder) })
+	geo.InitGeocoder(osloGeocoder{})
+	require.Equal(t, ExitOK, a.Run([]string{"prefs", "set", "--location", "Oslo"}), a.stderr.String())
+	assert.Equal(t, "Oslo, Norway", a.users[0].Preferences.Location)
+	assert.Equal(t, &models.Coordinates{Lat: 59.91, Lon: 10.75}, a.users[0].Preferences.Coords)
+}
+
+// TestConfigShow checks the effective settings are shown in each format
+// with secrets redacted.
+func TestConfigShow(t *testing.T) {
+	a := newTestApp(t, nil)
+	cfg := config.Defaults()
+	cfg.Server.TokenSecret = "hunter2"
+	cfg.Server.Addr = ":8081"
+	cfg.Notify.SMTPPassword = "hunter2"
+	a.Config, a.ConfigFile = &cfg, "/etc/xdg/weatherapp/config.yaml"
+
+	require.Equal(t, ExitOK, a.Run([]string{"config", "show"}), a.stderr.String())
+	out := a.stdout.String()
+	assert.Contains(t, out, "# from /etc/xdg/weatherapp/config.yaml")
+	assert.Contains(t, out, `addr: :8081`)
+	assert.Contains(t, out, "token_secret: '[redacted]'")
+	assert.Contains(t, out, "smtp_password: '[redacted]'")
+	assert.NotContains(t, out, "hunter2")
+
+	for _, format := range []string{"json", "toml"} {
+		a.stdout.Reset()
+		require.Equal(t, ExitOK, a.Run([]string{"config", "show", "--output", format}), format)
+		assert.Contains(t, a.stdout.String(), config.Redacted, format)
+		assert.NotContains(t, a.stdout.String(), "hunter2", format)
+	}
+	assert.Equal(t, ExitUsage, a.Run([]string{"config", "show", "--output", "ini"}))
+}
+
+// TestCheckAlerts_StateFile checks check-alerts keeps its record of sent
+// alerts in notify.state_file.
+func TestCheckAlerts_StateFile(t *testing.T) {
+	a := newTestApp(t, nil)
+	cfg := config.Defaults()
+	cfg.Notify.StateFile = filepath.Join(t.TempDir(), "sent.json")
+	a.Config = &cfg
+	require.Equal(t, ExitOK, a.Run([]string{"check-alerts"}), a.stderr.String())
+
+	require.NoError(t, os.WriteFile(cfg.Notify.StateFile, []byte("not json"), 0o644))
+	assert.Equal(t, ExitError, a.Run([]string{"check-alerts"}))
+	assert.Contains(t, a.stderr.String(), "loading sent alerts")
+}
+
+// TestParseGlobalFlags checks --config and --set are taken off the front
+// of the arguments and the command's own flags are left alone.
+func TestParseGlobalFlags(t *testing.T) {
+	g, rest, err := ParseGlobalFlags([]string{"--config", "a.toml", "-set=server.addr=:1", "--set", "cache.location_size=5", "serve", "--addr", ":2"})
+	require.NoError(t, err)
+	assert.Equal(t, GlobalFlags{Config: "a.toml", Set: []string{"server.addr=:1", "cache.location_size=5"}}, g)
+	assert.Equal(t, []string{"serve", "--addr", ":2"}, rest)
+
+	_, rest, err = ParseGlobalFlags([]string{"--help"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--help"}, rest)
+
+	g, rest, err = ParseGlobalFlags([]string{"--plain", "--config=a.toml"})
+	require.NoError(t, err)
+	assert.Equal(t, GlobalFlags{Config: "a.toml", Plain: true}, g)
+	assert.Empty(t, rest)
+
+	_, _, err = ParseGlobalFlags([]string{"--config"})
+	assert.Error(t, err)
+	_, _, err = ParseGlobalFlags([]string{"--plain=maybe"})
+	assert.Error(t, err)
+}
+
+// TestApplyConfig checks a reload swaps the provider only when a provider
+// setting changed.
+func TestApplyConfig(t *testing.T) {
+	a := newTestApp(t, nil)
+	origGeocoder := geo.Active()
+	t.Cleanup(func() { geo.InitGeocoder(origGeocoder) })
+	cfg := config.Defaults()
+
+	require.NoError(t, applyConfig(&cfg, []config.Change{{Key: "server.addr", Old: ":8080", New: ":8081"}}))
+	assert.Same(t, a.provider, weather.Provider())
+
+	cfg.WeatherProvider = "accuweather"
+	require.NoError(t, applyConfig(&cfg, []config.Change{{Key: "weather_provider", Old: "weatherstack", New: "accuweather"}}))
+	assert.IsType(t, &weather.AccuWeatherProvider{}, weather.Provider())
+	assert.Same(t, weather.Provider(), weather.Geocoder())
+
+	cfg.WeatherProvider = "weatherstack"
+	require.NoError(t, applyConfig(&cfg, []config.Change{{Key: "weather_provider", Old: "accuweather", New: "weatherstack"}}))
+	assert.IsType(t, &weather.WeatherstackProvider{}, weather.Provider())
+	assert.IsType(t, &geo.OpenMeteoGeocoder{}, weather.Geocoder())
+}
+
+// TestWatchConfig checks commands see the reloaded config once it is
+// watched.
+func TestWatchConfig(t *testing.T) {
+	a := newTestApp(t, nil)
+	origGeocoder := geo.Active()
+	t.Cleanup(func() { geo.InitGeocoder(origGeocoder) })
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("weather_provider: weatherstack\n"), 0o644))
+	cfg, file, err := config.Load(config.Options{File: path})
+	require.NoError(t, err)
+	a.Config, a.ConfigFile = cfg, file
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	a.watchConfig(ctx)
+	require.NoError(t, os.WriteFile(path, []byte("weather_provider: accuweather\n"), 0o644))
+	_, err = a.reloader.Reload()
+	require.NoError(t, err)
+	assert.Equal(t, "accuweather", a.config().WeatherProvider)
+}
@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"weatherapp/internal/config"
+	"weatherapp/internal/weather"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// GlobalFlags are given before the command: --config FILE names the config
+// file, each --set KEY=VALUE overrides one setting, and --plain uses the
+// numbered menus instead of the full-screen terminal UI.
+type GlobalFlags struct {
+	Config string
+	Set    []string
+	Plain  bool
+}
+
+// ParseGlobalFlags splits the global flags off the front of args, returning
+// the command and its arguments.
+func ParseGlobalFlags(args []string) (GlobalFlags, []string, error) {
+	var g GlobalFlags
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[0], "-"), "=")
+		if name == "plain" {
+			plain, err := strconv.ParseBool(firstNonEmpty(value, "true"))
+			if err != nil {
+				return g, nil, fmt.Errorf("flag --plain: %q is not true or false", value)
+			}
+			g.Plain, args = plain, args[1:]
+			continue
+		}
+		if name != "config" && name != "set" {
+			break
+		}
+		if !hasValue {
+			if len(args) < 2 {
+				return g, nil, fmt.Errorf("flag --%s needs a value", name)
+			}
+			value, args = args[1], args[1:]
+		}
+		args = args[1:]
+		if name == "config" {
+			g.Config = value
+		} else {
+			g.Set = append(g.Set, value)
+		}
+	}
+	return g, args, nil
+}
+
+// configShow prints the effective configuration, every layer applied, with
+// secrets redacted.
+func (a *App) configShow(args []string) int {
+	const name = "config show"
+	fs := a.flags(name, "Show the effective configuration from the defaults, config file, environment and --set flags, with secrets redacted")
+	output := fs.String("output", "yaml", "output format: yaml, json or toml")
+	if code, ok := a.parse(fs, args); !ok {
+		return code
+	}
+	cfg := a.config()
+	var buf bytes.Buffer
+	var err error
+	switch strings.ToLower(*output) {
+	case "yaml":
+		fmt.Fprintf(&buf, "# %s\n", a.configSource())
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		err = enc.Encode(cfg)
+	case "json":
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(cfg)
+	case "toml":
+		fmt.Fprintf(&buf, "# %s\n", a.configSource())
+		err = toml.NewEncoder(&buf).Encode(cfg)
+	default:
+		return a.usageError(fs, "unknown output format %q (want yaml, json or toml)", *output)
+	}
+	if err != nil {
+		return a.fail(name, err)
+	}
+	if _, err := a.Stdout.Write(buf.Bytes()); err != nil {
+		return a.fail(name, err)
+	}
+	return ExitOK
+}
+
+// configSource describes where the configuration came from.
+func (a *App) configSource() string {
+	if a.ConfigFile == "" {
+		return "no config file; defaults, environment and flags"
+	}
+	return "from " + a.ConfigFile + ", environment and flags"
+}
+
+// liveSettings are the key prefixes of the settings a reload applies while
+// the app runs; the rest take effect on restart.
+var liveSettings = []string{"weather_provider", "accuweather.", "weatherstack.", "cache.", "timeouts.provider"}
+
+// watchConfig starts reloading the config when its file changes or on
+// SIGHUP, until ctx is done. From then on a.config() returns the config in
+// effect.
+func (a *App) watchConfig(ctx context.Context) {
+	r := config.NewReloader(a.ConfigOptions, a.config(), a.ConfigFile, applyConfig)
+	a.reloader = r
+	go func() {
+		if err := r.Run(ctx); err != nil {
+			slog.WarnContext(ctx, "config reloading is off", "error", err)
+		}
+	}()
+}
+
+// applyConfig swaps in a provider built from next if any provider setting
+// changed. Building it is the only step that can fail, so a failure leaves
+// the running provider in place.
+func applyConfig(next *config.Config, changes []config.Change) error {
+	reconfigure := false
+	for _, c := range changes {
+		if isLive(c.Key) {
+			reconfigure = true
+		} else {
+			slog.Warn("setting changed; it takes effect on restart", "key", c.Key)
+		}
+	}
+	if reconfigure {
+		return weather.Configure(next)
+	}
+	return nil
+}
+
+func isLive(key string) bool {
+	for _, prefix := range liveSettings {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
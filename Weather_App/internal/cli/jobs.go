@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"weatherapp/internal/digest"
+	"weatherapp/internal/logging"
+	"weatherapp/internal/notify"
+	"weatherapp/internal/rules"
+	"weatherapp/internal/weather"
+	"weatherapp/models"
+)
+
+// checkAlerts evaluates every user's alert rules and delivers each newly
+// triggered one on the user's notification channels. It is meant to be run
+// from cron.
+func (a *App) checkAlerts(args []string) int {
+	const name = "check-alerts"
+	fs := a.flags(name, "Evaluate every user's alert rules and notify them of newly triggered ones")
+	if code, ok := a.parse(fs, args); !ok {
+		return code
+	}
+	store, err := a.openSentStore()
+	if err != nil {
+		return a.fail(name, fmt.Errorf("loading sent alerts: %w", err))
+	}
+	ctx := logging.Start(context.Background(), name)
+	users, err := a.loadUsers(ctx)
+	if err != nil {
+		return a.fail(name, err)
+	}
+	sinks := a.notifySinks()
+
+	code := ExitOK
+	for _, u := range users {
+		triggers, err := rules.Check(ctx, u, weather.Provider())
+		if err != nil {
+			fmt.Fprintf(a.Stderr, "%s: %s: %v\n", name, u.UserID, err)
+			code = ExitError
+			continue
+		}
+		n := &notify.Dedup{Next: notify.ForUser(u.Notifications, sinks), Store: store}
+		for _, t := range triggers {
+			msg := notify.Message{
+				UserID:  u.UserID,
+				Subject: fmt.Sprintf("Weather alert for %s", t.Location),
+				Body:    t.String(),
+				Key:     t.Key(),
+			}
+			if err := n.Notify(ctx, msg); err != nil {
+				fmt.Fprintf(a.Stderr, "%s: notifying %s: %v\n", name, u.UserID, err)
+				code = ExitError
+			}
+		}
+	}
+	return code
+}
+
+// serveDigest runs the daily digest scheduler until SIGINT or SIGTERM,
+// reloading provider settings like serve.
+func (a *App) serveDigest(args []string) int {
+	const name = "serve-digest"
+	fs := a.flags(name, "Send each user their daily digest at their configured time, until interrupted")
+	metricsAddr := fs.String("metrics-addr", a.config().Server.MetricsAddr, "serve /metrics, /healthz and /readyz on this address; empty for none (server.metrics_addr)")
+	if code, ok := a.parse(fs, args); !ok {
+		return code
+	}
+	store, err := a.openSentStore()
+	if err != nil {
+		return a.fail(name, fmt.Errorf("loading sent state: %w", err))
+	}
+	sinks := a.notifySinks()
+	s := &digest.Scheduler{
+		Users: a.loadUsers,
+		Notifier: func(u models.User) notify.Notifier {
+			return notify.ForUser(u.Notifications, sinks)
+		},
+		Sent: store,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	a.watchConfig(ctx)
+	if listen := *metricsAddr; listen != "" {
+		// The probes read the store, so connect it before they can run.
+		if err := a.initStorage(); err != nil {
+			return a.fail(name, err)
+		}
+		ln, err := net.Listen("tcp", listen)
+		if err != nil {
+			return a.fail(name, err)
+		}
+		mux := http.NewServeMux()
+		opsRoutes(mux)
+		srv := &http.Server{Handler: mux, ReadHeaderTimeout: a.config().Timeouts.ReadHeader.Duration}
+		defer srv.Close()
+		go func() {
+			if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("metrics server stopped", "error", err)
+			}
+		}()
+		slog.Info("metrics listening", "addr", listen)
+	}
+	slog.Info("digest scheduler running; press Ctrl+C to stop")
+	if err := s.Run(ctx); err != nil {
+		return a.fail(name, err)
+	}
+	slog.Info("digest scheduler stopped")
+	return ExitOK
+}
+
+// openSentStore opens the record of delivered notifications at
+// notify.state_file, or in the user cache dir if that is not set.
+func (a *App) openSentStore() (*notify.FileStore, error) {
+	return notify.OpenFileStore(firstNonEmpty(a.config().Notify.StateFile, notify.DefaultStatePath()))
+}
+
+// notifySinks returns the mail server and file directory users'
+// notifications are delivered through.
+func (a *App) notifySinks() notify.Sinks {
+	n := a.config().Notify
+	return notify.Sinks{
+		SMTP:    notify.SMTPConfig{Addr: n.SMTPAddr, From: n.SMTPFrom, Username: n.SMTPUsername, Password: string(n.SMTPPassword)},
+		FileDir: n.FileDir,
+	}
+}
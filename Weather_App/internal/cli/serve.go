@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"weatherapp/internal/health"
+	"weatherapp/internal/logging"
+	"weatherapp/internal/metrics"
+	"weatherapp/internal/rpc"
+	"weatherapp/internal/server"
+	"weatherapp/internal/storage"
+	"weatherapp/internal/weather"
+	"weatherapp/internal/web"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// serve runs the HTTP/JSON API and web dashboard on one address and the
+// gRPC API on another until SIGINT or SIGTERM. All accept the same tokens.
+// The HTTP address also serves /metrics, /healthz and /readyz. Provider
+// settings are reloaded when the config file changes or on SIGHUP.
+func (a *App) serve(args []string) int {
+	const name = "serve"
+	cfg := a.config()
+	fs := a.flags(name, "Serve the web dashboard and the HTTP/JSON and gRPC APIs; tokens are signed with server.token_secret")
+	addr := fs.String("addr", cfg.Server.Addr, "HTTP listen address (server.addr)")
+	grpcAddr := fs.String("grpc-addr", cfg.Server.GRPCAddr, "gRPC listen address (server.grpc_addr)")
+	ttl := fs.Duration("token-ttl", cfg.Server.TokenTTL.Duration, "how long login tokens stay valid (server.token_ttl)")
+	if code, ok := a.parse(fs, args); !ok {
+		return code
+	}
+	listen, grpcListen := *addr, *grpcAddr
+	secret := string(cfg.Server.TokenSecret)
+	if secret == "" {
+		slog.Warn("server.token_secret is not set; using a random secret, so tokens will not survive a restart")
+	}
+	grpcListener, err := net.Listen("tcp", grpcListen)
+	if err != nil {
+		return a.fail(name, err)
+	}
+	if err := a.initStorage(); err != nil {
+		return a.fail(name, err)
+	}
+
+	tokens := server.NewTokens([]byte(secret), *ttl)
+	api := server.New(tokens).Handler()
+	mux := http.NewServeMux()
+	mux.Handle("/v1/", api)
+	mux.Handle("/openapi.yaml", api)
+	mux.Handle("/", web.New(tokens).Handler())
+	opsRoutes(mux)
+	srv := &http.Server{
+		Addr:              listen,
+		Handler:           traced(logging.Middleware(mux)),
+		ReadHeaderTimeout: cfg.Timeouts.ReadHeader.Duration,
+	}
+	grpcSrv := rpc.New(tokens)
+	defer grpcSrv.Stop()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	a.watchConfig(ctx)
+	errc := make(chan error, 2)
+	go func() { errc <- srv.ListenAndServe() }()
+	go func() { errc <- grpcSrv.Serve(grpcListener) }()
+	slog.Info("dashboard and API listening", "http", listen, "grpc", grpcListen)
+
+	select {
+	case err := <-errc:
+		return a.fail(name, err)
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Timeouts.Shutdown.Duration)
+	defer cancel()
+	go grpcSrv.GracefulStop()
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return a.fail(name, err)
+	}
+	slog.Info("API stopped")
+	return ExitOK
+}
+
+// opsRoutes adds the Prometheus metrics and the health probes to mux. The
+// readiness checks reach the user store but only look at the provider's
+// configuration, so probing spends no API quota.
+func opsRoutes(mux *http.ServeMux) {
+	mux.Handle("GET /metrics", metrics.Handler())
+	health.Register(mux,
+		health.Check{Name: "storage", Run: storage.Ping},
+		health.Check{Name: "provider", Run: func(context.Context) error { return weather.CheckCredentials() }},
+	)
+}
+
+// traced continues the trace of a request that carries a traceparent header,
+// or starts one, so the spans of the storage and provider calls made for
+// the request join it.
+func traced(h http.Handler) http.Handler {
+	return otelhttp.NewHandler(h, "http", otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return r.Method + " " + r.URL.Path
+	}))
+}
@@ -0,0 +1,214 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"weatherapp/internal/auth"
+	"weatherapp/internal/i18n"
+	"weatherapp/internal/logging"
+	"weatherapp/internal/storage"
+	"weatherapp/internal/units"
+	"weatherapp/internal/user"
+	"weatherapp/internal/weather"
+	"weatherapp/models"
+)
+
+func (a *App) userRegister(args []string) int {
+	const name = "user register"
+	fs := a.flags(name, "Create a user. The password is read from "+EnvPassword+" or, with --password-stdin, from the first line of standard input")
+	id := fs.String("id", "", "user ID (required)")
+	userName := fs.String("name", "", "user name used to log in (required)")
+	fromStdin := fs.Bool("password-stdin", false, "read the password from standard input")
+	if code, ok := a.parse(fs, args); !ok {
+		return code
+	}
+	if *id == "" || *userName == "" {
+		return a.usageError(fs, "--id and --name are required")
+	}
+	password := a.env(EnvPassword)
+	if *fromStdin {
+		line, err := bufio.NewReader(a.Stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return a.fail(name, fmt.Errorf("reading password: %w", err))
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if password == "" {
+		return a.usageError(fs, "no password: set %s or use --password-stdin", EnvPassword)
+	}
+
+	if err := a.initStorage(); err != nil {
+		return a.fail(name, err)
+	}
+	if err := auth.RegisterUser(logging.Start(context.Background(), name), *id, *userName, password); err != nil {
+		return a.fail(name, err)
+	}
+	fmt.Fprintf(a.Stdout, "User %s registered\n", *id)
+	return ExitOK
+}
+
+// userDoc is the JSON form of a User in "user list"; it omits the password.
+type userDoc struct {
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+}
+
+func (a *App) userList(args []string) int {
+	const name = "user list"
+	fs := a.flags(name, "List users")
+	output := fs.String("output", "", "output format: text or json (env "+EnvOutput+")")
+	if code, ok := a.parse(fs, args); !ok {
+		return code
+	}
+	format := strings.ToLower(firstNonEmpty(*output, a.env(EnvOutput), "text"))
+	if format != "text" && format != "json" {
+		return a.usageError(fs, "unknown output format %q (want text or json)", format)
+	}
+
+	users, err := a.loadUsers(logging.Start(context.Background(), name))
+	if err != nil {
+		return a.fail(name, err)
+	}
+	if format == "json" {
+		docs := []userDoc{}
+		for _, u := range users {
+			docs = append(docs, userDoc{UserID: u.UserID, Name: u.Name})
+		}
+		return a.writeJSON(name, docs)
+	}
+	for _, u := range users {
+		fmt.Fprintf(a.Stdout, "UserID: %s, Name: %s\n", u.UserID, u.Name)
+	}
+	return ExitOK
+}
+
+// prefsDoc is the JSON form of a User's Preferences.
+type prefsDoc struct {
+	Location      string            `json:"location"`
+	Unit          string            `json:"unit"`
+	UnitOverrides map[string]string `json:"unit_overrides,omitempty"`
+	Verbosity     string            `json:"verbosity"`
+	Forecast      string            `json:"forecast"`
+	Output        string            `json:"output"`
+	Language      string            `json:"language"`
+}
+
+func (a *App) prefsShow(args []string) int {
+	const name = "prefs show"
+	fs := a.flags(name, "Show a user's preferences")
+	userID := fs.String("user", "", "user ID (env "+EnvUser+")")
+	output := fs.String("output", "", "output format: text or json (env "+EnvOutput+")")
+	if code, ok := a.parse(fs, args); !ok {
+		return code
+	}
+	id := firstNonEmpty(*userID, a.env(EnvUser))
+	if id == "" {
+		return a.usageError(fs, "--user is required")
+	}
+	format := strings.ToLower(firstNonEmpty(*output, a.env(EnvOutput), "text"))
+	if format != "text" && format != "json" {
+		return a.usageError(fs, "unknown output format %q (want text or json)", format)
+	}
+	ctx := logging.Start(context.Background(), name)
+	u, err := a.findUser(ctx, id)
+	if err != nil {
+		return a.fail(name, err)
+	}
+
+	p := u.Preferences
+	if format == "json" {
+		return a.writeJSON(name, prefsDoc{
+			Location: p.Location, Unit: p.Unit, UnitOverrides: p.UnitOverrides,
+			Verbosity: p.Verbosity, Forecast: p.Forecast, Output: p.Output, Language: p.Language,
+		})
+	}
+	unit := p.Unit
+	for _, q := range units.Quantities {
+		if v, ok := p.UnitOverrides[q]; ok {
+			unit += fmt.Sprintf(", %s=%s", q, v)
+		}
+	}
+	fmt.Fprintf(a.Stdout, "Location  : %s\n", p.Location)
+	fmt.Fprintf(a.Stdout, "Units     : %s\n", unit)
+	fmt.Fprintf(a.Stdout, "Verbosity : %s\n", p.Verbosity)
+	fmt.Fprintf(a.Stdout, "Forecast  : %s\n", p.Forecast)
+	fmt.Fprintf(a.Stdout, "Output    : %s\n", p.Output)
+	fmt.Fprintf(a.Stdout, "Language  : %s\n", p.Language)
+	return ExitOK
+}
+
+func (a *App) prefsSet(args []string) int {
+	const name = "prefs set"
+	fs := a.flags(name, "Change a user's preferences; only the flags given are changed")
+	userID := fs.String("user", "", "user ID (env "+EnvUser+")")
+	location := fs.String("location", "", "city, \"lat,lon\", postal code with country, or auto")
+	unit := fs.String("unit", "", "unit profile (metric, imperial, uk, si), optionally with overrides like \"metric,speed=kn\"")
+	verbosity := fs.String("verbosity", "", "brief or verbose")
+	forecast := fs.String("forecast", "", "day, week or month")
+	output := fs.String("output", "", "output format: "+strings.Join(weather.Formats, ", "))
+	language := fs.String("language", "", "language: "+strings.Join(i18n.Languages, ", ")+", or empty to follow the environment")
+	if code, ok := a.parse(fs, args); !ok {
+		return code
+	}
+	id := firstNonEmpty(*userID, a.env(EnvUser))
+	if id == "" {
+		return a.usageError(fs, "--user is required")
+	}
+
+	var changes user.PreferenceChanges
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "location":
+			changes.Location = location
+		case "unit":
+			changes.Unit = unit
+		case "verbosity":
+			changes.Verbosity = verbosity
+		case "forecast":
+			changes.Forecast = forecast
+		case "output":
+			changes.Output = output
+		case "language":
+			changes.Language = language
+		}
+	})
+	if changes.Empty() {
+		return a.usageError(fs, "nothing to change")
+	}
+	if err := changes.Apply(&models.Preferences{}); err != nil {
+		return a.usageError(fs, "%v", err)
+	}
+
+	ctx := logging.Start(context.Background(), name)
+	if err := changes.ResolveLocation(ctx); err != nil {
+		return a.usageError(fs, "%v; give its coordinates as \"lat,lon\" instead", err)
+	}
+	u, err := a.findUser(ctx, id)
+	if err != nil {
+		return a.fail(name, err)
+	}
+	if err := changes.Apply(&u.Preferences); err != nil {
+		return a.fail(name, err)
+	}
+	if err := storage.UpdateUser(ctx, u); err != nil {
+		return a.fail(name, err)
+	}
+	fmt.Fprintln(a.Stdout, "Preferences updated")
+	return ExitOK
+}
+
+func (a *App) writeJSON(name string, v any) int {
+	enc := json.NewEncoder(a.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return a.fail(name, err)
+	}
+	return ExitOK
+}
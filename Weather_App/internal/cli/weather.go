@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"weatherapp/internal/geo"
+	"weatherapp/internal/logging"
+	"weatherapp/internal/units"
+	"weatherapp/internal/weather"
+	"weatherapp/models"
+)
+
+func (a *App) weatherCurrent(args []string) int {
+	return a.showWeather("weather current", "Show current conditions for a location or a user's preferred location", args, false)
+}
+
+func (a *App) weatherForecast(args []string) int {
+	return a.showWeather("weather forecast", "Show a daily forecast for a location or a user's preferred location", args, true)
+}
+
+// showWeather renders current conditions or a forecast. Flags win over the
+// user's preferences, which win over the environment.
+func (a *App) showWeather(name, summary string, args []string, forecast bool) int {
+	fs := a.flags(name, summary)
+	userID := fs.String("user", "", "use this user's location, units and output format (env "+EnvUser+")")
+	location := fs.String("location", "", "city, \"lat,lon\", postal code with country, or auto (env "+EnvLocation+")")
+	unit := fs.String("unit", "", "unit profile (metric, imperial, uk, si, c, f, k), optionally with overrides like \"metric,speed=kn\" (env "+EnvUnit+")")
+	output := fs.String("output", "", "output format: "+strings.Join(weather.Formats, ", ")+" (env "+EnvOutput+")")
+	verbose := fs.Bool("verbose", false, "include feels-like, humidity, wind and other details in text output")
+	days := 0
+	if forecast {
+		fs.IntVar(&days, "days", 7, "number of days to forecast, 1-30")
+	}
+	if code, ok := a.parse(fs, args); !ok {
+		return code
+	}
+	if forecast && (days < 1 || days > 30) {
+		return a.usageError(fs, "--days must be between 1 and 30")
+	}
+
+	ctx := logging.Start(context.Background(), name)
+	var prefs models.Preferences
+	if id := firstNonEmpty(*userID, a.env(EnvUser)); id != "" {
+		u, err := a.findUser(ctx, id)
+		if err != nil {
+			return a.fail(name, err)
+		}
+		prefs = u.Preferences
+	}
+	if *location != "" || prefs.Location == "" {
+		prefs.Location = firstNonEmpty(*location, a.env(EnvLocation))
+		prefs.Coords = nil
+	}
+	if prefs.Location == "" {
+		return a.usageError(fs, "--location or --user is required")
+	}
+	if u := firstNonEmpty(*unit, a.envUnless(EnvUnit, prefs.Unit)); u != "" {
+		profile, overrides, err := units.ParsePreference(u)
+		if err != nil {
+			return a.usageError(fs, "%v", err)
+		}
+		prefs.Unit, prefs.UnitOverrides = profile, overrides
+	}
+	prefs.Output = firstNonEmpty(*output, prefs.Output, a.env(EnvOutput))
+	if *verbose {
+		prefs.Verbosity = "verbose"
+	}
+
+	sys, err := units.FromPreferences(prefs)
+	if err != nil {
+		return a.fail(name, err)
+	}
+	r, err := weather.NewRenderer(prefs.Output)
+	if err != nil {
+		return a.usageError(fs, "%v", err)
+	}
+	report, err := weather.FetchReport(ctx, geo.FromPreferences(prefs), days, prefs.Language)
+	if err != nil {
+		return a.fail(name, err)
+	}
+	report.Units = sys
+	report.Verbosity = strings.ToLower(prefs.Verbosity)
+	if forecast {
+		report.Period = fmt.Sprintf("%d days", days)
+	}
+	if err := weather.ForTerminal(r, a.Stdout).Render(a.Stdout, report); err != nil {
+		return a.fail(name, err)
+	}
+	return ExitOK
+}
+
+// envUnless returns the environment value for key unless the user already
+// has a preference of their own.
+func (a *App) envUnless(key, pref string) string {
+	if pref != "" {
+		return ""
+	}
+	return a.env(key)
+}
@@ -1,25 +1,151 @@
-package config
-
-import (
-	"encoding/json"
-	"os"
-)
-
-// AppConfig holds which weather provider to use.
-type AppConfig struct {
-	WeatherProvider string `json:"weather_provider"`
-}
-
-// Load reads and parses the JSON file at path.
-func Load(path string) (*AppConfig, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-	var cfg AppConfig
-	if err := json.NewDecoder(f).Decode(&cfg); err != nil {
-		return nil, err
-	}
-	return &cfg, nil
-}
+// Package config loads the app's settings in layers: the defaults, then a
+// JSON, YAML or TOML file, then environment variables, then command-line
+// overrides, each layer replacing only the settings it names. The result is
+// validated as a whole, so every mistake is reported at once.
+package config
+
+import (
+	"time"
+)
+
+// Config holds every setting. Each field's env tag names the environment
+// variable that overrides it; in a section, the tag is a prefix added to
+// the names of its fields.
+type Config struct {
+	WeatherProvider string   `json:"weather_provider" yaml:"weather_provider" toml:"weather_provider" env:"WEATHER_PROVIDER"`
+	RefreshInterval Duration `json:"refresh_interval" yaml:"refresh_interval" toml:"refresh_interval" env:"WEATHER_REFRESH_INTERVAL"`
+
+	AccuWeather  Provider `json:"accuweather" yaml:"accuweather" toml:"accuweather" env:"ACCUWEATHER_"`
+	Weatherstack Provider `json:"weatherstack" yaml:"weatherstack" toml:"weatherstack" env:"WEATHERSTACK_"`
+	Storage      Storage  `json:"storage" yaml:"storage" toml:"storage"`
+	Cache        Cache    `json:"cache" yaml:"cache" toml:"cache"`
+	Timeouts     Timeouts `json:"timeouts" yaml:"timeouts" toml:"timeouts"`
+	Server       Server   `json:"server" yaml:"server" toml:"server"`
+	Notify       Notify   `json:"notify" yaml:"notify" toml:"notify"`
+	Tracing      Tracing  `json:"tracing" yaml:"tracing" toml:"tracing"`
+}
+
+// Provider holds one weather provider's account.
+type Provider struct {
+	APIKey  Secret `json:"api_key" yaml:"api_key" toml:"api_key" env:"API_KEY"`
+	BaseURL string `json:"base_url" yaml:"base_url" toml:"base_url" env:"BASE_URL"`
+}
+
+// Storage says where users are stored.
+type Storage struct {
+	ProjectID string `json:"project_id" yaml:"project_id" toml:"project_id" env:"GOOGLE_CLOUD_PROJECT"`
+}
+
+// Cache sizes the AccuWeather location cache; a zero size disables it.
+type Cache struct {
+	LocationTTL  Duration `json:"location_ttl" yaml:"location_ttl" toml:"location_ttl" env:"WEATHER_CACHE_TTL"`
+	LocationSize int      `json:"location_size" yaml:"location_size" toml:"location_size" env:"WEATHER_CACHE_SIZE"`
+}
+
+// Timeouts bound provider calls and the servers.
+type Timeouts struct {
+	Provider   Duration `json:"provider" yaml:"provider" toml:"provider" env:"WEATHER_PROVIDER_TIMEOUT"`
+	ReadHeader Duration `json:"read_header" yaml:"read_header" toml:"read_header" env:"WEATHER_READ_HEADER_TIMEOUT"`
+	Shutdown   Duration `json:"shutdown" yaml:"shutdown" toml:"shutdown" env:"WEATHER_SHUTDOWN_TIMEOUT"`
+}
+
+// Server holds the listen addresses and token settings of the serve and
+// serve-digest commands. An empty MetricsAddr turns off serve-digest's
+// metrics listener.
+type Server struct {
+	Addr        string   `json:"addr" yaml:"addr" toml:"addr" env:"WEATHER_ADDR"`
+	GRPCAddr    string   `json:"grpc_addr" yaml:"grpc_addr" toml:"grpc_addr" env:"WEATHER_GRPC_ADDR"`
+	MetricsAddr string   `json:"metrics_addr" yaml:"metrics_addr" toml:"metrics_addr" env:"WEATHER_METRICS_ADDR"`
+	TokenSecret Secret   `json:"token_secret" yaml:"token_secret" toml:"token_secret" env:"API_TOKEN_SECRET"`
+	TokenTTL    Duration `json:"token_ttl" yaml:"token_ttl" toml:"token_ttl" env:"WEATHER_TOKEN_TTL"`
+}
+
+// Notify holds the mail server email notifications are sent through, the
+// directory users' file notifications are written to and the file
+// check-alerts and serve-digest record sent messages in. An empty FileDir
+// disables file notifications; an empty StateFile uses one in the user
+// cache directory.
+type Notify struct {
+	SMTPAddr     string `json:"smtp_addr" yaml:"smtp_addr" toml:"smtp_addr" env:"SMTP_ADDR"`
+	SMTPFrom     string `json:"smtp_from" yaml:"smtp_from" toml:"smtp_from" env:"SMTP_FROM"`
+	SMTPUsername string `json:"smtp_username" yaml:"smtp_username" toml:"smtp_username" env:"SMTP_USERNAME"`
+	SMTPPassword Secret `json:"smtp_password" yaml:"smtp_password" toml:"smtp_password" env:"SMTP_PASSWORD"`
+	FileDir      string `json:"file_dir" yaml:"file_dir" toml:"file_dir" env:"NOTIFY_FILE_DIR"`
+	StateFile    string `json:"state_file" yaml:"state_file" toml:"state_file" env:"ALERT_STATE_FILE"`
+}
+
+// Tracing says where spans are exported: "none", "stdout" or "otlp",
+// which sends them to the collector set by the OTEL_EXPORTER_OTLP_*
+// variables.
+type Tracing struct {
+	Exporter string `json:"exporter" yaml:"exporter" toml:"exporter" env:"WEATHER_TRACE_EXPORTER"`
+}
+
+// Defaults returns the settings used when nothing overrides them.
+func Defaults() Config {
+	return Config{
+		WeatherProvider: "weatherstack",
+		RefreshInterval: Duration{5 * time.Minute},
+		AccuWeather:     Provider{BaseURL: "http://dataservice.accuweather.com"},
+		Weatherstack:    Provider{BaseURL: "http://api.weatherstack.com"},
+		Cache:           Cache{LocationTTL: Duration{24 * time.Hour}, LocationSize: 1000},
+		Timeouts: Timeouts{
+			Provider:   Duration{10 * time.Second},
+			ReadHeader: Duration{10 * time.Second},
+			Shutdown:   Duration{10 * time.Second},
+		},
+		Server: Server{
+			Addr:     ":8080",
+			GRPCAddr: ":9090",
+			TokenTTL: Duration{24 * time.Hour},
+		},
+		Tracing: Tracing{Exporter: "none"},
+	}
+}
+
+// Duration is a time.Duration written as a string such as "5m" or "1h30m".
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText parses a duration string.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+// MarshalText writes the duration in time.Duration's format.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// Secret is a setting such as an API key that must not be shown. It is
+// redacted whenever it is printed, marshaled or logged; convert it to a
+// string to use it.
+type Secret string
+
+// Redacted replaces a set Secret when it is shown.
+const Redacted = "[redacted]"
+
+// String returns Redacted, or "" if the secret is not set.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return Redacted
+}
+
+// MarshalText writes the redacted form.
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText reads the secret as is.
+func (s *Secret) UnmarshalText(text []byte) error {
+	*s = Secret(text)
+	return nil
+}
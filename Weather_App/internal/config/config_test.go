@@ -0,0 +1,265 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// write creates the file at path, making its directories.
+func write(t *testing.T, path, content string) string {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+// envOf returns a getenv reading m.
+func envOf(m map[string]string) func(string) string {
+	return func(k string) string { return m[k] }
+}
+
+// TestLoad_Layers checks each layer overrides only what it sets: the file
+// over the defaults, the environment over the file, overrides over both.
+func TestLoad_Layers(t *testing.T) {
+	path := write(t, filepath.Join(t.TempDir(), "app.yaml"), `
+weather_provider: AccuWeather
+accuweather:
+  api_key: from-file
+server:
+  addr: ":8000"
+  grpc_addr: ":9000"
+notify:
+  smtp_addr: mail.example.com:587
+  smtp_from: weather@example.com
+`)
+	env := envOf(map[string]string{
+		"ACCUWEATHER_API_KEY": "from-env",
+		"WEATHER_GRPC_ADDR":   ":9001",
+		"WEATHER_CACHE_SIZE":  "50",
+		"SMTP_PASSWORD":       "from-env",
+		"ALERT_STATE_FILE":    "/var/lib/weatherapp/sent.json",
+	})
+	cfg, file, err := Load(Options{File: path, Getenv: env, Overrides: []string{"server.grpc_addr=:9002", "timeouts.provider=3s"}})
+	require.NoError(t, err)
+	assert.Equal(t, path, file)
+
+	assert.Equal(t, "accuweather", cfg.WeatherProvider)
+	assert.Equal(t, Secret("from-env"), cfg.AccuWeather.APIKey)
+	assert.Equal(t, ":8000", cfg.Server.Addr)
+	assert.Equal(t, ":9002", cfg.Server.GRPCAddr)
+	assert.Equal(t, 50, cfg.Cache.LocationSize)
+	assert.Equal(t, 3*time.Second, cfg.Timeouts.Provider.Duration)
+	assert.Equal(t, Notify{
+		SMTPAddr:     "mail.example.com:587",
+		SMTPFrom:     "weather@example.com",
+		SMTPPassword: "from-env",
+		StateFile:    "/var/lib/weatherapp/sent.json",
+	}, cfg.Notify)
+	assert.Equal(t, Defaults().Weatherstack, cfg.Weatherstack)
+	assert.Equal(t, Defaults().RefreshInterval, cfg.RefreshInterval)
+}
+
+// TestLoad_Formats checks the same settings read alike from each format,
+// and that unknown keys are rejected rather than ignored.
+func TestLoad_Formats(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"c.json": `{"refresh_interval": "1m", "storage": {"project_id": "p"}}`,
+		"c.yaml": "refresh_interval: 1m\nstorage:\n  project_id: p\n",
+		"c.toml": "refresh_interval = \"1m\"\n[storage]\nproject_id = \"p\"\n",
+	}
+	for name, content := range files {
+		cfg, _, err := Load(Options{File: write(t, filepath.Join(dir, name), content)})
+		require.NoError(t, err, name)
+		assert.Equal(t, time.Minute, cfg.RefreshInterval.Duration, name)
+		assert.Equal(t, "p", cfg.Storage.ProjectID, name)
+	}
+
+	typos := map[string]string{
+		"t.json": `{"storage": {"projectid": "p"}}`,
+		"t.yaml": "storage:\n  projectid: p\n",
+		"t.toml": "[storage]\nprojectid = \"p\"\n",
+	}
+	for name, content := range typos {
+		_, _, err := Load(Options{File: write(t, filepath.Join(dir, name), content)})
+		assert.ErrorContains(t, err, "projectid", name)
+	}
+
+	_, _, err := Load(Options{File: write(t, filepath.Join(dir, "c.ini"), "")})
+	assert.ErrorContains(t, err, `unsupported format ".ini"`)
+	_, _, err = Load(Options{File: filepath.Join(dir, "missing.yaml")})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// TestFind checks XDG_CONFIG_HOME is searched before XDG_CONFIG_DIRS, and
+// that WEATHER_CONFIG and an explicit file skip the search.
+func TestFind(t *testing.T) {
+	home, sys := t.TempDir(), t.TempDir()
+	env := map[string]string{"XDG_CONFIG_HOME": home, "XDG_CONFIG_DIRS": sys}
+
+	path, err := Find(envOf(env))
+	require.NoError(t, err)
+	assert.Empty(t, path)
+
+	sysFile := write(t, filepath.Join(sys, "weatherapp", "config.toml"), `weather_provider = "accuweather"`)
+	path, err = Find(envOf(env))
+	require.NoError(t, err)
+	assert.Equal(t, sysFile, path)
+
+	homeFile := write(t, filepath.Join(home, "weatherapp", "config.json"), `{}`)
+	path, err = Find(envOf(env))
+	require.NoError(t, err)
+	assert.Equal(t, homeFile, path)
+
+	env[EnvFile] = sysFile
+	cfg, file, err := Load(Options{Getenv: envOf(env)})
+	require.NoError(t, err)
+	assert.Equal(t, sysFile, file)
+	assert.Equal(t, "accuweather", cfg.WeatherProvider)
+
+	assert.Equal(t, []string{"/home/u/.config/weatherapp", "/etc/xdg/weatherapp"}, Dirs(envOf(map[string]string{"HOME": "/home/u"})))
+}
+
+// TestValidate checks every problem is reported with its key.
+func TestValidate(t *testing.T) {
+	defaults := Defaults()
+	require.NoError(t, defaults.Validate())
+
+	_, _, err := Load(Options{
+		Getenv: envOf(map[string]string{"WEATHER_PROVIDER": "metoffice", "WEATHER_TRACE_EXPORTER": "zipkin"}),
+		Overrides: []string{
+			"refresh_interval=1s",
+			"weatherstack.base_url=api.weatherstack.com",
+			"server.addr=8080",
+			"server.metrics_addr=:99999",
+			"timeouts.shutdown=0s",
+			"cache.location_size=-1",
+			"notify.smtp_addr=:25",
+		},
+	})
+	var verr *ValidationError
+	require.True(t, errors.As(err, &verr), err)
+	assert.Len(t, verr.Problems, 10)
+	for _, key := range []string{"weather_provider", "refresh_interval", "weatherstack.base_url", "server.addr", "server.metrics_addr", "timeouts.shutdown", "cache.location_size", "notify.smtp_addr", "notify.smtp_from", "tracing.exporter"} {
+		assert.Contains(t, err.Error(), "\n  "+key+": ")
+	}
+
+	_, _, err = Load(Options{Overrides: []string{"server.port=1"}})
+	assert.ErrorContains(t, err, `unknown setting "server.port"`)
+	_, _, err = Load(Options{Getenv: envOf(map[string]string{"WEATHER_CACHE_SIZE": "lots"})})
+	assert.ErrorContains(t, err, "WEATHER_CACHE_SIZE")
+}
+
+// TestSecret checks secrets are redacted however the config is shown.
+func TestSecret(t *testing.T) {
+	cfg := Defaults()
+	cfg.Server.TokenSecret = "hunter2"
+	for name, marshal := range map[string]func(any) ([]byte, error){"json": json.Marshal, "yaml": yaml.Marshal} {
+		out, err := marshal(cfg)
+		require.NoError(t, err)
+		assert.NotContains(t, string(out), "hunter2", name)
+		assert.Contains(t, string(out), Redacted, name)
+	}
+	assert.Equal(t, Redacted, cfg.Server.TokenSecret.String())
+	assert.Equal(t, "hunter2", string(cfg.Server.TokenSecret))
+	assert.Empty(t, Secret("").String())
+}
+
+// TestDiff checks changed settings are listed by key, with secrets redacted.
+func TestDiff(t *testing.T) {
+	old, next := Defaults(), Defaults()
+	assert.Empty(t, Diff(&old, &next))
+
+	next.Cache.LocationSize = 10
+	next.AccuWeather.APIKey = "hunter2"
+	assert.Equal(t, []Change{
+		{Key: "accuweather.api_key", Old: "", New: Redacted},
+		{Key: "cache.location_size", Old: "1000", New: "10"},
+	}, Diff(&old, &next))
+}
+
+// TestReloader checks a valid edit is applied, and that an invalid one, or
+// one apply rejects, leaves the current config in effect.
+func TestReloader(t *testing.T) {
+	path := write(t, filepath.Join(t.TempDir(), "app.yaml"), "cache:\n  location_size: 10\n")
+	cfg, file, err := Load(Options{File: path})
+	require.NoError(t, err)
+
+	var applied []*Config
+	var reject error
+	r := NewReloader(Options{}, cfg, file, func(next *Config, changes []Change) error {
+		if reject != nil {
+			return reject
+		}
+		applied = append(applied, next)
+		return nil
+	})
+
+	changes, err := r.Reload()
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+	assert.Empty(t, applied)
+
+	write(t, path, "cache:\n  location_size: 20\n")
+	changes, err = r.Reload()
+	require.NoError(t, err)
+	assert.Equal(t, []Change{{Key: "cache.location_size", Old: "10", New: "20"}}, changes)
+	require.Len(t, applied, 1)
+	assert.Same(t, applied[0], r.Current())
+
+	write(t, path, "cache:\n  location_size: -1\n")
+	_, err = r.Reload()
+	var verr *ValidationError
+	assert.True(t, errors.As(err, &verr), err)
+	assert.Equal(t, 20, r.Current().Cache.LocationSize)
+
+	reject = errors.New("provider unavailable")
+	write(t, path, "cache:\n  location_size: 30\n")
+	_, err = r.Reload()
+	assert.ErrorIs(t, err, reject)
+	assert.Equal(t, 20, r.Current().Cache.LocationSize)
+	assert.Len(t, applied, 1)
+}
+
+// TestReloader_Watch checks Run reloads when the file is replaced.
+func TestReloader_Watch(t *testing.T) {
+	dir := t.TempDir()
+	path := write(t, filepath.Join(dir, "app.yaml"), "cache:\n  location_size: 10\n")
+	cfg, file, err := Load(Options{File: path})
+	require.NoError(t, err)
+
+	applied := make(chan *Config, 1)
+	r := NewReloader(Options{}, cfg, file, func(next *Config, _ []Change) error {
+		applied <- next
+		return nil
+	})
+	r.settle = time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- r.Run(ctx) }()
+	defer func() {
+		cancel()
+		require.NoError(t, <-done)
+	}()
+
+	// Give the watcher time to start, then save the way editors do.
+	require.Eventually(t, func() bool {
+		tmp := write(t, filepath.Join(dir, "app.yaml.tmp"), "cache:\n  location_size: 20\n")
+		require.NoError(t, os.Rename(tmp, path))
+		select {
+		case next := <-applied:
+			assert.Equal(t, 20, next.Cache.LocationSize)
+			return true
+		case <-time.After(50 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+}
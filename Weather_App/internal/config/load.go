@@ -0,0 +1,243 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvFile names the config file, like the --config flag.
+const EnvFile = "WEATHER_CONFIG"
+
+// appDir is the app's directory under each XDG config directory.
+const appDir = "weatherapp"
+
+// fileNames are tried in order in each directory searched.
+var fileNames = []string{"config.yaml", "config.yml", "config.toml", "config.json"}
+
+// Options says where Load finds each layer.
+type Options struct {
+	// File is the config file to read. When empty, EnvFile is used, and
+	// failing that the XDG config directories are searched; finding no
+	// file there is not an error.
+	File string
+	// Getenv reads the environment layer; nil skips it.
+	Getenv func(string) string
+	// Overrides are "key=value" settings applied last, e.g.
+	// "server.addr=:8081".
+	Overrides []string
+}
+
+// Load builds the Config from the layers opts names and validates it. It
+// also returns the file read, or "" if there was none.
+func Load(opts Options) (*Config, string, error) {
+	getenv := opts.Getenv
+	if getenv == nil {
+		getenv = func(string) string { return "" }
+	}
+	cfg := Defaults()
+	file := firstNonEmpty(opts.File, getenv(EnvFile))
+	if file == "" {
+		var err error
+		if file, err = Find(getenv); err != nil {
+			return nil, "", err
+		}
+	}
+	if file != "" {
+		if err := cfg.readFile(file); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := cfg.applyEnv(getenv); err != nil {
+		return nil, "", err
+	}
+	for _, o := range opts.Overrides {
+		key, value, ok := strings.Cut(o, "=")
+		if !ok {
+			return nil, "", fmt.Errorf("override %q: want key=value", o)
+		}
+		if err := cfg.Set(key, value); err != nil {
+			return nil, "", err
+		}
+	}
+	cfg.WeatherProvider = strings.ToLower(strings.TrimSpace(cfg.WeatherProvider))
+	if err := cfg.Validate(); err != nil {
+		return nil, "", err
+	}
+	return &cfg, file, nil
+}
+
+// Dirs returns the directories searched for a config file, most important
+// first: $XDG_CONFIG_HOME (default ~/.config), then each of
+// $XDG_CONFIG_DIRS (default /etc/xdg), each with "weatherapp" appended.
+func Dirs(getenv func(string) string) []string {
+	var dirs []string
+	if home := getenv("XDG_CONFIG_HOME"); home != "" {
+		dirs = append(dirs, home)
+	} else if home := getenv("HOME"); home != "" {
+		dirs = append(dirs, filepath.Join(home, ".config"))
+	}
+	for _, d := range filepath.SplitList(firstNonEmpty(getenv("XDG_CONFIG_DIRS"), "/etc/xdg")) {
+		if filepath.IsAbs(d) {
+			dirs = append(dirs, d)
+		}
+	}
+	for i, d := range dirs {
+		dirs[i] = filepath.Join(d, appDir)
+	}
+	return dirs
+}
+
+// Find returns the first config file in Dirs, or "" if there is none.
+func Find(getenv func(string) string) (string, error) {
+	for _, dir := range Dirs(getenv) {
+		for _, name := range fileNames {
+			path := filepath.Join(dir, name)
+			_, err := os.Stat(path)
+			if err == nil {
+				return path, nil
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				return "", fmt.Errorf("looking for config: %w", err)
+			}
+		}
+	}
+	return "", nil
+}
+
+// readFile decodes the file at path over c, in the format its extension
+// names. Unknown keys are rejected, as they are usually misspellings.
+func (c *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(c)
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err = dec.Decode(c); errors.Is(err, io.EOF) {
+			err = nil // an empty file
+		}
+	case ".toml":
+		var md toml.MetaData
+		md, err = toml.Decode(string(data), c)
+		if err == nil {
+			if undecoded := md.Undecoded(); len(undecoded) > 0 {
+				err = fmt.Errorf("unknown setting %q", undecoded[0].String())
+			}
+		}
+	default:
+		err = fmt.Errorf("unsupported format %q (want .json, .yaml, .yml or .toml)", ext)
+	}
+	if err != nil {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+	return nil
+}
+
+// setting is one leaf of a Config: its dotted key, environment variable
+// and field.
+type setting struct {
+	key   string
+	env   string
+	field reflect.Value
+}
+
+// settings lists every leaf of c in declaration order.
+func (c *Config) settings() []setting {
+	var out []setting
+	var walk func(v reflect.Value, keyPrefix, envPrefix string)
+	walk = func(v reflect.Value, keyPrefix, envPrefix string) {
+		t := v.Type()
+		for i := range t.NumField() {
+			f := t.Field(i)
+			key := keyPrefix + strings.Split(f.Tag.Get("json"), ",")[0]
+			env := f.Tag.Get("env")
+			if env != "" {
+				env = envPrefix + env
+			}
+			if f.Type.Kind() == reflect.Struct && f.Type != reflect.TypeOf(Duration{}) {
+				walk(v.Field(i), key+".", env)
+				continue
+			}
+			out = append(out, setting{key: key, env: env, field: v.Field(i)})
+		}
+	}
+	walk(reflect.ValueOf(c).Elem(), "", "")
+	return out
+}
+
+// applyEnv sets each setting whose environment variable is not empty.
+func (c *Config) applyEnv(getenv func(string) string) error {
+	for _, s := range c.settings() {
+		if s.env == "" {
+			continue
+		}
+		if v := getenv(s.env); v != "" {
+			if err := s.set(v); err != nil {
+				return fmt.Errorf("%s: %w", s.env, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Set changes the setting with the dotted key, e.g. "server.addr", parsing
+// value as its type.
+func (c *Config) Set(key, value string) error {
+	for _, s := range c.settings() {
+		if s.key == key {
+			if err := s.set(value); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown setting %q", key)
+}
+
+func (s setting) set(value string) error {
+	if u, ok := s.field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(value))
+	}
+	switch s.field.Kind() {
+	case reflect.String:
+		s.field.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", value)
+		}
+		s.field.SetInt(int64(n))
+	default:
+		return fmt.Errorf("unsupported setting type %s", s.field.Type())
+	}
+	return nil
+}
+
+// firstNonEmpty returns the first of values that is not empty.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// settleTime lets a burst of file events, as editors make when saving,
+// end before the file is read.
+const settleTime = 100 * time.Millisecond
+
+// Change is one setting that differs between two configs. Secrets show as
+// Redacted.
+type Change struct {
+	Key      string
+	Old, New string
+}
+
+// Diff lists the settings that differ from old to new, in the order they
+// are declared.
+func Diff(old, new *Config) []Change {
+	var changes []Change
+	olds, news := old.settings(), new.settings()
+	for i, s := range olds {
+		o, n := s.field.Interface(), news[i].field.Interface()
+		if o != n {
+			changes = append(changes, Change{Key: s.key, Old: fmt.Sprint(o), New: fmt.Sprint(n)})
+		}
+	}
+	return changes
+}
+
+// Reloader keeps the config current while the app runs. It loads the
+// config again when its file changes or the process gets SIGHUP, and hands
+// each valid new config to its apply function. A config that fails to load
+// or validate, or that apply rejects, is logged and the current one stays
+// in effect.
+type Reloader struct {
+	opts   Options
+	apply  func(next *Config, changes []Change) error
+	settle time.Duration
+
+	mu      sync.Mutex
+	current *Config
+}
+
+// NewReloader creates a Reloader for cfg, loaded from file with opts. Later
+// loads read the same file even if one with higher priority appears.
+// apply must make next take effect, or leave the app unchanged and return
+// an error.
+func NewReloader(opts Options, cfg *Config, file string, apply func(next *Config, changes []Change) error) *Reloader {
+	opts.File = firstNonEmpty(opts.File, file)
+	return &Reloader{opts: opts, apply: apply, settle: settleTime, current: cfg}
+}
+
+// Current returns the config in effect.
+func (r *Reloader) Current() *Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current
+}
+
+// Reload loads the config and applies it if it is valid and differs from
+// the current one, returning the changes applied.
+func (r *Reloader) Reload() ([]Change, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	next, _, err := Load(r.opts)
+	if err != nil {
+		return nil, err
+	}
+	changes := Diff(r.current, next)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	if err := r.apply(next, changes); err != nil {
+		return nil, fmt.Errorf("applying config: %w", err)
+	}
+	r.current = next
+	return changes, nil
+}
+
+// Run reloads on SIGHUP and, if the config came from a file, whenever the
+// file changes, until ctx is done.
+func (r *Reloader) Run(ctx context.Context) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var events <-chan fsnotify.Event
+	var watchErrs <-chan error
+	if r.opts.File != "" {
+		w, err := fsnotify.NewWatcher()
+		if err != nil {
+			return fmt.Errorf("watching config: %w", err)
+		}
+		defer w.Close()
+		// Editors often save by renaming a new file over the old one, so
+		// the directory is watched rather than the file.
+		if err := w.Add(filepath.Dir(r.opts.File)); err != nil {
+			return fmt.Errorf("watching config: %w", err)
+		}
+		events, watchErrs = w.Events, w.Errors
+	}
+	file := filepath.Clean(r.opts.File)
+
+	var settled <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+			r.reload(ctx, "SIGHUP")
+		case ev := <-events:
+			if filepath.Clean(ev.Name) == file && ev.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename) {
+				settled = time.After(r.settle)
+			}
+		case err := <-watchErrs:
+			slog.WarnContext(ctx, "watching config", "file", file, "error", err)
+		case <-settled:
+			settled = nil
+			r.reload(ctx, "file changed")
+		}
+	}
+}
+
+// reload runs Reload and logs the outcome.
+func (r *Reloader) reload(ctx context.Context, trigger string) {
+	changes, err := r.Reload()
+	if err != nil {
+		slog.ErrorContext(ctx, "config reload failed; keeping the current config", "trigger", trigger, "error", err)
+		return
+	}
+	for _, c := range changes {
+		slog.InfoContext(ctx, "config changed", "key", c.Key, "old", c.Old, "new", c.New)
+	}
+	slog.InfoContext(ctx, "config reloaded", "trigger", trigger, "file", r.opts.File, "changes", len(changes))
+}
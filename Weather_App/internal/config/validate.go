@@ -0,0 +1,129 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Providers lists the supported weather providers.
+var Providers = []string{"accuweather", "weatherstack"}
+
+// Exporters lists the trace exporters tracing.exporter may name.
+var Exporters = []string{"none", "stdout", "otlp"}
+
+// minRefreshInterval keeps the terminal UI from spending the provider's
+// quota.
+const minRefreshInterval = 10 * time.Second
+
+// Validate checks every setting and reports all problems found, each
+// prefixed with its key.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(key string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+
+	check("weather_provider", oneOf(c.WeatherProvider, Providers))
+	if c.RefreshInterval.Duration < minRefreshInterval {
+		check("refresh_interval", fmt.Errorf("must be at least %s", minRefreshInterval))
+	}
+	check("accuweather.base_url", httpURL(c.AccuWeather.BaseURL))
+	check("weatherstack.base_url", httpURL(c.Weatherstack.BaseURL))
+	if c.Cache.LocationTTL.Duration < 0 {
+		check("cache.location_ttl", errors.New("must not be negative"))
+	}
+	if c.Cache.LocationSize < 0 {
+		check("cache.location_size", errors.New("must not be negative"))
+	}
+	check("timeouts.provider", positive(c.Timeouts.Provider))
+	check("timeouts.read_header", positive(c.Timeouts.ReadHeader))
+	check("timeouts.shutdown", positive(c.Timeouts.Shutdown))
+	check("server.addr", listenAddr(c.Server.Addr))
+	check("server.grpc_addr", listenAddr(c.Server.GRPCAddr))
+	if c.Server.MetricsAddr != "" {
+		check("server.metrics_addr", listenAddr(c.Server.MetricsAddr))
+	}
+	check("server.token_ttl", positive(c.Server.TokenTTL))
+	if c.Notify.SMTPAddr != "" {
+		check("notify.smtp_addr", dialAddr(c.Notify.SMTPAddr))
+		if c.Notify.SMTPFrom == "" {
+			check("notify.smtp_from", errors.New("must be set to send email"))
+		}
+	}
+	check("tracing.exporter", oneOf(c.Tracing.Exporter, Exporters))
+
+	if len(errs) > 0 {
+		return &ValidationError{Problems: errs}
+	}
+	return nil
+}
+
+// ValidationError lists every problem Validate found, one per line.
+type ValidationError struct {
+	Problems []error
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	b.WriteString("invalid configuration:")
+	for _, p := range e.Problems {
+		b.WriteString("\n  ")
+		b.WriteString(p.Error())
+	}
+	return b.String()
+}
+
+func (e *ValidationError) Unwrap() []error {
+	return e.Problems
+}
+
+func oneOf(v string, allowed []string) error {
+	for _, a := range allowed {
+		if v == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is not one of %q", v, allowed)
+}
+
+func positive(d Duration) error {
+	if d.Duration <= 0 {
+		return errors.New("must be positive")
+	}
+	return nil
+}
+
+func httpURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an http or https URL", s)
+	}
+	return nil
+}
+
+// dialAddr checks s is a host:port to connect to.
+func dialAddr(s string) error {
+	if host, _, err := net.SplitHostPort(s); err == nil && host == "" {
+		return fmt.Errorf("%q has no host", s)
+	}
+	return listenAddr(s)
+}
+
+// listenAddr checks s is a host:port to listen on; the host may be empty.
+func listenAddr(s string) error {
+	_, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return fmt.Errorf("%q is not host:port", s)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		return fmt.Errorf("%q has an invalid port", s)
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package digest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"weatherapp/internal/logging"
+	"weatherapp/internal/notify"
+	"weatherapp/internal/weather"
+	"weatherapp/models"
+)
+
+// DefaultCatchUp is how late a missed digest may still be sent after downtime.
+const DefaultCatchUp = 6 * time.Hour
+
+// A digest that fails is retried after minRetry, then after twice as long
+// each time, up to maxRetry, so a failing provider or channel is not
+// called on every tick.
+const (
+	minRetry = time.Minute
+	maxRetry = time.Hour
+)
+
+// Scheduler sends each User their daily weather digest at their configured
+// local time.
+type Scheduler struct {
+	// Users returns the current user list; it is called on every tick so
+	// preference changes are picked up without a restart.
+	Users func(ctx context.Context) ([]models.User, error)
+	// Notifier returns the channel(s) a User's digest is delivered on.
+	Notifier func(models.User) notify.Notifier
+	// Sent remembers delivered digests across restarts.
+	Sent notify.SentStore
+	// Report builds the digest body; defaults to weather.WriteReport.
+	Report func(context.Context, models.User) (string, error)
+
+	Interval time.Duration
+	CatchUp  time.Duration
+	Now      func() time.Time
+
+	// retries holds the failed digests by key, which names the user and
+	// day, until they are sent or too late to send.
+	retries map[string]retry
+}
+
+// retry records a failed digest and when it may next be tried.
+type retry struct {
+	attempts int
+	next     time.Time
+}
+
+// Run checks for due digests every Interval until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) error {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.Tick(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick sends every digest that is due and not yet delivered. Each User's
+// digest is logged as an operation of its own.
+func (s *Scheduler) Tick(ctx context.Context) {
+	now := s.now()
+	users, err := s.Users(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "digest: loading users", "error", err)
+		return
+	}
+	for _, u := range users {
+		if ctx.Err() != nil {
+			return
+		}
+		ctx := logging.Start(ctx, "digest")
+		due, err := LastDue(u.Notifications, now)
+		if err != nil {
+			slog.WarnContext(ctx, "digest: invalid schedule", "user", u.UserID, "error", err)
+			continue
+		}
+		if due.IsZero() || now.Sub(due) > s.catchUp() {
+			continue
+		}
+		key := fmt.Sprintf("digest|%s|%s", u.UserID, due.Format("2006-01-02"))
+		if s.Sent.Seen(key) || now.Before(s.retries[key].next) {
+			continue
+		}
+		body, err := s.report(ctx, u)
+		if err != nil {
+			slog.ErrorContext(ctx, "digest: building report", "user", u.UserID, "error", err, "retry_at", s.failed(key, now))
+			continue
+		}
+		msg := notify.Message{
+			UserID:  u.UserID,
+			Subject: fmt.Sprintf("Daily weather for %s", u.Preferences.Location),
+			Body:    body,
+			Key:     key,
+		}
+		n := &notify.Dedup{Next: s.Notifier(u), Store: s.Sent, Now: s.Now}
+		if err := n.Notify(ctx, msg); err != nil {
+			slog.ErrorContext(ctx, "digest: sending", "user", u.UserID, "error", err, "retry_at", s.failed(key, now))
+			continue
+		}
+		delete(s.retries, key)
+		slog.InfoContext(ctx, "digest: sent", "user", u.UserID)
+	}
+	for key, r := range s.retries {
+		if now.Sub(r.next) > s.catchUp() {
+			delete(s.retries, key)
+		}
+	}
+}
+
+// failed records a failed attempt at the digest with key and returns when
+// it may be tried again.
+func (s *Scheduler) failed(key string, now time.Time) time.Time {
+	if s.retries == nil {
+		s.retries = map[string]retry{}
+	}
+	r := s.retries[key]
+	wait := min(minRetry<<r.attempts, maxRetry)
+	r.attempts++
+	r.next = now.Add(wait)
+	s.retries[key] = r
+	return r.next
+}
+
+// LastDue returns the most recent scheduled digest time at or before now, or
+// the zero time if the User has no digest configured.
+func LastDue(n models.NotificationSettings, now time.Time) (time.Time, error) {
+	if n.DigestTime == "" {
+		return time.Time{}, nil
+	}
+	at, err := time.Parse("15:04", n.DigestTime)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid digest time %q", n.DigestTime)
+	}
+	loc := time.UTC
+	if n.TimeZone != "" {
+		if loc, err = time.LoadLocation(n.TimeZone); err != nil {
+			return time.Time{}, err
+		}
+	}
+	local := now.In(loc)
+	due := time.Date(local.Year(), local.Month(), local.Day(), at.Hour(), at.Minute(), 0, 0, loc)
+	if due.After(now) {
+		due = time.Date(local.Year(), local.Month(), local.Day()-1, at.Hour(), at.Minute(), 0, 0, loc)
+	}
+	return due, nil
+}
+
+func (s *Scheduler) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+func (s *Scheduler) catchUp() time.Duration {
+	if s.CatchUp > 0 {
+		return s.CatchUp
+	}
+	return DefaultCatchUp
+}
+
+func (s *Scheduler) report(ctx context.Context, u models.User) (string, error) {
+	if s.Report != nil {
+		return s.Report(ctx, u)
+	}
+	var buf bytes.Buffer
+	if err := weather.WriteReport(ctx, &buf, u, weather.TextRenderer{}); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,170 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"weatherapp/internal/notify"
+	"weatherapp/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memStore is an in-memory notify.SentStore.
+type memStore map[string]time.Time
+
+func (m memStore) Seen(key string) bool { _, ok := m[key]; return ok }
+
+func (m memStore) Mark(key string, at time.Time) error { m[key] = at; return nil }
+
+// recorder captures delivered messages.
+type recorder struct{ msgs []notify.Message }
+
+func (r *recorder) Notify(_ context.Context, m notify.Message) error {
+	r.msgs = append(r.msgs, m)
+	return nil
+}
+
+func newScheduler(now *time.Time, store memStore, rec *recorder) *Scheduler {
+	users := []models.User{{
+		UserID:        "u1",
+		Preferences:   models.Preferences{Location: "Pune"},
+		Notifications: models.NotificationSettings{DigestTime: "07:30", TimeZone: "Asia/Kolkata"},
+	}}
+	return &Scheduler{
+		Users:    func(context.Context) ([]models.User, error) { return users, nil },
+		Notifier: func(models.User) notify.Notifier { return rec },
+		Sent:     store,
+		Report: func(_ context.Context, u models.User) (string, error) {
+			return "report for " + u.Preferences.Location, nil
+		},
+		Now: func() time.Time { return *now },
+	}
+}
+
+// TestTick_SendsOncePerDayInUserZone checks the digest fires at the user's
+// local time, only once, and again the next day.
+func TestTick_SendsOncePerDayInUserZone(t *testing.T) {
+	ist, _ := time.LoadLocation("Asia/Kolkata")
+	now := time.Date(2026, 10, 19, 7, 29, 0, 0, ist)
+	store, rec := memStore{}, &recorder{}
+	s := newScheduler(&now, store, rec)
+
+	s.Tick(context.Background())
+	assert.Empty(t, rec.msgs)
+
+	now = time.Date(2026, 10, 19, 7, 30, 0, 0, ist)
+	s.Tick(context.Background())
+	s.Tick(context.Background())
+	require.Len(t, rec.msgs, 1)
+	assert.Equal(t, "report for Pune", rec.msgs[0].Body)
+	assert.Equal(t, "digest|u1|2026-10-19", rec.msgs[0].Key)
+
+	now = time.Date(2026, 10, 20, 7, 31, 0, 0, ist)
+	s.Tick(context.Background())
+	assert.Len(t, rec.msgs, 2)
+}
+
+// TestTick_MissedRuns checks a restarted scheduler catches up on a recently
+// missed digest, does not resend one already delivered, and skips stale ones.
+func TestTick_MissedRuns(t *testing.T) {
+	ist, _ := time.LoadLocation("Asia/Kolkata")
+	store, rec := memStore{}, &recorder{}
+
+	now := time.Date(2026, 10, 19, 10, 0, 0, 0, ist)
+	newScheduler(&now, store, rec).Tick(context.Background())
+	require.Len(t, rec.msgs, 1)
+
+	newScheduler(&now, store, rec).Tick(context.Background())
+	assert.Len(t, rec.msgs, 1)
+
+	now = time.Date(2026, 10, 20, 16, 0, 0, 0, ist)
+	newScheduler(&now, store, rec).Tick(context.Background())
+	assert.Len(t, rec.msgs, 1)
+}
+
+// TestTick_Backoff checks a failing digest is retried with growing delays
+// rather than on every tick, and is sent once it succeeds.
+func TestTick_Backoff(t *testing.T) {
+	ist, _ := time.LoadLocation("Asia/Kolkata")
+	now := time.Date(2026, 10, 19, 7, 30, 0, 0, ist)
+	store, rec := memStore{}, &recorder{}
+	s := newScheduler(&now, store, rec)
+	calls, fail := 0, true
+	s.Report = func(context.Context, models.User) (string, error) {
+		calls++
+		if fail {
+			return "", errors.New("provider down")
+		}
+		return "report", nil
+	}
+
+	// Tick every 30 seconds for an hour: 1, 2, 4, 8, 16 and 32 minutes
+	// apart makes 6 attempts rather than 120.
+	for range 120 {
+		s.Tick(context.Background())
+		now = now.Add(30 * time.Second)
+	}
+	assert.Equal(t, 6, calls)
+	assert.Empty(t, rec.msgs)
+
+	fail = false
+	now = now.Add(time.Hour)
+	s.Tick(context.Background())
+	require.Len(t, rec.msgs, 1)
+	assert.Empty(t, s.retries)
+}
+
+// TestLastDue covers zone conversion, the previous-day fallback and bad input.
+func TestLastDue(t *testing.T) {
+	now := time.Date(2026, 10, 19, 1, 0, 0, 0, time.UTC)
+
+	due, err := LastDue(models.NotificationSettings{DigestTime: "07:00", TimeZone: "America/New_York"}, now)
+	require.NoError(t, err)
+	assert.True(t, due.Equal(time.Date(2026, 10, 18, 11, 0, 0, 0, time.UTC)), due)
+
+	due, err = LastDue(models.NotificationSettings{}, now)
+	require.NoError(t, err)
+	assert.True(t, due.IsZero())
+
+	_, err = LastDue(models.NotificationSettings{DigestTime: "7am"}, now)
+	assert.Error(t, err)
+}
+
+// TestRun_StopsOnCancel checks graceful shutdown.
+func TestRun_StopsOnCancel(t *testing.T) {
+	now := time.Now()
+	s := newScheduler(&now, memStore{}, &recorder{})
+	s.Interval = time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
+
+// TestTick_UsersError checks a failed user lookup skips the tick, and the
+// digest goes out once the store is back.
+func TestTick_UsersError(t *testing.T) {
+	ist, _ := time.LoadLocation("Asia/Kolkata")
+	now := time.Date(2026, 10, 19, 8, 0, 0, 0, ist)
+	store, rec := memStore{}, &recorder{}
+	s := newScheduler(&now, store, rec)
+	users := s.Users
+	s.Users = func(context.Context) ([]models.User, error) { return nil, errors.New("firestore unavailable") }
+
+	s.Tick(context.Background())
+	assert.Empty(t, rec.msgs)
+
+	s.Users = users
+	s.Tick(context.Background())
+	assert.Len(t, rec.msgs, 1)
+}
@@ -0,0 +1,302 @@
+package geo
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+
+	"weatherapp/internal/i18n"
+)
+
+// citiesTSV is a small sample of about 250 large cities in the GeoNames
+// cities15000 layout, not a full extract: name, ASCII name, comma-separated
+// alternate names, latitude, longitude, country code, admin1 name,
+// population and IANA time zone.
+//
+//go:embed data/cities-sample.tsv
+var citiesTSV string
+
+// City is one gazetteer entry.
+type City struct {
+	Name       string
+	ASCIIName  string
+	AltNames   []string
+	Lat        float64
+	Lon        float64
+	Country    string
+	Admin1     string
+	Population int
+	TimeZone   string
+}
+
+// String returns "Name, Admin1, CC".
+func (c City) String() string {
+	return c.Place().String()
+}
+
+// Place converts the City into a geocoding candidate.
+func (c City) Place() Place {
+	return Place{Name: c.Name, Region: c.Admin1, Country: c.Country, Lat: c.Lat, Lon: c.Lon, TimeZone: c.TimeZone}
+}
+
+// Match is a gazetteer search hit. Distance is the edit distance between
+// the query and the matched name; Alternate is set when the match was on an
+// alternate name or transliteration rather than the city's own name.
+type Match struct {
+	City      City
+	Matched   string
+	Distance  int
+	Alternate bool
+}
+
+// Gazetteer is an in-memory fuzzy index over the embedded city list.
+type Gazetteer struct {
+	cities []City
+	names  []indexedName
+	exact  map[string][]int
+	grams  map[string][]int
+}
+
+// indexedName is one searchable spelling of a city.
+type indexedName struct {
+	norm      string
+	raw       string
+	city      int
+	alternate bool
+}
+
+var (
+	gazetteerOnce sync.Once
+	gazetteer     *Gazetteer
+)
+
+// Cities returns the embedded gazetteer, building its index on first use.
+func Cities() *Gazetteer {
+	gazetteerOnce.Do(func() {
+		g, err := NewGazetteer(citiesTSV)
+		if err != nil {
+			panic(fmt.Sprintf("geo: embedded gazetteer: %v", err))
+		}
+		gazetteer = g
+	})
+	return gazetteer
+}
+
+// NewGazetteer parses TSV data in the embedded format and indexes it.
+func NewGazetteer(data string) (*Gazetteer, error) {
+	g := &Gazetteer{exact: map[string][]int{}, grams: map[string][]int{}}
+	sc := bufio.NewScanner(strings.NewReader(data))
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimRight(sc.Text(), "\r")
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		f := strings.Split(text, "\t")
+		if len(f) != 9 {
+			return nil, fmt.Errorf("line %d: want 9 fields, got %d", line, len(f))
+		}
+		lat, err1 := strconv.ParseFloat(f[3], 64)
+		lon, err2 := strconv.ParseFloat(f[4], 64)
+		pop, err3 := strconv.Atoi(f[7])
+		if err1 != nil || err2 != nil || err3 != nil {
+			return nil, fmt.Errorf("line %d: invalid number", line)
+		}
+		c := City{
+			Name: f[0], ASCIIName: f[1], Lat: lat, Lon: lon,
+			Country: f[5], Admin1: f[6], Population: pop, TimeZone: f[8],
+		}
+		if f[2] != "" {
+			c.AltNames = strings.Split(f[2], ",")
+		}
+		g.add(c)
+	}
+	return g, sc.Err()
+}
+
+func (g *Gazetteer) add(c City) {
+	id := len(g.cities)
+	g.cities = append(g.cities, c)
+	seen := map[string]bool{}
+	addName := func(raw string, alternate bool) {
+		n := normalizeName(raw)
+		if n == "" || seen[n] {
+			return
+		}
+		seen[n] = true
+		nameID := len(g.names)
+		g.names = append(g.names, indexedName{norm: n, raw: raw, city: id, alternate: alternate})
+		g.exact[n] = append(g.exact[n], nameID)
+		for _, gram := range bigrams(n) {
+			g.grams[gram] = append(g.grams[gram], nameID)
+		}
+	}
+	addName(c.Name, false)
+	addName(c.ASCIIName, false)
+	for _, alt := range c.AltNames {
+		addName(alt, true)
+	}
+}
+
+// Search returns up to limit cities whose name, ASCII name or alternate
+// names are within a small edit distance of query, best first. Ties are
+// broken by population.
+func (g *Gazetteer) Search(query string, limit int) []Match {
+	q := normalizeName(query)
+	if q == "" {
+		return nil
+	}
+	maxDist := maxEditDistance(q)
+
+	best := map[int]Match{}
+	consider := func(nameID, dist int) {
+		n := g.names[nameID]
+		m, ok := best[n.city]
+		if ok && (m.Distance < dist || (m.Distance == dist && !m.Alternate)) {
+			return
+		}
+		best[n.city] = Match{City: g.cities[n.city], Matched: n.raw, Distance: dist, Alternate: n.alternate}
+	}
+
+	for _, id := range g.exact[q] {
+		consider(id, 0)
+	}
+	checked := map[int]bool{}
+	for _, gram := range bigrams(q) {
+		for _, id := range g.grams[gram] {
+			if checked[id] {
+				continue
+			}
+			checked[id] = true
+			if d := editDistance(q, g.names[id].norm); d <= maxDist {
+				consider(id, d)
+			}
+		}
+	}
+
+	out := make([]Match, 0, len(best))
+	for _, m := range best {
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Distance != out[j].Distance {
+			return out[i].Distance < out[j].Distance
+		}
+		return out[i].City.Population > out[j].City.Population
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// Suggest offers the closest gazetteer city as a correction for a place
+// name that is not already a listed city. It only consults the embedded
+// gazetteer, so it works offline and leaves geocoding to Resolve. The
+// gazetteer is a small sample, so a correction is applied only if the user
+// accepts it. It returns the input with any accepted correction applied.
+func Suggest(reader *bufio.Reader, input string) string {
+	if ParseLocation(input).Query == "" {
+		return input
+	}
+	name, rest, hasRest := strings.Cut(input, ",")
+	matches := Cities().Search(name, 1)
+	if len(matches) == 0 {
+		return input
+	}
+	m := matches[0]
+	if m.Distance == 0 && !m.Alternate {
+		return input
+	}
+	i18n.Printf("Did you mean %s? [y/N]: ", m.City)
+	answer, _ := reader.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer != "y" && answer != "yes" {
+		return input
+	}
+	if hasRest {
+		return m.City.Name + "," + rest
+	}
+	return m.City.Name
+}
+
+// normalizeName lower-cases s, strips diacritics and collapses punctuation
+// to single spaces so "São Paulo" and "sao-paulo" compare equal.
+func normalizeName(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range norm.NFD.String(s) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// bigrams returns the rune bigrams of s, padded so short names index too.
+func bigrams(s string) []string {
+	r := []rune(" " + s + " ")
+	out := make([]string, 0, len(r)-1)
+	for i := 0; i+1 < len(r); i++ {
+		out = append(out, string(r[i:i+2]))
+	}
+	return out
+}
+
+// maxEditDistance is the largest typo distance accepted for a query.
+func maxEditDistance(q string) int {
+	switch n := len([]rune(q)); {
+	case n <= 3:
+		return 0
+	case n <= 5:
+		return 1
+	case n <= 9:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// editDistance is the optimal string alignment distance: insertions,
+// deletions, substitutions and adjacent transpositions each cost one.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
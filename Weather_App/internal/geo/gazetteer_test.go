@@ -0,0 +1,75 @@
+package geo
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGazetteerSearch covers typos, alternate names, transliterations and
+// diacritics against the embedded data.
+func TestGazetteerSearch(t *testing.T) {
+	cases := []struct {
+		query, want string
+		alternate   bool
+	}{
+		{"Londn", "London", false},
+		{"london", "London", false},
+		{"Bangalore", "Bengaluru", true},
+		{"Bangalor", "Bengaluru", true},
+		{"Москва", "Moscow", true},
+		{"Sao Paulo", "Sao Paulo", false},
+		{"são-paulo", "Sao Paulo", false},
+		{"Munchen", "Munich", true},
+		{"Reykjavik", "Reykjavik", false},
+		{"Edinbrugh", "Edinburgh", false},
+	}
+	for _, c := range cases {
+		m := Cities().Search(c.query, 1)
+		require.Len(t, m, 1, c.query)
+		assert.Equal(t, c.want, m[0].City.Name, c.query)
+		assert.Equal(t, c.alternate, m[0].Alternate, c.query)
+	}
+
+	assert.Empty(t, Cities().Search("Xyzzyplugh", 5))
+	assert.Empty(t, Cities().Search("", 5))
+}
+
+// TestGazetteerSearch_RanksByPopulation checks equally good matches are
+// ordered by size and carry their time zone.
+func TestGazetteerSearch_RanksByPopulation(t *testing.T) {
+	m := Cities().Search("Springfield", 0)
+	require.Len(t, m, 3)
+	assert.Equal(t, "Missouri", m[0].City.Admin1)
+	assert.Equal(t, "America/New_York", m[1].City.TimeZone)
+	assert.Equal(t, "Illinois", m[2].City.Admin1)
+}
+
+// TestSuggest checks corrections are offered only for non-canonical names,
+// and applied only when accepted.
+func TestSuggest(t *testing.T) {
+	assert.Equal(t, "London", Suggest(bufio.NewReader(strings.NewReader("y\n")), "Londn"))
+	assert.Equal(t, "Londn", Suggest(bufio.NewReader(strings.NewReader("\n")), "Londn"))
+	assert.Equal(t, "Bengaluru, India", Suggest(bufio.NewReader(strings.NewReader("y\n")), "Bangalore, India"))
+	assert.Equal(t, "Mumbai", Suggest(bufio.NewReader(strings.NewReader("")), "Mumbai"))
+	assert.Equal(t, "10001,US", Suggest(bufio.NewReader(strings.NewReader("")), "10001,US"))
+}
+
+// TestSuggest_Offline checks Suggest never calls the geocoder.
+func TestSuggest_Offline(t *testing.T) {
+	defer InitGeocoder(nil)
+	g := &fakeGeocoder{}
+	InitGeocoder(g)
+	assert.Equal(t, "London", Suggest(bufio.NewReader(strings.NewReader("y\n")), "Londn"))
+	assert.Equal(t, "Mumbai", Suggest(bufio.NewReader(strings.NewReader("")), "Mumbai"))
+	assert.Zero(t, g.calls)
+}
+
+// TestNewGazetteer_Invalid checks malformed data is rejected.
+func TestNewGazetteer_Invalid(t *testing.T) {
+	_, err := NewGazetteer("Oslo\tOslo\t\t59.9\t10.7\tNO\tOslo\n")
+	assert.Error(t, err)
+}
@@ -0,0 +1,165 @@
+package geo
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"weatherapp/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGeocoder returns fixed candidates and counts lookups.
+type fakeGeocoder struct {
+	places []Place
+	calls  int
+}
+
+func (f *fakeGeocoder) Geocode(ctx context.Context, query string) ([]Place, error) {
+	f.calls++
+	return f.places, nil
+}
+
+var springfields = []Place{
+	{Name: "Springfield", Region: "Illinois", Country: "United States", Lat: 39.80, Lon: -89.64},
+	{Name: "Springfield", Region: "Missouri", Country: "United States", Lat: 37.21, Lon: -93.29},
+}
+
+// TestOpenMeteoGeocoder checks the search request and response mapping.
+func TestOpenMeteoGeocoder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/search", r.URL.Path)
+		assert.Equal(t, "san jose", r.URL.Query().Get("name"))
+		w.Write([]byte(`{"results":[
+			{"name":"San Jose","admin1":"California","country":"United States","latitude":37.34,"longitude":-121.89},
+			{"name":"San José","admin1":"San José","country":"Costa Rica","latitude":9.93,"longitude":-84.08}]}`))
+	}))
+	defer srv.Close()
+
+	places, err := (&OpenMeteoGeocoder{baseURL: srv.URL, client: srv.Client()}).Geocode(context.Background(), "san jose")
+	require.NoError(t, err)
+	require.Len(t, places, 2)
+	assert.Equal(t, "San Jose, California, United States", places[0].String())
+	assert.Equal(t, "San José, Costa Rica", places[1].String())
+	assert.Equal(t, 9.93, places[1].Lat)
+	assert.Equal(t, 3*time.Second, NewOpenMeteoGeocoder(3*time.Second).client.Timeout)
+}
+
+// TestResolve covers picking among ambiguous matches and the fallbacks.
+func TestResolve(t *testing.T) {
+	defer InitGeocoder(nil)
+
+	_, ok := Resolve(bufio.NewReader(strings.NewReader("")), "Springfield")
+	assert.False(t, ok, "no geocoder configured")
+
+	InitGeocoder(&fakeGeocoder{places: springfields})
+	p, ok := Resolve(bufio.NewReader(strings.NewReader("2\n")), "Springfield")
+	require.True(t, ok)
+	assert.Equal(t, "Springfield, Missouri, United States", p.String())
+
+	p, ok = Resolve(bufio.NewReader(strings.NewReader("\n")), "Springfield")
+	require.True(t, ok)
+	assert.Equal(t, "Illinois", p.Region)
+
+	_, ok = Resolve(bufio.NewReader(strings.NewReader("9\n")), "Springfield")
+	assert.False(t, ok)
+
+	_, ok = Resolve(bufio.NewReader(strings.NewReader("")), "48.85,2.35")
+	assert.False(t, ok, "coordinates are used as typed")
+}
+
+// TestCandidates covers geocoder matches, gazetteer typo suggestions and
+// queries that are kept as typed.
+func TestCandidates(t *testing.T) {
+	defer InitGeocoder(nil)
+	ctx := context.Background()
+
+	InitGeocoder(&fakeGeocoder{places: springfields})
+	places, exact := Candidates(ctx, "Springfield")
+	assert.False(t, exact)
+	assert.Equal(t, springfields, places)
+
+	InitGeocoder(&fakeGeocoder{places: springfields[:1]})
+	places, exact = Candidates(ctx, "Springfield, IL")
+	assert.True(t, exact)
+	assert.Len(t, places, 1)
+
+	InitGeocoder(&fakeGeocoder{})
+	places, exact = Candidates(ctx, "Pariss")
+	assert.False(t, exact)
+	require.NotEmpty(t, places)
+	assert.Equal(t, "Paris", places[0].Name)
+
+	places, _ = Candidates(ctx, "Paris")
+	assert.Empty(t, places, "known names are kept as typed")
+	places, _ = Candidates(ctx, "48.85,2.35")
+	assert.Empty(t, places)
+	places, _ = Candidates(ctx, "auto")
+	assert.Empty(t, places)
+}
+
+// TestParseCoords checks valid pairs and rejects names and out-of-range values.
+func TestParseCoords(t *testing.T) {
+	lat, lon, ok := ParseCoords(" -33.87, 151.21 ")
+	assert.True(t, ok)
+	assert.Equal(t, -33.87, lat)
+	assert.Equal(t, 151.21, lon)
+
+	for _, s := range []string{"Paris", "Paris, FR", "91,0", "0,181"} {
+		_, _, ok := ParseCoords(s)
+		assert.False(t, ok, s)
+	}
+	assert.Equal(t, "-33.8700,151.2100", FormatCoords(-33.87, 151.21))
+}
+
+// TestParseLocation checks each input form is normalized.
+func TestParseLocation(t *testing.T) {
+	l := ParseLocation("Auto")
+	assert.True(t, l.Auto)
+
+	l = ParseLocation("51.5,-0.12")
+	require.NotNil(t, l.Coords)
+	assert.Equal(t, "51.5000,-0.1200", l.QueryString())
+
+	l = ParseLocation("560001, in")
+	assert.Equal(t, "560001", l.PostalCode)
+	assert.Equal(t, "IN", l.Country)
+	assert.Equal(t, "560001,IN", l.QueryString())
+
+	l = ParseLocation("Paris, FR")
+	assert.Equal(t, "Paris, FR", l.Query)
+	assert.Empty(t, l.PostalCode)
+
+	l = FromPreferences(models.Preferences{Location: "Paris, Texas", Coords: &models.Coordinates{Lat: 33.66, Lon: -95.56}})
+	assert.Equal(t, "Paris, Texas", l.String())
+	assert.Equal(t, "33.6600,-95.5600", l.QueryString())
+}
+
+// TestIPAPILocator checks "auto" locations are resolved from a stand-in service.
+func TestIPAPILocator(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/json/", r.URL.Path)
+		w.Write([]byte(`{"city":"Bengaluru","region":"Karnataka","country_name":"India","latitude":12.97,"longitude":77.59}`))
+	}))
+	defer srv.Close()
+
+	orig := ipLocator.Load()
+	defer ipLocator.Store(orig)
+	InitIPLocator(&IPAPILocator{baseURL: srv.URL, client: srv.Client()})
+	assert.Equal(t, 3*time.Second, NewIPAPILocator(3*time.Second).client.Timeout)
+
+	l, err := Locate(context.Background(), ParseLocation("auto"))
+	require.NoError(t, err)
+	assert.Equal(t, "Bengaluru, Karnataka, India", l.String())
+	assert.Equal(t, &models.Coordinates{Lat: 12.97, Lon: 77.59}, l.Coords)
+
+	same, err := Locate(context.Background(), ParseLocation("Pune"))
+	require.NoError(t, err)
+	assert.Equal(t, "Pune", same.Query)
+}
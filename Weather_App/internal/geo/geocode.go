@@ -0,0 +1,145 @@
+package geo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"weatherapp/models"
+)
+
+// Place is a geocoded candidate for a location query.
+type Place struct {
+	Name    string
+	Region  string
+	Country string
+	Lat     float64
+	Lon     float64
+	// TimeZone is the IANA zone name, when the source reports one.
+	TimeZone string
+}
+
+// String returns the canonical display name, e.g. "Springfield, Illinois, United States".
+func (p Place) String() string {
+	parts := []string{p.Name}
+	if p.Region != "" && p.Region != p.Name {
+		parts = append(parts, p.Region)
+	}
+	if p.Country != "" {
+		parts = append(parts, p.Country)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Coordinates returns the Place's position.
+func (p Place) Coordinates() *models.Coordinates {
+	return &models.Coordinates{Lat: p.Lat, Lon: p.Lon}
+}
+
+// Geocoder turns a free-text location into candidate places.
+type Geocoder interface {
+	Geocode(ctx context.Context, query string) ([]Place, error)
+}
+
+// geocoder holds the active Geocoder, boxed for atomic.Pointer, so that it
+// can be swapped when the weather provider is.
+var geocoder atomic.Pointer[geocoderBox]
+
+type geocoderBox struct{ g Geocoder }
+
+// InitGeocoder sets the active Geocoder. It is safe to call while other
+// goroutines geocode.
+func InitGeocoder(g Geocoder) {
+	geocoder.Store(&geocoderBox{g})
+}
+
+// Active returns the active Geocoder, or nil if none is configured.
+func Active() Geocoder {
+	if b := geocoder.Load(); b != nil {
+		return b.g
+	}
+	return nil
+}
+
+// FormatCoords renders a "lat,lon" pair as accepted by ParseCoords.
+func FormatCoords(lat, lon float64) string {
+	return strconv.FormatFloat(lat, 'f', 4, 64) + "," + strconv.FormatFloat(lon, 'f', 4, 64)
+}
+
+// ParseCoords parses "lat,lon", reporting false if s is not a coordinate pair.
+func ParseCoords(s string) (lat, lon float64, ok bool) {
+	a, b, found := strings.Cut(s, ",")
+	if !found {
+		return 0, 0, false
+	}
+	lat, err1 := strconv.ParseFloat(strings.TrimSpace(a), 64)
+	lon, err2 := strconv.ParseFloat(strings.TrimSpace(b), 64)
+	if err1 != nil || err2 != nil || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return 0, 0, false
+	}
+	return lat, lon, true
+}
+
+const openMeteoGeocodeURL = "https://geocoding-api.open-meteo.com"
+
+// OpenMeteoGeocoder uses the keyless Open-Meteo geocoding API.
+type OpenMeteoGeocoder struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewOpenMeteoGeocoder creates an OpenMeteoGeocoder whose requests give up
+// after timeout.
+func NewOpenMeteoGeocoder(timeout time.Duration) *OpenMeteoGeocoder {
+	return &OpenMeteoGeocoder{baseURL: openMeteoGeocodeURL, client: &http.Client{Timeout: timeout}}
+}
+
+// Geocode returns up to ten places matching query.
+func (o *OpenMeteoGeocoder) Geocode(ctx context.Context, query string) ([]Place, error) {
+	searchURL := fmt.Sprintf("%s/v1/search?count=10&format=json&name=%s", o.baseURL, url.QueryEscape(query))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, searchURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("geocoding request failed: %s", resp.Status)
+	}
+
+	var r struct {
+		Results []struct {
+			Name      string  `json:"name"`
+			Admin1    string  `json:"admin1"`
+			Country   string  `json:"country"`
+			Latitude  float64 `json:"latitude"`
+			Longitude float64 `json:"longitude"`
+			Timezone  string  `json:"timezone"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+	var out []Place
+	for _, res := range r.Results {
+		out = append(out, Place{
+			Name:    res.Name,
+			Region:  res.Admin1,
+			Country: res.Country,
+			Lat:     res.Latitude,
+			Lon:     res.Longitude,
+
+			TimeZone: res.Timezone,
+		})
+	}
+	return out, nil
+}
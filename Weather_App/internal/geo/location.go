@@ -0,0 +1,179 @@
+package geo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"weatherapp/models"
+)
+
+// Auto is the location input that asks for the position of the public IP.
+const Auto = "auto"
+
+// Location is the normalized form of every kind of location input: a place
+// name, "lat,lon" coordinates, a postal code with country, or "auto".
+// Exactly one of Coords, PostalCode or Query identifies the place; Name is
+// for display only. TimeZone is the IANA zone when it is already known.
+type Location struct {
+	Name       string
+	Query      string
+	Coords     *models.Coordinates
+	PostalCode string
+	Country    string
+	Auto       bool
+	TimeZone   string
+}
+
+// postalPattern matches "10001,US", "SW1A 1AA, GB" or "560001 IN".
+var postalPattern = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9 -]{1,9}[A-Za-z0-9])\s*[, ]\s*([A-Za-z]{2})$`)
+
+// ParseLocation normalizes free-text location input. An "auto" location
+// must be passed through Locate before it can be queried.
+func ParseLocation(s string) Location {
+	s = strings.TrimSpace(s)
+	if strings.EqualFold(s, Auto) {
+		return Location{Name: Auto, Auto: true}
+	}
+	if lat, lon, ok := ParseCoords(s); ok {
+		return Location{Name: s, Coords: &models.Coordinates{Lat: lat, Lon: lon}}
+	}
+	if m := postalPattern.FindStringSubmatch(s); m != nil && strings.ContainsAny(m[1], "0123456789") {
+		code, country := strings.ToUpper(m[1]), strings.ToUpper(m[2])
+		return Location{Name: code + ", " + country, PostalCode: code, Country: country}
+	}
+	return Location{Name: s, Query: s}
+}
+
+// FromPreferences returns a User's preferred Location, using the stored
+// coordinates when it has been resolved.
+func FromPreferences(p models.Preferences) Location {
+	return fromStored(p.Location, p.Coords)
+}
+
+// FromSaved returns the Location of a saved favorite.
+func FromSaved(s models.SavedLocation) Location {
+	return fromStored(s.Location, s.Coords)
+}
+
+func fromStored(name string, c *models.Coordinates) Location {
+	if c != nil {
+		return Location{Name: name, Coords: c}
+	}
+	return ParseLocation(name)
+}
+
+// String returns the display name.
+func (l Location) String() string {
+	return l.Name
+}
+
+// QueryString renders the Location as a single search string accepted by
+// most providers: "lat,lon", "code,CC" or the name as typed.
+func (l Location) QueryString() string {
+	switch {
+	case l.Coords != nil:
+		return FormatCoords(l.Coords.Lat, l.Coords.Lon)
+	case l.PostalCode != "":
+		return l.PostalCode + "," + l.Country
+	default:
+		return l.Query
+	}
+}
+
+// Location converts a geocoded Place into an exact Location.
+func (p Place) Location() Location {
+	return Location{Name: p.String(), Coords: p.Coordinates(), TimeZone: p.TimeZone}
+}
+
+// IPLocator works out the location of the machine's public IP address.
+type IPLocator interface {
+	LocateIP(ctx context.Context) (Location, error)
+}
+
+// ipLocator holds the active IPLocator, boxed for atomic.Pointer, so that
+// it can be replaced when the configuration is reloaded.
+var ipLocator atomic.Pointer[ipLocatorBox]
+
+type ipLocatorBox struct{ l IPLocator }
+
+func init() {
+	InitIPLocator(NewIPAPILocator(10 * time.Second))
+}
+
+// InitIPLocator sets the IPLocator used for "auto" locations. It is safe to
+// call while other goroutines locate.
+func InitIPLocator(l IPLocator) {
+	ipLocator.Store(&ipLocatorBox{l})
+}
+
+// Locate resolves an "auto" Location through the active IPLocator; any
+// other Location is returned unchanged.
+func Locate(ctx context.Context, l Location) (Location, error) {
+	if !l.Auto {
+		return l, nil
+	}
+	b := ipLocator.Load()
+	if b == nil || b.l == nil {
+		return Location{}, errors.New("automatic location is not configured")
+	}
+	return b.l.LocateIP(ctx)
+}
+
+const ipAPIURL = "https://ipapi.co"
+
+// IPAPILocator uses the ipapi.co JSON service.
+type IPAPILocator struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewIPAPILocator creates an IPAPILocator whose requests give up after
+// timeout.
+func NewIPAPILocator(timeout time.Duration) *IPAPILocator {
+	return &IPAPILocator{baseURL: ipAPIURL, client: &http.Client{Timeout: timeout}}
+}
+
+// LocateIP looks up the caller's public IP address.
+func (i *IPAPILocator) LocateIP(ctx context.Context) (Location, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, i.baseURL+"/json/", nil)
+	if err != nil {
+		return Location{}, err
+	}
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return Location{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Location{}, fmt.Errorf("IP geolocation failed: %s", resp.Status)
+	}
+
+	var r struct {
+		City        string  `json:"city"`
+		Region      string  `json:"region"`
+		CountryName string  `json:"country_name"`
+		Latitude    float64 `json:"latitude"`
+		Longitude   float64 `json:"longitude"`
+		Timezone    string  `json:"timezone"`
+		Error       bool    `json:"error"`
+		Reason      string  `json:"reason"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return Location{}, err
+	}
+	if r.Error {
+		return Location{}, fmt.Errorf("IP geolocation failed: %s", r.Reason)
+	}
+	p := Place{
+		Name: r.City, Region: r.Region, Country: r.CountryName,
+		Lat: r.Latitude, Lon: r.Longitude, TimeZone: r.Timezone,
+	}
+	return p.Location(), nil
+}
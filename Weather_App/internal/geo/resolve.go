@@ -0,0 +1,82 @@
+package geo
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"weatherapp/internal/i18n"
+)
+
+// Resolve geocodes a place name with the active Geocoder and, when several
+// places match, asks the user to pick one. It returns false when the query
+// should be used as typed: coordinates, postal codes and "auto" are already
+// exact, and names that fail to resolve are kept verbatim.
+func Resolve(reader *bufio.Reader, query string) (Place, bool) {
+	geocoder := Active()
+	if geocoder == nil || ParseLocation(query).Query == "" {
+		return Place{}, false
+	}
+	places, err := geocoder.Geocode(context.Background(), query)
+	if err != nil {
+		i18n.Println("Could not look up location: %v", err)
+		return Place{}, false
+	}
+	switch len(places) {
+	case 0:
+		i18n.Println("No places matched %q; using it as entered", query)
+		return Place{}, false
+	case 1:
+		return places[0], true
+	}
+
+	i18n.Println("Several places match %q:", query)
+	for i, p := range places {
+		fmt.Printf("  %d. %s (%.2f, %.2f)\n", i+1, p, p.Lat, p.Lon)
+	}
+	i18n.Printf("Choose a place [1]: ")
+	choice, _ := reader.ReadString('\n')
+	choice = strings.TrimSpace(choice)
+	if choice == "" {
+		return places[0], true
+	}
+	n, err := strconv.Atoi(choice)
+	if err != nil || n < 1 || n > len(places) {
+		i18n.Println("Invalid choice; using the location as entered")
+		return Place{}, false
+	}
+	return places[n-1], true
+}
+
+// Candidates returns the places a location query may mean, for front ends
+// that offer a choice instead of prompting on stdin. Exact is true when
+// the geocoder found exactly one place, which can be taken without asking.
+// When the geocoder finds nothing, close gazetteer matches for a
+// misspelled city name are offered. Coordinates, postal codes, "auto" and
+// known names that fail to resolve yield no candidates and are kept as
+// typed.
+func Candidates(ctx context.Context, query string) (places []Place, exact bool) {
+	if ParseLocation(query).Query == "" {
+		return nil, false
+	}
+	if geocoder := Active(); geocoder != nil {
+		var err error
+		if places, err = geocoder.Geocode(ctx, query); err != nil {
+			slog.WarnContext(ctx, "geocoding failed; trying the gazetteer", "query", query, "error", err)
+		}
+	}
+	if len(places) > 0 {
+		return places, len(places) == 1
+	}
+	name, _, _ := strings.Cut(query, ",")
+	for _, m := range Cities().Search(name, 5) {
+		if m.Distance == 0 && !m.Alternate && len(places) == 0 {
+			return nil, false
+		}
+		places = append(places, m.City.Place())
+	}
+	return places, false
+}
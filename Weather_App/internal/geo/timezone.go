@@ -0,0 +1,116 @@
+package geo
+
+import (
+	"math"
+	"time"
+)
+
+// earthRadiusKm is the mean radius used for great-circle distances.
+const earthRadiusKm = 6371.0
+
+// nearbyKm is how far from a gazetteer city a position may be and still
+// borrow its time zone.
+const nearbyKm = 150.0
+
+// Nearest returns the gazetteer city closest to lat/lon, if one lies
+// within maxKm.
+func (g *Gazetteer) Nearest(lat, lon, maxKm float64) (City, bool) {
+	best, bestKm := -1, maxKm
+	for i, c := range g.cities {
+		if d := DistanceKm(lat, lon, c.Lat, c.Lon); d <= bestKm {
+			best, bestKm = i, d
+		}
+	}
+	if best < 0 {
+		return City{}, false
+	}
+	return g.cities[best], true
+}
+
+// Lookup finds the gazetteer city for a Location, by position when it has
+// coordinates and otherwise by exact name.
+func (g *Gazetteer) Lookup(l Location) (City, bool) {
+	if l.Coords != nil {
+		return g.Nearest(l.Coords.Lat, l.Coords.Lon, nearbyKm)
+	}
+	if l.Query == "" {
+		return City{}, false
+	}
+	m := g.Search(l.Query, 1)
+	if len(m) == 0 || m[0].Distance > 0 {
+		return City{}, false
+	}
+	return m[0].City, true
+}
+
+// Zone returns the time zone of a Location: zoneName when a provider
+// reported one, else the Location's own zone, else the gazetteer's, and
+// finally the machine's local zone.
+func Zone(l Location, zoneName string) *time.Location {
+	for _, name := range []string{zoneName, l.TimeZone} {
+		if name == "" {
+			continue
+		}
+		if z, err := time.LoadLocation(name); err == nil {
+			return z
+		}
+	}
+	if c, ok := Cities().Lookup(l); ok {
+		if z, err := time.LoadLocation(c.TimeZone); err == nil {
+			return z
+		}
+	}
+	return time.Local
+}
+
+// Position returns the coordinates of a Location, falling back to the
+// gazetteer for place names.
+func Position(l Location) (lat, lon float64, ok bool) {
+	if l.Coords != nil {
+		return l.Coords.Lat, l.Coords.Lon, true
+	}
+	if c, found := Cities().Lookup(l); found {
+		return c.Lat, c.Lon, true
+	}
+	return 0, 0, false
+}
+
+// DistanceKm is the great-circle distance between two positions.
+func DistanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// SunTimes computes sunrise and sunset for the calendar day of day (in its
+// own zone) at lat/lon using the NOAA sunrise equation, accurate to a couple
+// of minutes. ok is false during polar day or night. The results are in
+// day's zone.
+func SunTimes(day time.Time, lat, lon float64) (rise, set time.Time, ok bool) {
+	rad := math.Pi / 180
+	noon := time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, time.UTC)
+	julian := float64(noon.Unix())/86400 + 2440587.5
+	n := math.Round(julian - 2451545.0 + 0.0008)
+
+	meanNoon := n - lon/360
+	m := math.Mod(357.5291+0.98560028*meanNoon, 360)
+	c := 1.9148*math.Sin(m*rad) + 0.02*math.Sin(2*m*rad) + 0.0003*math.Sin(3*m*rad)
+	lambda := math.Mod(m+c+180+102.9372, 360)
+	transit := 2451545.0 + meanNoon + 0.0053*math.Sin(m*rad) - 0.0069*math.Sin(2*lambda*rad)
+
+	sinDecl := math.Sin(lambda*rad) * math.Sin(23.4397*rad)
+	cosDecl := math.Cos(math.Asin(sinDecl))
+	cosHour := (math.Sin(-0.833*rad) - math.Sin(lat*rad)*sinDecl) / (math.Cos(lat*rad) * cosDecl)
+	if cosHour < -1 || cosHour > 1 {
+		return time.Time{}, time.Time{}, false
+	}
+	hour := math.Acos(cosHour) / rad
+
+	toTime := func(j float64) time.Time {
+		return time.Unix(int64(math.Round((j-2440587.5)*86400)), 0).In(day.Location())
+	}
+	return toTime(transit - hour/360), toTime(transit + hour/360), true
+}
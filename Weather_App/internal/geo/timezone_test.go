@@ -0,0 +1,38 @@
+package geo
+
+import (
+	"testing"
+	"time"
+
+	"weatherapp/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestZone checks the provider zone wins, then the Location's own zone, then
+// the nearest gazetteer city.
+func TestZone(t *testing.T) {
+	tokyo := Location{Name: "x", Coords: &models.Coordinates{Lat: 35.69, Lon: 139.69}}
+	assert.Equal(t, "Asia/Tokyo", Zone(tokyo, "").String())
+	assert.Equal(t, "Europe/Paris", Zone(tokyo, "Europe/Paris").String())
+
+	tokyo.TimeZone = "UTC"
+	assert.Equal(t, "UTC", Zone(tokyo, "").String())
+
+	assert.Equal(t, "Europe/London", Zone(ParseLocation("london"), "").String())
+}
+
+// TestSunTimes compares against published sunrise and sunset times.
+func TestSunTimes(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+	rise, set, ok := SunTimes(time.Date(2026, 6, 21, 12, 0, 0, 0, london), 51.5074, -0.1278)
+	require.True(t, ok)
+	assert.Equal(t, "04:43", rise.In(london).Format("15:04"))
+	assert.Equal(t, "21:21", set.In(london).Format("15:04"))
+
+	// Midnight sun: no sunrise or sunset.
+	_, _, ok = SunTimes(time.Date(2026, 6, 21, 12, 0, 0, 0, time.UTC), 78.2, 15.6)
+	assert.False(t, ok)
+}
@@ -0,0 +1,80 @@
+// Package health serves the liveness and readiness probes. /healthz only
+// says the process is up; /readyz runs the dependency checks, each of which
+// must be cheap, since probes run every few seconds, and must not spend
+// provider API quota.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Timeout bounds each readiness check.
+const Timeout = 2 * time.Second
+
+// Check is one readiness check, e.g. that the user store answers.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// report is the JSON body of both probes. Checks maps each check's name
+// to "ok" or its error.
+type report struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Register adds GET /healthz and GET /readyz, running checks, to mux.
+func Register(mux *http.ServeMux, checks ...Check) {
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		write(w, http.StatusOK, report{Status: "ok"})
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		status, rep := ready(r.Context(), checks)
+		write(w, status, rep)
+	})
+}
+
+// ready runs checks concurrently and returns 200 if all passed and 503
+// otherwise, with the report to send.
+func ready(ctx context.Context, checks []Check) (int, report) {
+	type result struct {
+		name string
+		err  error
+	}
+	results := make(chan result, len(checks))
+	for _, c := range checks {
+		go func() {
+			ctx, cancel := context.WithTimeout(ctx, Timeout)
+			defer cancel()
+			results <- result{c.Name, c.Run(ctx)}
+		}()
+	}
+	rep := report{Status: "ok", Checks: map[string]string{}}
+	status := http.StatusOK
+	for range checks {
+		r := <-results
+		if r.err != nil {
+			slog.WarnContext(ctx, "readiness check failed", "check", r.name, "error", r.err)
+			rep.Checks[r.name] = r.err.Error()
+			rep.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+			continue
+		}
+		rep.Checks[r.name] = "ok"
+	}
+	return status, rep
+}
+
+func write(w http.ResponseWriter, status int, rep report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(rep); err != nil {
+		slog.Debug("writing response", "error", err)
+	}
+}
@@ -0,0 +1,63 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// get serves a GET for path and decodes the report.
+func get(t *testing.T, mux *http.ServeMux, path string) (int, report) {
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	var rep report
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rep))
+	return rec.Code, rep
+}
+
+// TestProbes checks /healthz ignores the checks while /readyz reports
+// each one and fails if any does.
+func TestProbes(t *testing.T) {
+	storageErr := errors.New("Firestore is not connected")
+	mux := http.NewServeMux()
+	Register(mux,
+		Check{Name: "storage", Run: func(context.Context) error { return storageErr }},
+		Check{Name: "provider", Run: func(context.Context) error { return nil }},
+	)
+
+	code, rep := get(t, mux, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", rep.Status)
+
+	code, rep = get(t, mux, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, report{
+		Status: "unavailable",
+		Checks: map[string]string{"storage": "Firestore is not connected", "provider": "ok"},
+	}, rep)
+
+	storageErr = nil
+	code, rep = get(t, mux, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", rep.Status)
+}
+
+// TestReady_Timeout checks a hung dependency fails its check instead of
+// the probe.
+func TestReady_Timeout(t *testing.T) {
+	hung := Check{Name: "storage", Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	code, rep := ready(ctx, []Check{hung})
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, context.Canceled.Error(), rep.Checks["storage"])
+}
@@ -0,0 +1,246 @@
+package i18n
+
+import "golang.org/x/text/language"
+
+// translations maps each message, by its English text, to its wording in
+// the other supported languages. Labels that are lined up in reports are
+// padded by the renderer, and menu letters such as "a = add" stay the same
+// in every language since they are what the user types.
+var translations = map[language.Tag]map[string]string{
+	language.German: {
+		// Menus and prompts.
+		"=== Weather CLI App ===":  "=== Wetter-App ===",
+		"1. Register":              "1. Registrieren",
+		"2. Login":                 "2. Anmelden",
+		"3. Exit":                  "3. Beenden",
+		"Enter choice: ":           "Auswahl eingeben: ",
+		"Exiting...":               "Wird beendet ...",
+		"Invalid option":           "Ungültige Option",
+		"Invalid choice":           "Ungültige Auswahl",
+		"=== Dashboard ===":        "=== Übersicht ===",
+		"1. View My Weather":       "1. Mein Wetter anzeigen",
+		"2. Change Preferences":    "2. Einstellungen ändern",
+		"3. View Other Locations":  "3. Andere Orte anzeigen",
+		"4. List Users":            "4. Benutzer auflisten",
+		"5. Manage Alert Rules":    "5. Warnregeln verwalten",
+		"6. Notification Settings": "6. Benachrichtigungen",
+		"7. Saved Locations":       "7. Gespeicherte Orte",
+		"8. Logout":                "8. Abmelden",
+		"Error fetching user: %v":  "Fehler beim Laden des Benutzers: %v",
+		"Error: %v":                "Fehler: %v",
+
+		"Enter UserID: ":                "Benutzer-ID eingeben: ",
+		"Enter Name (Username): ":       "Name (Benutzername) eingeben: ",
+		"Enter Password: ":              "Passwort eingeben: ",
+		"Enter Username: ":              "Benutzername eingeben: ",
+		"Error saving user: %v":         "Fehler beim Speichern des Benutzers: %v",
+		"User registered successfully!": "Benutzer erfolgreich registriert!",
+		"Invalid credentials":           "Ungültige Anmeldedaten",
+		"Login successful!":             "Anmeldung erfolgreich!",
+		"User not found":                "Benutzer nicht gefunden",
+		"UserID: %s, Name: %s":          "Benutzer-ID: %s, Name: %s",
+		"Error loading users: %v":       "Fehler beim Laden der Benutzer: %v",
+		"Error saving preferences: %v":  "Fehler beim Speichern der Einstellungen: %v",
+
+		"Preferences updated":                  "Einstellungen gespeichert",
+		"Please set your weather preferences:": "Bitte lege deine Wettereinstellungen fest:",
+		"Enter your location (city, \"lat,lon\", postal code with country, or auto): ":          "Ort eingeben (Stadt, \"Breite,Länge\", Postleitzahl mit Land oder auto): ",
+		"Units (metric/imperial/uk/si, optionally followed by overrides like \", speed=kn\"): ": "Einheiten (metric/imperial/uk/si, optional gefolgt von Abweichungen wie \", speed=kn\"): ",
+		"Verbosity (brief/verbose): ":                       "Ausführlichkeit (brief/verbose): ",
+		"Forecast (day/week/month): ":                       "Vorhersage (day/week/month): ",
+		"Output format (text/chart/json/yaml/csv) [text]: ": "Ausgabeformat (text/chart/json/yaml/csv) [text]: ",
+		"Language (%s, blank to follow the system): ":       "Sprache (%s, leer für die Systemsprache): ",
+
+		"=== Alert Rules ===":             "=== Warnregeln ===",
+		"(no rules)":                      "(keine Regeln)",
+		"a = add, d = delete, b = back: ": "a = hinzufügen, d = löschen, b = zurück: ",
+		"Rule (e.g. \"temp_max > 35 tomorrow\" or \"condition = thunder\"; metrics: %s): ": "Regel (z. B. \"temp_max > 35 tomorrow\" oder \"condition = thunder\"; Messgrößen: %s): ",
+		"Invalid rule: %v":        "Ungültige Regel: %v",
+		"Rule number to delete: ": "Nummer der zu löschenden Regel: ",
+		"Invalid rule number":     "Ungültige Regelnummer",
+		"Error saving rules: %v":  "Fehler beim Speichern der Regeln: %v",
+
+		"=== Saved Locations ===": "=== Gespeicherte Orte ===",
+		"(none)":                  "(keine)",
+		"(default)":               "(Standard)",
+		"v = view all, a = add, r = remove, m = move, d = set default, b = back: ": "v = alle anzeigen, a = hinzufügen, r = entfernen, m = verschieben, d = als Standard, b = zurück: ",
+		"Name (e.g. Home, Office): ":       "Name (z. B. Zuhause, Büro): ",
+		"Location: ":                       "Ort: ",
+		"Number to remove: ":               "Nummer zum Entfernen: ",
+		"Number to move: ":                 "Nummer zum Verschieben: ",
+		"New position: ":                   "Neue Position: ",
+		"Number to make default: ":         "Nummer des neuen Standardorts: ",
+		"Error saving locations: %v":       "Fehler beim Speichern der Orte: %v",
+		"Enter location: ":                 "Ort eingeben: ",
+		"No location entered.":             "Kein Ort eingegeben.",
+		"No saved locations.":              "Keine gespeicherten Orte.",
+		"NAME\tLOCATION\tCONDITIONS\tTEMP": "NAME\tORT\tWETTER\tTEMP",
+
+		"Leave a field blank to disable that channel.": "Lass ein Feld leer, um diesen Kanal abzuschalten.",
+		"Email address: ":                                "E-Mail-Adresse: ",
+		"Webhook URL (Slack-compatible): ":               "Webhook-URL (Slack-kompatibel): ",
+		"%v; webhook disabled":                           "%v; Webhook abgeschaltet",
+		"Append to file: ":                               "An Datei anhängen: ",
+		"%v; file disabled":                              "%v; Datei abgeschaltet",
+		"Also print to stdout (y/n): ":                   "Auch auf stdout ausgeben (y/n): ",
+		"Daily digest time (HH:MM, blank to disable): ":  "Uhrzeit der Tagesübersicht (HH:MM, leer zum Abschalten): ",
+		"Invalid time; digest disabled":                  "Ungültige Uhrzeit; Tagesübersicht abgeschaltet",
+		"Time zone (e.g. Asia/Kolkata, blank for UTC): ": "Zeitzone (z. B. Europe/Berlin, leer für UTC): ",
+		"Unknown time zone; using UTC":                   "Unbekannte Zeitzone; UTC wird verwendet",
+		"Error saving notification settings: %v":         "Fehler beim Speichern der Benachrichtigungen: %v",
+		"Notification settings updated":                  "Benachrichtigungen gespeichert",
+
+		"Could not look up location: %v":                "Ort konnte nicht nachgeschlagen werden: %v",
+		"No places matched %q; using it as entered":     "Kein Ort passt zu %q; die Eingabe wird übernommen",
+		"Several places match %q:":                      "Mehrere Orte passen zu %q:",
+		"Choose a place [1]: ":                          "Ort auswählen [1]: ",
+		"Invalid choice; using the location as entered": "Ungültige Auswahl; die Eingabe wird übernommen",
+		"Did you mean %s? [y/N]: ":                      "Meintest du %s? [y/N]: ",
+
+		// Reports.
+		"Weather for %s":                     "Wetter für %s",
+		"Forecast for %s (%s)":               "Vorhersage für %s (%s)",
+		"Location: %s | %s | %s":             "Ort: %s | %s | %s",
+		"!!! %d active weather alert(s) !!!": "!!! %d aktive Unwetterwarnung(en) !!!",
+		" (until %s)":                        " (bis %s)",
+		"%.0f%% humidity":                    "%.0f%% Luftfeuchte",
+		"Local time":                         "Ortszeit",
+		"Description":                        "Wetterlage",
+		"Temperature":                        "Temperatur",
+		"Sunrise":                            "Sonnenaufgang",
+		"Sunset":                             "Sonnenuntergang",
+		"Feels Like":                         "Gefühlt",
+		"Feels like":                         "Gefühlt",
+		"Humidity":                           "Luftfeuchte",
+		"Wind":                               "Wind",
+		"Pressure":                           "Luftdruck",
+		"Precip":                             "Niederschlag",
+		"Precipitation":                      "Niederschlag",
+		"Visibility":                         "Sichtweite",
+		"day":                                "Tag",
+		"week":                               "Woche",
+		"month":                              "Monat",
+
+		"Mon": "Mo.", "Tue": "Di.", "Wed": "Mi.", "Thu": "Do.", "Fri": "Fr.", "Sat": "Sa.", "Sun": "So.",
+		"Jan": "Jan.", "Feb": "Feb.", "Mar": "März", "Apr": "Apr.", "May": "Mai", "Jun": "Juni",
+		"Jul": "Juli", "Aug": "Aug.", "Sep": "Sep.", "Oct": "Okt.", "Nov": "Nov.", "Dec": "Dez.",
+	},
+	language.French: {
+		// Menus and prompts.
+		"=== Weather CLI App ===":  "=== Application météo ===",
+		"1. Register":              "1. Créer un compte",
+		"2. Login":                 "2. Se connecter",
+		"3. Exit":                  "3. Quitter",
+		"Enter choice: ":           "Votre choix : ",
+		"Exiting...":               "Au revoir...",
+		"Invalid option":           "Option invalide",
+		"Invalid choice":           "Choix invalide",
+		"=== Dashboard ===":        "=== Tableau de bord ===",
+		"1. View My Weather":       "1. Voir ma météo",
+		"2. Change Preferences":    "2. Modifier les préférences",
+		"3. View Other Locations":  "3. Voir d'autres lieux",
+		"4. List Users":            "4. Liste des utilisateurs",
+		"5. Manage Alert Rules":    "5. Gérer les règles d'alerte",
+		"6. Notification Settings": "6. Notifications",
+		"7. Saved Locations":       "7. Lieux enregistrés",
+		"8. Logout":                "8. Se déconnecter",
+		"Error fetching user: %v":  "Erreur lors du chargement de l'utilisateur : %v",
+		"Error: %v":                "Erreur : %v",
+
+		"Enter UserID: ":                "Identifiant : ",
+		"Enter Name (Username): ":       "Nom (nom d'utilisateur) : ",
+		"Enter Password: ":              "Mot de passe : ",
+		"Enter Username: ":              "Nom d'utilisateur : ",
+		"Error saving user: %v":         "Erreur lors de l'enregistrement de l'utilisateur : %v",
+		"User registered successfully!": "Compte créé !",
+		"Invalid credentials":           "Identifiants invalides",
+		"Login successful!":             "Connexion réussie !",
+		"User not found":                "Utilisateur introuvable",
+		"UserID: %s, Name: %s":          "Identifiant : %s, nom : %s",
+		"Error loading users: %v":       "Erreur lors du chargement des utilisateurs : %v",
+		"Error saving preferences: %v":  "Erreur lors de l'enregistrement des préférences : %v",
+
+		"Preferences updated":                  "Préférences enregistrées",
+		"Please set your weather preferences:": "Veuillez choisir vos préférences météo :",
+		"Enter your location (city, \"lat,lon\", postal code with country, or auto): ":          "Votre lieu (ville, \"lat,lon\", code postal avec pays, ou auto) : ",
+		"Units (metric/imperial/uk/si, optionally followed by overrides like \", speed=kn\"): ": "Unités (metric/imperial/uk/si, éventuellement suivies d'exceptions comme \", speed=kn\") : ",
+		"Verbosity (brief/verbose): ":                       "Niveau de détail (brief/verbose) : ",
+		"Forecast (day/week/month): ":                       "Prévisions (day/week/month) : ",
+		"Output format (text/chart/json/yaml/csv) [text]: ": "Format de sortie (text/chart/json/yaml/csv) [text] : ",
+		"Language (%s, blank to follow the system): ":       "Langue (%s, vide pour suivre le système) : ",
+
+		"=== Alert Rules ===":             "=== Règles d'alerte ===",
+		"(no rules)":                      "(aucune règle)",
+		"a = add, d = delete, b = back: ": "a = ajouter, d = supprimer, b = retour : ",
+		"Rule (e.g. \"temp_max > 35 tomorrow\" or \"condition = thunder\"; metrics: %s): ": "Règle (par ex. \"temp_max > 35 tomorrow\" ou \"condition = thunder\" ; mesures : %s) : ",
+		"Invalid rule: %v":        "Règle invalide : %v",
+		"Rule number to delete: ": "Numéro de la règle à supprimer : ",
+		"Invalid rule number":     "Numéro de règle invalide",
+		"Error saving rules: %v":  "Erreur lors de l'enregistrement des règles : %v",
+
+		"=== Saved Locations ===": "=== Lieux enregistrés ===",
+		"(none)":                  "(aucun)",
+		"(default)":               "(par défaut)",
+		"v = view all, a = add, r = remove, m = move, d = set default, b = back: ": "v = tout voir, a = ajouter, r = retirer, m = déplacer, d = par défaut, b = retour : ",
+		"Name (e.g. Home, Office): ":       "Nom (par ex. Maison, Bureau) : ",
+		"Location: ":                       "Lieu : ",
+		"Number to remove: ":               "Numéro à retirer : ",
+		"Number to move: ":                 "Numéro à déplacer : ",
+		"New position: ":                   "Nouvelle position : ",
+		"Number to make default: ":         "Numéro du lieu par défaut : ",
+		"Error saving locations: %v":       "Erreur lors de l'enregistrement des lieux : %v",
+		"Enter location: ":                 "Lieu : ",
+		"No location entered.":             "Aucun lieu saisi.",
+		"No saved locations.":              "Aucun lieu enregistré.",
+		"NAME\tLOCATION\tCONDITIONS\tTEMP": "NOM\tLIEU\tCONDITIONS\tTEMP",
+
+		"Leave a field blank to disable that channel.": "Laissez un champ vide pour désactiver ce canal.",
+		"Email address: ":                                "Adresse e-mail : ",
+		"Webhook URL (Slack-compatible): ":               "URL de webhook (compatible Slack) : ",
+		"%v; webhook disabled":                           "%v ; webhook désactivé",
+		"Append to file: ":                               "Ajouter au fichier : ",
+		"%v; file disabled":                              "%v ; fichier désactivé",
+		"Also print to stdout (y/n): ":                   "Afficher aussi sur stdout (y/n) : ",
+		"Daily digest time (HH:MM, blank to disable): ":  "Heure du résumé quotidien (HH:MM, vide pour désactiver) : ",
+		"Invalid time; digest disabled":                  "Heure invalide ; résumé désactivé",
+		"Time zone (e.g. Asia/Kolkata, blank for UTC): ": "Fuseau horaire (par ex. Europe/Paris, vide pour UTC) : ",
+		"Unknown time zone; using UTC":                   "Fuseau horaire inconnu ; UTC utilisé",
+		"Error saving notification settings: %v":         "Erreur lors de l'enregistrement des notifications : %v",
+		"Notification settings updated":                  "Notifications enregistrées",
+
+		"Could not look up location: %v":                "Impossible de rechercher le lieu : %v",
+		"No places matched %q; using it as entered":     "Aucun lieu ne correspond à %q ; saisie conservée",
+		"Several places match %q:":                      "Plusieurs lieux correspondent à %q :",
+		"Choose a place [1]: ":                          "Choisissez un lieu [1] : ",
+		"Invalid choice; using the location as entered": "Choix invalide ; saisie conservée",
+		"Did you mean %s? [y/N]: ":                      "Vouliez-vous dire %s ? [y/N] : ",
+
+		// Reports.
+		"Weather for %s":                     "Météo pour %s",
+		"Forecast for %s (%s)":               "Prévisions pour %s (%s)",
+		"Location: %s | %s | %s":             "Lieu : %s | %s | %s",
+		"!!! %d active weather alert(s) !!!": "!!! %d alerte(s) météo en cours !!!",
+		" (until %s)":                        " (jusqu'au %s)",
+		"%.0f%% humidity":                    "%.0f %% d'humidité",
+		"Local time":                         "Heure locale",
+		"Description":                        "Conditions",
+		"Temperature":                        "Température",
+		"Sunrise":                            "Lever du soleil",
+		"Sunset":                             "Coucher du soleil",
+		"Feels Like":                         "Ressenti",
+		"Feels like":                         "Ressenti",
+		"Humidity":                           "Humidité",
+		"Wind":                               "Vent",
+		"Pressure":                           "Pression",
+		"Precip":                             "Précipitations",
+		"Precipitation":                      "Précipitations",
+		"Visibility":                         "Visibilité",
+		"day":                                "jour",
+		"week":                               "semaine",
+		"month":                              "mois",
+
+		"Mon": "lun.", "Tue": "mar.", "Wed": "mer.", "Thu": "jeu.", "Fri": "ven.", "Sat": "sam.", "Sun": "dim.",
+		"Jan": "janv.", "Feb": "févr.", "Mar": "mars", "Apr": "avr.", "May": "mai", "Jun": "juin",
+		"Jul": "juil.", "Aug": "août", "Sep": "sept.", "Oct": "oct.", "Nov": "nov.", "Dec": "déc.",
+	},
+}
@@ -0,0 +1,156 @@
+// Package i18n holds the message catalog for the interactive menus and text
+// reports, and picks the language they are shown in. Messages are looked up
+// by their English text, so untranslated ones fall back to English.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/message/catalog"
+)
+
+// Languages lists the supported languages by their BCP 47 code, English,
+// the fallback, first.
+var Languages = []string{"en", "de", "fr"}
+
+var (
+	tags    = []language.Tag{language.English, language.German, language.French}
+	matcher = language.NewMatcher(tags)
+	cat     = catalog.NewBuilder(catalog.Fallback(language.English))
+
+	// current is used for prompts and for Users without a Language
+	// preference; Use changes it.
+	current = language.English
+)
+
+func init() {
+	for tag, messages := range translations {
+		for key, msg := range messages {
+			if err := cat.SetString(tag, key, msg); err != nil {
+				panic(err)
+			}
+		}
+	}
+}
+
+// Parse checks a Language preference and returns its supported code; empty
+// means the default language.
+func Parse(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", nil
+	}
+	tag, err := language.Parse(s)
+	if err == nil {
+		base, _ := tag.Base()
+		for _, l := range Languages {
+			if base.String() == l {
+				return l, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("unsupported language %q (want one of %s)", s, strings.Join(Languages, ", "))
+}
+
+// Match returns the supported language closest to lang, such as German for
+// "de-AT"; empty or unknown values mean the default language.
+func Match(lang string) language.Tag {
+	if strings.TrimSpace(lang) == "" {
+		return current
+	}
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return current
+	}
+	_, i, confidence := matcher.Match(tag)
+	if confidence == language.No {
+		return current
+	}
+	return tags[i]
+}
+
+// Code returns the two-letter code of the language lang resolves to, as
+// sent to the weather providers.
+func Code(lang string) string {
+	base, _ := Match(lang).Base()
+	return base.String()
+}
+
+// Use sets the default language: the one prompts are printed in and reports
+// use for Users without a Language preference.
+func Use(lang string) {
+	current = Match(lang)
+}
+
+// FromEnv returns the language named by the first of WEATHER_LANG,
+// LC_ALL, LC_MESSAGES and LANG that is set, such as "de" for
+// "de_DE.UTF-8".
+func FromEnv(getenv func(string) string) string {
+	for _, name := range []string{"WEATHER_LANG", "LC_ALL", "LC_MESSAGES", "LANG"} {
+		v := getenv(name)
+		if v == "" {
+			continue
+		}
+		v, _, _ = strings.Cut(v, ".")
+		v, _, _ = strings.Cut(v, "@")
+		if v == "C" || v == "POSIX" {
+			return "en"
+		}
+		return strings.ReplaceAll(v, "_", "-")
+	}
+	return ""
+}
+
+// UseEnv sets the default language from the environment.
+func UseEnv() {
+	Use(FromEnv(os.Getenv))
+}
+
+// Printer returns a printer that translates messages into lang.
+func Printer(lang string) *message.Printer {
+	return message.NewPrinter(Match(lang), message.Catalog(cat))
+}
+
+// Printf prints a translated prompt in the default language.
+func Printf(key string, a ...any) {
+	Printer("").Printf(key, a...)
+}
+
+// Println prints a translated line in the default language.
+func Println(key string, a ...any) {
+	p := Printer("")
+	p.Printf(key, a...)
+	p.Println()
+}
+
+// Sprintf returns a translated message in the default language.
+func Sprintf(key string, a ...any) string {
+	return Printer("").Sprintf(key, a...)
+}
+
+// Title capitalizes the first letter of each word the way lang does,
+// leaving the rest as written so "US" stays upper case.
+func Title(lang, s string) string {
+	return cases.Title(Match(lang), cases.NoLower).String(s)
+}
+
+// FormatDate formats t with a time layout, translating the English day
+// and month abbreviations "Mon" and "Jan" it may contain.
+func FormatDate(p *message.Printer, t time.Time, layout string) string {
+	s := t.Format(layout)
+	if strings.Contains(layout, "Mon") {
+		day := t.Format("Mon")
+		s = strings.Replace(s, day, p.Sprintf(day), 1)
+	}
+	if strings.Contains(layout, "Jan") {
+		month := t.Format("Jan")
+		s = strings.Replace(s, month, p.Sprintf(month), 1)
+	}
+	return s
+}
@@ -0,0 +1,70 @@
+package i18n
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParse checks Language preferences are reduced to a supported code.
+func TestParse(t *testing.T) {
+	cases := map[string]string{"": "", "de": "de", " FR ": "fr", "de-AT": "de", "en-GB": "en"}
+	for input, want := range cases {
+		got, err := Parse(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	_, err := Parse("ja")
+	assert.EqualError(t, err, `unsupported language "ja" (want one of en, de, fr)`)
+}
+
+// TestCode checks regional and unknown languages resolve to a supported one.
+func TestCode(t *testing.T) {
+	defer Use("en")
+	assert.Equal(t, "de", Code("de-CH"))
+	assert.Equal(t, "en", Code("ja"))
+	assert.Equal(t, "en", Code(""))
+
+	Use("fr")
+	assert.Equal(t, "fr", Code(""))
+	assert.Equal(t, "fr", Code("not a language"))
+	assert.Equal(t, "de", Code("de"))
+}
+
+// TestFromEnv checks the order the variables are read in and that locale
+// suffixes are dropped.
+func TestFromEnv(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(name string) string { return vars[name] }
+	}
+	assert.Equal(t, "", FromEnv(env(nil)))
+	assert.Equal(t, "de-DE", FromEnv(env(map[string]string{"LANG": "de_DE.UTF-8"})))
+	assert.Equal(t, "fr-FR", FromEnv(env(map[string]string{"LANG": "de_DE.UTF-8", "LC_ALL": "fr_FR@euro"})))
+	assert.Equal(t, "en", FromEnv(env(map[string]string{"LC_ALL": "C", "LANG": "de_DE"})))
+	assert.Equal(t, "de", FromEnv(env(map[string]string{"WEATHER_LANG": "de", "LC_ALL": "C"})))
+}
+
+// TestPrinter checks messages are translated, falling back to English.
+func TestPrinter(t *testing.T) {
+	assert.Equal(t, "Wetter für Paris", Printer("de").Sprintf("Weather for %s", "Paris"))
+	assert.Equal(t, "Météo pour Paris", Printer("fr").Sprintf("Weather for %s", "Paris"))
+	assert.Equal(t, "Weather for Paris", Printer("ja").Sprintf("Weather for %s", "Paris"))
+	assert.Equal(t, "No such message", Printer("de").Sprintf("No such message"))
+}
+
+// TestTitle checks words are capitalized without lowering abbreviations.
+func TestTitle(t *testing.T) {
+	assert.Equal(t, "New York, US", Title("en", "new york, US"))
+	assert.Equal(t, "Île-De-France", Title("fr", "île-de-france"))
+}
+
+// TestFormatDate checks day and month abbreviations are translated.
+func TestFormatDate(t *testing.T) {
+	day := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	assert.Equal(t, "Mo. 02 März", FormatDate(Printer("de"), day, "Mon 02 Jan"))
+	assert.Equal(t, "lun. 02", FormatDate(Printer("fr"), day, "Mon 02"))
+	assert.Equal(t, "Mon 02 Mar 09:00", FormatDate(Printer("en"), day, "Mon 02 Jan 15:04"))
+}
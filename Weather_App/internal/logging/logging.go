@@ -0,0 +1,137 @@
+// Package logging sets up the structured logger shared by every mode of the
+// app and gives each operation, such as an API request or a menu action, a
+// correlation ID. Records logged with an operation's context carry its ID,
+// so everything one request did can be found together.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Environment variables read by Setup.
+const (
+	EnvLevel  = "WEATHER_LOG_LEVEL"
+	EnvFormat = "WEATHER_LOG_FORMAT"
+	EnvFile   = "WEATHER_LOG_FILE"
+)
+
+// Setup installs the default slog logger. The level ("debug", "info",
+// "warn" or "error", default info) and format ("text" or "json", default
+// text) come from the environment. Records go to w, or are appended to
+// the file named by WEATHER_LOG_FILE when it is set.
+func Setup(getenv func(string) string, w io.Writer) error {
+	var level slog.Level
+	if s := getenv(EnvLevel); s != "" {
+		if err := level.UnmarshalText([]byte(s)); err != nil {
+			return fmt.Errorf("%s: %w", EnvLevel, err)
+		}
+	}
+	if path := getenv(EnvFile); path != "" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("%s: %w", EnvFile, err)
+		}
+		w = f
+	}
+	h, err := NewHandler(w, level, getenv(EnvFormat))
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(h))
+	return nil
+}
+
+// NewHandler returns a handler writing records at or above level to w as
+// "text" or "json", adding the correlation ID and operation of each
+// record's context.
+func NewHandler(w io.Writer, level slog.Leveler, format string) (slog.Handler, error) {
+	opts := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(format) {
+	case "", "text":
+		return contextHandler{slog.NewTextHandler(w, opts)}, nil
+	case "json":
+		return contextHandler{slog.NewJSONHandler(w, opts)}, nil
+	default:
+		return nil, fmt.Errorf("%s: unknown log format %q (want text or json)", EnvFormat, format)
+	}
+}
+
+type operationKey struct{}
+
+// operation identifies what a context is being used for.
+type operation struct {
+	id   string
+	name string
+}
+
+// Start begins the operation name, returning a context carrying a new
+// correlation ID. An operation started within another keeps its ID.
+func Start(ctx context.Context, name string) context.Context {
+	id := ID(ctx)
+	if id == "" {
+		id = NewID()
+	}
+	return context.WithValue(ctx, operationKey{}, operation{id: id, name: name})
+}
+
+// WithID begins the operation name under the ID a client sent, as in an
+// X-Request-ID header, so its logs and ours can be matched. A missing or
+// malformed ID is replaced by a new one.
+func WithID(ctx context.Context, name, id string) context.Context {
+	if !validID.MatchString(id) {
+		id = NewID()
+	}
+	return context.WithValue(ctx, operationKey{}, operation{id: id, name: name})
+}
+
+// validID limits client-chosen IDs to short, log-safe strings.
+var validID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// ID returns the correlation ID of ctx's operation, or "" outside one.
+func ID(ctx context.Context) string {
+	op, _ := ctx.Value(operationKey{}).(operation)
+	return op.id
+}
+
+// NewID returns a random 16-character correlation ID.
+func NewID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// contextHandler adds the operation of a record's context to the record,
+// and the trace and span IDs when the context is being traced.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if op, ok := ctx.Value(operationKey{}).(operation); ok {
+		r.AddAttrs(slog.String("correlation_id", op.id), slog.String("op", op.name))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,127 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// capture installs a JSON logger writing to the returned buffer for the
+// duration of a test.
+func capture(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	h, err := NewHandler(&buf, slog.LevelDebug, "json")
+	require.NoError(t, err)
+	orig := slog.Default()
+	slog.SetDefault(slog.New(h))
+	t.Cleanup(func() { slog.SetDefault(orig) })
+	return &buf
+}
+
+// records decodes each JSON line written to buf.
+func records(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var out []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var r map[string]any
+		require.NoError(t, dec.Decode(&r))
+		out = append(out, r)
+	}
+	return out
+}
+
+// TestStart checks records carry their operation's correlation ID, and
+// that nested operations keep it.
+func TestStart(t *testing.T) {
+	buf := capture(t)
+	ctx := Start(context.Background(), "view weather")
+	slog.InfoContext(ctx, "outer")
+	slog.With("user", "u1").InfoContext(Start(ctx, "alerts"), "inner")
+	slog.Info("no operation")
+
+	recs := records(t, buf)
+	require.Len(t, recs, 3)
+	id := ID(ctx)
+	assert.Len(t, id, 16)
+	assert.Equal(t, id, recs[0]["correlation_id"])
+	assert.Equal(t, "view weather", recs[0]["op"])
+	assert.Equal(t, id, recs[1]["correlation_id"])
+	assert.Equal(t, "alerts", recs[1]["op"])
+	assert.Equal(t, "u1", recs[1]["user"])
+	assert.NotContains(t, recs[2], "correlation_id")
+	assert.NotEqual(t, id, ID(Start(context.Background(), "other")))
+}
+
+// TestTraceIDs checks records logged within a span carry its trace and
+// span IDs, so they can be found from the trace.
+func TestTraceIDs(t *testing.T) {
+	buf := capture(t)
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "view weather")
+	slog.InfoContext(ctx, "traced")
+	span.End()
+
+	recs := records(t, buf)
+	require.Len(t, recs, 1)
+	assert.Equal(t, span.SpanContext().TraceID().String(), recs[0]["trace_id"])
+	assert.Equal(t, span.SpanContext().SpanID().String(), recs[0]["span_id"])
+}
+
+// TestSetup checks the level and format come from the environment and
+// bad values are rejected.
+func TestSetup(t *testing.T) {
+	orig := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(orig) })
+	env := func(vars map[string]string) func(string) string {
+		return func(name string) string { return vars[name] }
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Setup(env(map[string]string{EnvLevel: "warn", EnvFormat: "json"}), &buf))
+	slog.Info("hidden")
+	slog.Warn("shown")
+	recs := records(t, &buf)
+	require.Len(t, recs, 1)
+	assert.Equal(t, "shown", recs[0]["msg"])
+
+	assert.ErrorContains(t, Setup(env(map[string]string{EnvLevel: "loud"}), &buf), EnvLevel)
+	assert.ErrorContains(t, Setup(env(map[string]string{EnvFormat: "xml"}), &buf), `unknown log format "xml"`)
+}
+
+// TestMiddleware checks requests get a correlation ID, keeping a valid one
+// sent by the client, and are logged with their status.
+func TestMiddleware(t *testing.T) {
+	buf := capture(t)
+	var seen string
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = ID(r.Context())
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/weather/current", nil)
+	req.Header.Set(RequestIDHeader, "client-42")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, "client-42", seen)
+	assert.Equal(t, "client-42", rec.Header().Get(RequestIDHeader))
+
+	req.Header.Set(RequestIDHeader, "bad id\nwith newline")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Len(t, seen, 16)
+	assert.Equal(t, seen, rec.Header().Get(RequestIDHeader))
+
+	recs := records(t, buf)
+	require.Len(t, recs, 2)
+	assert.Equal(t, "request", recs[0]["msg"])
+	assert.Equal(t, "client-42", recs[0]["correlation_id"])
+	assert.Equal(t, "GET /v1/weather/current", recs[0]["op"])
+	assert.Equal(t, float64(http.StatusTeapot), recs[0]["status"])
+}
@@ -0,0 +1,118 @@
+// Package metrics records the app's Prometheus metrics: provider requests,
+// cache lookups, logins and storage latency. They are kept in their own
+// registry, alongside the Go runtime and process collectors, and served by
+// Handler at /metrics in the server and daemon modes.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "weatherapp"
+
+// Login outcomes recorded by Login.
+const (
+	LoginSuccess = "success"
+	LoginFailure = "failure"
+	LoginError   = "error"
+)
+
+var (
+	// Registry holds every metric below.
+	Registry = prometheus.NewRegistry()
+
+	providerRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "provider_requests_total",
+		Help:      "Weather provider API requests by provider, endpoint and HTTP status (\"error\" when there was no response).",
+	}, []string{"provider", "endpoint", "status"})
+
+	providerDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "provider_request_duration_seconds",
+		Help:      "Latency of weather provider API requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"provider", "endpoint"})
+
+	cacheHits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_hits_total",
+		Help:      "Lookups answered from a cache.",
+	}, []string{"cache"})
+
+	cacheMisses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_misses_total",
+		Help:      "Lookups a cache could not answer.",
+	}, []string{"cache"})
+
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Login attempts by result: success, failure (bad credentials) or error.",
+	}, []string{"result"})
+
+	storageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_duration_seconds",
+		Help:      "Latency of user store operations by operation and outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "outcome"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		providerRequests, providerDuration,
+		cacheHits, cacheMisses,
+		logins,
+		storageDuration,
+	)
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ProviderRequest records a provider API request that got an HTTP status,
+// or none if status is 0.
+func ProviderRequest(provider, endpoint string, status int, d time.Duration) {
+	label := "error"
+	if status != 0 {
+		label = strconv.Itoa(status)
+	}
+	providerRequests.WithLabelValues(provider, endpoint, label).Inc()
+	providerDuration.WithLabelValues(provider, endpoint).Observe(d.Seconds())
+}
+
+// CacheLookup records a hit or miss in the named cache.
+func CacheLookup(cache string, hit bool) {
+	if hit {
+		cacheHits.WithLabelValues(cache).Inc()
+	} else {
+		cacheMisses.WithLabelValues(cache).Inc()
+	}
+}
+
+// Login records a login attempt with one of the Login results.
+func Login(result string) {
+	logins.WithLabelValues(result).Inc()
+}
+
+// StorageOperation records a user store operation begun at start that
+// ended with err.
+func StorageOperation(op string, start time.Time, err error) {
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	storageDuration.WithLabelValues(op, outcome).Observe(time.Since(start).Seconds())
+}
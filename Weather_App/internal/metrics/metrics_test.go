@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHandler checks recorded events are exposed with their labels.
+func TestHandler(t *testing.T) {
+	ProviderRequest("accuweather", "current", 200, 120*time.Millisecond)
+	ProviderRequest("accuweather", "current", 0, time.Second)
+	CacheLookup("accuweather_location", true)
+	Login(LoginFailure)
+	StorageOperation("load_users", time.Now(), nil)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	for _, want := range []string{
+		`weatherapp_provider_requests_total{endpoint="current",provider="accuweather",status="200"} 1`,
+		`weatherapp_provider_requests_total{endpoint="current",provider="accuweather",status="error"} 1`,
+		`weatherapp_provider_request_duration_seconds_count{endpoint="current",provider="accuweather"} 2`,
+		`weatherapp_cache_hits_total{cache="accuweather_location"} 1`,
+		`weatherapp_logins_total{result="failure"} 1`,
+		`weatherapp_storage_operation_duration_seconds_count{operation="load_users",outcome="ok"} 1`,
+		"go_goroutines",
+	} {
+		assert.Contains(t, body, want)
+	}
+}
@@ -0,0 +1,146 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// sentRetention is how long a delivered key is remembered.
+const sentRetention = 14 * 24 * time.Hour
+
+// SentStore remembers which message keys have already been delivered.
+type SentStore interface {
+	Seen(key string) bool
+	Mark(key string, at time.Time) error
+}
+
+// FileStore is a SentStore persisted as JSON so that repeated cron runs
+// share state.
+type FileStore struct {
+	path string
+	mu   sync.Mutex
+	sent map[string]time.Time
+}
+
+// DefaultStatePath returns a file in the user cache dir, for when no state
+// file is configured.
+func DefaultStatePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "weatherapp", "sent-alerts.json")
+}
+
+// OpenFileStore loads the store at path; a missing file is an empty store.
+func OpenFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path, sent: map[string]time.Time{}}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &s.sent); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Seen reports whether key has been marked.
+func (s *FileStore) Seen(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.sent[key]
+	return ok
+}
+
+// Mark records key as delivered, drops expired keys and saves the file.
+func (s *FileStore) Mark(key string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent[key] = at
+	for k, t := range s.sent {
+		if at.Sub(t) > sentRetention {
+			delete(s.sent, k)
+		}
+	}
+	b, err := json.MarshalIndent(s.sent, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, b, 0o644)
+}
+
+// Dedup wraps a Notifier and drops messages whose Key was already sent.
+type Dedup struct {
+	Next  Notifier
+	Store SentStore
+	Now   func() time.Time
+}
+
+// Notify forwards unseen messages and marks them once delivered. Each
+// channel of a Multi is marked on its own, so a channel that failed is
+// retried next time without resending to those that succeeded; the Key
+// itself is marked once every channel has it. Messages without a Key are
+// always forwarded.
+func (d *Dedup) Notify(ctx context.Context, m Message) error {
+	if m.Key == "" {
+		return d.Next.Notify(ctx, m)
+	}
+	if d.Store.Seen(m.Key) {
+		return nil
+	}
+	now := time.Now
+	if d.Now != nil {
+		now = d.Now
+	}
+	channels, ok := d.Next.(Multi)
+	if !ok {
+		channels = Multi{d.Next}
+	}
+	var errs []error
+	for _, n := range channels {
+		key := m.Key + "|" + channelID(n)
+		if d.Store.Seen(key) {
+			continue
+		}
+		if err := n.Notify(ctx, m); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := d.Store.Mark(key, now()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return d.Store.Mark(m.Key, now())
+}
+
+// channelID names a notifier's channel for per-channel deduplication.
+func channelID(n Notifier) string {
+	switch n := n.(type) {
+	case *SMTPNotifier:
+		return "email:" + n.To
+	case *WebhookNotifier:
+		return "webhook:" + n.URL
+	case *FileNotifier:
+		return "file:" + n.Path
+	case *WriterNotifier:
+		return "stdout"
+	default:
+		return fmt.Sprintf("%T", n)
+	}
+}
@@ -0,0 +1,250 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/smtp"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"weatherapp/models"
+)
+
+// Message is a single notification. Key identifies the underlying event so
+// that the same alert is not delivered twice.
+type Message struct {
+	UserID  string
+	Subject string
+	Body    string
+	Key     string
+}
+
+// Notifier delivers a Message over some channel.
+type Notifier interface {
+	Notify(ctx context.Context, m Message) error
+}
+
+// WriterNotifier prints messages to an io.Writer such as os.Stdout.
+type WriterNotifier struct {
+	W io.Writer
+}
+
+// Notify writes the message subject and body.
+func (n *WriterNotifier) Notify(_ context.Context, m Message) error {
+	_, err := fmt.Fprintf(n.W, "%s\n%s\n", m.Subject, m.Body)
+	return err
+}
+
+// FileNotifier appends messages to a file.
+type FileNotifier struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// Notify appends the message to the file, creating it if needed.
+func (n *FileNotifier) Notify(ctx context.Context, m Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	f, err := os.OpenFile(n.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return (&WriterNotifier{W: f}).Notify(ctx, m)
+}
+
+// WebhookNotifier posts Slack-compatible JSON ({"text": ...}) to a URL.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// Notify posts the message and fails on any non-2xx response.
+func (n *WebhookNotifier) Notify(ctx context.Context, m Message) error {
+	payload, err := json.Marshal(map[string]string{"text": m.Subject + "\n" + m.Body})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// SMTPConfig holds the outgoing mail server settings.
+type SMTPConfig struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
+}
+
+// SMTPNotifier sends messages as plain-text email.
+type SMTPNotifier struct {
+	Config SMTPConfig
+	To     string
+}
+
+// Notify sends the message to n.To.
+func (n *SMTPNotifier) Notify(_ context.Context, m Message) error {
+	if n.Config.Addr == "" {
+		return errors.New("no mail server: set notify.smtp_addr or SMTP_ADDR")
+	}
+	var auth smtp.Auth
+	if n.Config.Username != "" {
+		host := n.Config.Addr
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		auth = smtp.PlainAuth("", n.Config.Username, n.Config.Password, host)
+	}
+	if strings.ContainsAny(n.Config.From+n.To+m.Subject, "\r\n") {
+		return errors.New("mail headers must not contain line breaks")
+	}
+	body := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n",
+		n.Config.From, n.To, m.Subject, strings.ReplaceAll(m.Body, "\n", "\r\n"))
+	return smtp.SendMail(n.Config.Addr, auth, n.Config.From, []string{n.To}, []byte(body))
+}
+
+// Multi fans a message out to several notifiers, returning all failures.
+type Multi []Notifier
+
+// Notify delivers to every channel even if some fail.
+func (m Multi) Notify(ctx context.Context, msg Message) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Sinks holds the operator's settings for the channels ForUser builds.
+// Users' file notifications are appended to files in FileDir; an empty
+// FileDir disables them.
+type Sinks struct {
+	SMTP    SMTPConfig
+	FileDir string
+}
+
+// ForUser builds the channels configured in a User's NotificationSettings.
+// Users with no channels configured get stdout. A webhook or file the
+// user may not use is kept as a channel that fails, so the user's other
+// channels are still delivered to.
+func ForUser(s models.NotificationSettings, sinks Sinks) Notifier {
+	var m Multi
+	if s.Email != "" {
+		m = append(m, &SMTPNotifier{Config: sinks.SMTP, To: s.Email})
+	}
+	if s.WebhookURL != "" {
+		if err := CheckWebhookURL(s.WebhookURL); err != nil {
+			m = append(m, rejected{err})
+		} else {
+			m = append(m, &WebhookNotifier{URL: s.WebhookURL, Client: publicClient})
+		}
+	}
+	if s.File != "" {
+		switch err := CheckFileName(s.File); {
+		case err != nil:
+			m = append(m, rejected{err})
+		case sinks.FileDir == "":
+			m = append(m, rejected{errors.New("file notifications are disabled: set notify.file_dir")})
+		default:
+			m = append(m, &FileNotifier{Path: filepath.Join(sinks.FileDir, s.File)})
+		}
+	}
+	if s.Stdout || len(m) == 0 {
+		m = append(m, &WriterNotifier{W: os.Stdout})
+	}
+	return m
+}
+
+// rejected is a channel that could not be built; it fails every delivery.
+type rejected struct{ err error }
+
+func (r rejected) Notify(context.Context, Message) error { return r.err }
+
+// CheckFileName reports whether name can be used as a user's notification
+// file: a plain file name, which ForUser places in the operator's FileDir.
+func CheckFileName(name string) error {
+	if !filepath.IsLocal(name) || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("notification file %q must be a file name, not a path", name)
+	}
+	return nil
+}
+
+// CheckWebhookURL reports whether raw can be used as a user's webhook: an
+// https URL whose host is not localhost or a non-public IP address. Host
+// names are checked again when dialled, see publicClient.
+func CheckWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	if u.Scheme != "https" || u.Hostname() == "" {
+		return fmt.Errorf("webhook URL %q must be an https URL", raw)
+	}
+	host := u.Hostname()
+	if ip, err := netip.ParseAddr(host); (err == nil && !publicAddr(ip)) || strings.EqualFold(host, "localhost") {
+		return fmt.Errorf("webhook host %q is not a public address", host)
+	}
+	return nil
+}
+
+// publicClient posts users' webhooks. It refuses to connect to non-public
+// addresses, checking the address actually dialled so that a host name
+// cannot be pointed at an internal service, and ignores proxy settings.
+var publicClient = &http.Client{
+	Timeout: 30 * time.Second,
+	Transport: &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: 10 * time.Second, Control: dialPublic}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+	},
+}
+
+func dialPublic(_, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !publicAddr(ap.Addr()) {
+		return fmt.Errorf("webhook address %s is not public", ap.Addr())
+	}
+	return nil
+}
+
+// sharedAddrs is the carrier-grade NAT range, which IsPrivate leaves out.
+var sharedAddrs = netip.MustParsePrefix("100.64.0.0/10")
+
+// publicAddr reports whether ip is a globally routable unicast address.
+func publicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddrs.Contains(ip)
+}
@@ -0,0 +1,229 @@
+package notify
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"weatherapp/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startSMTP runs a minimal SMTP server that accepts one message and sends
+// its DATA section on the returned channel.
+func startSMTP(t *testing.T) (string, <-chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	got := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost ready")
+		var data strings.Builder
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if inData {
+				if line == ".\r\n" {
+					inData = false
+					got <- data.String()
+					reply("250 OK")
+					continue
+				}
+				data.WriteString(line)
+				continue
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case cmd == "DATA":
+				inData = true
+				reply("354 go ahead")
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	return ln.Addr().String(), got
+}
+
+// TestSMTPNotifier sends a message to a local SMTP stand-in.
+func TestSMTPNotifier(t *testing.T) {
+	addr, got := startSMTP(t)
+	n := &SMTPNotifier{Config: SMTPConfig{Addr: addr, From: "alerts@example.com"}, To: "asha@example.com"}
+
+	err := n.Notify(context.Background(), Message{Subject: "Weather alert for Pune", Body: "temp_max > 35 tomorrow was 37°C"})
+	require.NoError(t, err)
+
+	select {
+	case data := <-got:
+		assert.Contains(t, data, "To: asha@example.com")
+		assert.Contains(t, data, "Subject: Weather alert for Pune")
+		assert.Contains(t, data, "temp_max > 35 tomorrow was 37°C")
+	case <-time.After(5 * time.Second):
+		t.Fatal("SMTP server received no message")
+	}
+
+	err = n.Notify(context.Background(), Message{Subject: "Alert\r\nBcc: everyone@example.com"})
+	assert.ErrorContains(t, err, "line breaks")
+	n.To = "asha@example.com\nBcc: everyone@example.com"
+	assert.Error(t, n.Notify(context.Background(), Message{Subject: "Alert"}))
+}
+
+// TestWebhookNotifier checks the Slack-style payload and error statuses.
+func TestWebhookNotifier(t *testing.T) {
+	var payload map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		json.NewDecoder(r.Body).Decode(&payload)
+		if payload["text"] == "fail\n" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	n := &WebhookNotifier{URL: srv.URL}
+	require.NoError(t, n.Notify(context.Background(), Message{Subject: "Alert", Body: "rain"}))
+	assert.Equal(t, "Alert\nrain", payload["text"])
+
+	assert.Error(t, n.Notify(context.Background(), Message{Subject: "fail"}))
+}
+
+// countingNotifier counts deliveries and can be made to fail.
+type countingNotifier struct {
+	sent int
+	fail bool
+}
+
+func (c *countingNotifier) Notify(context.Context, Message) error {
+	if c.fail {
+		return errors.New("down")
+	}
+	c.sent++
+	return nil
+}
+
+// TestDedup verifies a key is delivered once, survives a reload, and is
+// retried if delivery failed.
+func TestDedup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sent.json")
+	store, err := OpenFileStore(path)
+	require.NoError(t, err)
+
+	c := &countingNotifier{fail: true}
+	d := &Dedup{Next: c, Store: store}
+	msg := Message{Subject: "Alert", Key: "u1|rain > 60 tomorrow|2026-10-20"}
+
+	assert.Error(t, d.Notify(context.Background(), msg))
+	c.fail = false
+	require.NoError(t, d.Notify(context.Background(), msg))
+	require.NoError(t, d.Notify(context.Background(), msg))
+	assert.Equal(t, 1, c.sent)
+
+	reloaded, err := OpenFileStore(path)
+	require.NoError(t, err)
+	d = &Dedup{Next: c, Store: reloaded}
+	require.NoError(t, d.Notify(context.Background(), msg))
+	assert.Equal(t, 1, c.sent)
+}
+
+// TestDedup_Channels verifies a channel that failed is retried without
+// resending to the channels that delivered.
+func TestDedup_Channels(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenFileStore(filepath.Join(dir, "sent.json"))
+	require.NoError(t, err)
+	var stdout bytes.Buffer
+	file := filepath.Join(dir, "alerts", "log.txt")
+	d := &Dedup{Next: Multi{&WriterNotifier{W: &stdout}, &FileNotifier{Path: file}}, Store: store}
+	msg := Message{Subject: "Alert", Key: "u1|rain > 60 tomorrow|2026-10-20"}
+
+	assert.Error(t, d.Notify(context.Background(), msg))
+	assert.Equal(t, 1, strings.Count(stdout.String(), "Alert"))
+	assert.False(t, store.Seen(msg.Key))
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o755))
+	require.NoError(t, d.Notify(context.Background(), msg))
+	assert.Equal(t, 1, strings.Count(stdout.String(), "Alert"))
+	b, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "Alert")
+	assert.True(t, store.Seen(msg.Key))
+}
+
+// TestForUser checks users' webhooks and files are limited to public
+// https hosts and the operator's directory, without stopping delivery to
+// their other channels.
+func TestForUser(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	msg := Message{Subject: "Alert", Body: "rain"}
+
+	require.NoError(t, ForUser(models.NotificationSettings{File: "alerts.log"}, Sinks{FileDir: dir}).Notify(ctx, msg))
+	b, err := os.ReadFile(filepath.Join(dir, "alerts.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "Alert\nrain\n", string(b))
+
+	assert.ErrorContains(t, ForUser(models.NotificationSettings{File: "alerts.log"}, Sinks{}).Notify(ctx, msg), "notify.file_dir")
+	for _, s := range []models.NotificationSettings{
+		{File: "../alerts.log"},
+		{File: "/etc/passwd"},
+		{WebhookURL: "http://hooks.example.com/x"},
+		{WebhookURL: "https://127.0.0.1/x"},
+		{WebhookURL: "https://[::1]:8443/x"},
+		{WebhookURL: "https://169.254.169.254/latest/meta-data"},
+		{WebhookURL: "https://localhost/x"},
+	} {
+		assert.Error(t, ForUser(s, Sinks{FileDir: dir}).Notify(ctx, msg), s)
+	}
+}
+
+// TestCheckWebhookURL checks which webhook URLs users may set.
+func TestCheckWebhookURL(t *testing.T) {
+	assert.NoError(t, CheckWebhookURL("https://hooks.slack.com/services/T0/B0/x"))
+	assert.NoError(t, CheckWebhookURL("https://93.184.216.34/hook"))
+	assert.Error(t, CheckWebhookURL("ftp://hooks.example.com"))
+	assert.Error(t, CheckWebhookURL("https://10.0.0.8/hook"))
+	assert.Error(t, CheckWebhookURL("https://100.64.0.1/hook"))
+}
+
+// TestDialPublic checks the webhook client refuses internal addresses a
+// host name resolves to.
+func TestDialPublic(t *testing.T) {
+	assert.NoError(t, dialPublic("tcp", "93.184.216.34:443", nil))
+	assert.Error(t, dialPublic("tcp", "127.0.0.1:443", nil))
+	assert.Error(t, dialPublic("tcp", "[fd00::1]:443", nil))
+	assert.Error(t, dialPublic("tcp", "[::ffff:192.168.1.1]:443", nil))
+}
+
+// TestWriterNotifier checks the stdout/file sink format.
+func TestWriterNotifier(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, (&WriterNotifier{W: &buf}).Notify(context.Background(), Message{Subject: "S", Body: "B"}))
+	assert.Equal(t, "S\nB\n", buf.String())
+}
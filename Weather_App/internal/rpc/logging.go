@@ -0,0 +1,64 @@
+package rpc
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"weatherapp/internal/logging"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// requestIDKey is the metadata key of a call's correlation ID, the gRPC
+// counterpart of the X-Request-ID header.
+var requestIDKey = strings.ToLower(logging.RequestIDHeader)
+
+// logUnary runs each call as an operation with its own correlation ID and
+// logs its method, status code and duration.
+func logUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx = startCall(ctx, info.FullMethod)
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	logCall(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+// logStream is logUnary for streaming calls, which are logged when the
+// stream ends.
+func logStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := startCall(ss.Context(), info.FullMethod)
+	start := time.Now()
+	err := handler(srv, &authedStream{ServerStream: ss, ctx: ctx})
+	logCall(ctx, info.FullMethod, start, err)
+	return err
+}
+
+// startCall returns ctx for the call method, keeping the client's
+// correlation ID if it sent one and returning the ID in the header.
+func startCall(ctx context.Context, method string) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	id := ""
+	if ids := md.Get(requestIDKey); len(ids) > 0 {
+		id = ids[0]
+	}
+	ctx = logging.WithID(ctx, method, id)
+	if err := grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, logging.ID(ctx))); err != nil {
+		slog.DebugContext(ctx, "setting response header", "error", err)
+	}
+	return ctx
+}
+
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	switch code {
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		level = slog.LevelError
+	}
+	slog.Log(ctx, level, "rpc", "method", method, "code", code.String(), "duration", time.Since(start))
+}
@@ -0,0 +1,111 @@
+// Package rpc serves the gRPC API defined in proto/weatherapp/v1. It offers
+// the same operations as the REST API in package server and accepts the
+// same bearer tokens, sent as "authorization" metadata.
+package rpc
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"weatherapp/internal/rpc/weatherpb"
+	"weatherapp/internal/server"
+	"weatherapp/models"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// New returns a gRPC server with every service registered. Calls other
+// than AuthService.Login must carry a token issued by tokens.
+func New(tokens *server.Tokens, opts ...grpc.ServerOption) *grpc.Server {
+	a := authenticator{tokens: tokens}
+	opts = append(opts,
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(logUnary, a.unary),
+		grpc.ChainStreamInterceptor(logStream, a.stream),
+	)
+	s := grpc.NewServer(opts...)
+	weatherpb.RegisterAuthServiceServer(s, &authServer{tokens: tokens})
+	weatherpb.RegisterWeatherServiceServer(s, &weatherServer{})
+	weatherpb.RegisterPreferencesServiceServer(s, &preferencesServer{})
+	return s
+}
+
+// publicMethods may be called without a token.
+var publicMethods = map[string]bool{
+	weatherpb.AuthService_Login_FullMethodName: true,
+}
+
+type userKey struct{}
+
+// authenticator checks bearer tokens and puts the caller's User in the
+// request context.
+type authenticator struct {
+	tokens *server.Tokens
+}
+
+func (a authenticator) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if publicMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+	ctx, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a authenticator) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticate returns ctx carrying the User named by the token in the
+// incoming metadata.
+func (a authenticator) authenticate(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+	userID, err := a.tokens.Verify(strings.TrimSpace(token))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	u, ok, err := server.FindUser(ctx, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "loading user", "user", userID, "error", err)
+		return nil, status.Error(codes.Internal, "loading user failed")
+	}
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "user no longer exists")
+	}
+	return context.WithValue(ctx, userKey{}, u), nil
+}
+
+// caller returns the User authenticated for ctx.
+func caller(ctx context.Context) models.User {
+	u, _ := ctx.Value(userKey{}).(models.User)
+	return u
+}
+
+// authedStream overrides the context of a stream with the authenticated one.
+type authedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authedStream) Context() context.Context {
+	return s.ctx
+}
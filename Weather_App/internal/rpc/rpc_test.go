@@ -0,0 +1,317 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"weatherapp/internal/auth"
+	"weatherapp/internal/geo"
+	"weatherapp/internal/rpc/weatherpb"
+	"weatherapp/internal/server"
+	"weatherapp/internal/storage"
+	"weatherapp/internal/units"
+	"weatherapp/internal/weather"
+	"weatherapp/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// fakeProvider serves a temperature tests can change, with one alert.
+type fakeProvider struct {
+	mu    sync.Mutex
+	temp  units.Temperature
+	calls int
+	err   error
+}
+
+func (f *fakeProvider) setTemp(t units.Temperature) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.temp = t
+}
+
+// setErr makes the provider fail with err, or succeed if it is nil, and
+// restarts the call count.
+func (f *fakeProvider) setErr(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err, f.calls = err, 0
+}
+
+func (f *fakeProvider) Current(ctx context.Context, loc geo.Location) (*weather.WeatherData, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	return &weather.WeatherData{Description: "Sunny", Temperature: f.temp, WindSpeed: 10}, f.err
+}
+
+func (f *fakeProvider) Forecast(ctx context.Context, loc geo.Location, days int) ([]weather.WeatherData, error) {
+	return make([]weather.WeatherData, days), f.err
+}
+
+func (f *fakeProvider) Alerts(ctx context.Context, loc geo.Location) ([]weather.Alert, error) {
+	return []weather.Alert{{Severity: weather.SeveritySevere, Category: "wind", Description: "Gales"}}, f.err
+}
+
+type testEnv struct {
+	users    []models.User
+	provider *fakeProvider
+	conn     *grpc.ClientConn
+	ctx      context.Context
+}
+
+// newEnv serves the gRPC API over an in-memory listener to one registered
+// user, and logs in as them.
+func newEnv(t *testing.T) *testEnv {
+	env := &testEnv{provider: &fakeProvider{temp: 20}}
+	origLoad, origGet, origSave, origUpdate := storage.LoadUsers, storage.GetUserByID, storage.CreateUser, storage.UpdateUser
+	t.Cleanup(func() {
+		storage.LoadUsers, storage.GetUserByID, storage.CreateUser, storage.UpdateUser = origLoad, origGet, origSave, origUpdate
+	})
+	var mu sync.Mutex
+	storage.LoadUsers = func(context.Context) ([]models.User, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]models.User(nil), env.users...), nil
+	}
+	storage.GetUserByID = func(ctx context.Context, userID string) (*models.User, error) {
+		users, err := storage.LoadUsers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			if u.UserID == userID {
+				return &u, nil
+			}
+		}
+		return nil, storage.ErrUserNotFound
+	}
+	storage.CreateUser = func(_ context.Context, u models.User) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, existing := range env.users {
+			if existing.UserID == u.UserID || existing.Name == u.Name {
+				return storage.ErrUserExists
+			}
+		}
+		env.users = append(env.users, u)
+		return nil
+	}
+	storage.UpdateUser = func(_ context.Context, u models.User) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for i := range env.users {
+			if env.users[i].UserID == u.UserID {
+				env.users[i] = u
+			}
+		}
+		return nil
+	}
+	weather.InitProvider(env.provider)
+	require.NoError(t, auth.RegisterUser(context.Background(), "u1", "asha", "correct horse"))
+
+	lis := bufconn.Listen(1 << 20)
+	srv := New(server.NewTokens([]byte("test-secret"), time.Hour))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	env.conn = conn
+
+	resp, err := weatherpb.NewAuthServiceClient(conn).Login(context.Background(),
+		&weatherpb.LoginRequest{Name: "asha", Password: "correct horse"})
+	require.NoError(t, err)
+	assert.Equal(t, "u1", resp.UserId)
+	env.ctx = metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+resp.Token)
+	return env
+}
+
+func requireCode(t *testing.T, want codes.Code, err error) {
+	t.Helper()
+	require.Error(t, err)
+	assert.Equal(t, want, status.Code(err), err.Error())
+}
+
+// TestAuthentication checks logins and that calls need a valid token.
+func TestAuthentication(t *testing.T) {
+	env := newEnv(t)
+	_, err := weatherpb.NewAuthServiceClient(env.conn).Login(context.Background(),
+		&weatherpb.LoginRequest{Name: "asha", Password: "wrong"})
+	requireCode(t, codes.Unauthenticated, err)
+	assert.Equal(t, "invalid name or password", status.Convert(err).Message())
+
+	client := weatherpb.NewWeatherServiceClient(env.conn)
+	_, err = client.Current(context.Background(), &weatherpb.CurrentRequest{Location: "Paris"})
+	requireCode(t, codes.Unauthenticated, err)
+
+	forged := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer forged.token")
+	_, err = client.Current(forged, &weatherpb.CurrentRequest{Location: "Paris"})
+	requireCode(t, codes.Unauthenticated, err)
+
+	stream, err := client.Watch(context.Background(), &weatherpb.WatchRequest{Location: "Paris"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	requireCode(t, codes.Unauthenticated, err)
+
+	storage.LoadUsers = func(context.Context) ([]models.User, error) { return nil, errors.New("firestore: deadline exceeded") }
+	_, err = weatherpb.NewAuthServiceClient(env.conn).Login(context.Background(),
+		&weatherpb.LoginRequest{Name: "asha", Password: "correct horse"})
+	requireCode(t, codes.Internal, err)
+	assert.NotContains(t, err.Error(), "firestore")
+}
+
+// TestWeather covers current conditions, forecasts, alerts and errors.
+func TestWeather(t *testing.T) {
+	env := newEnv(t)
+	client := weatherpb.NewWeatherServiceClient(env.conn)
+
+	_, err := client.Current(env.ctx, &weatherpb.CurrentRequest{})
+	requireCode(t, codes.InvalidArgument, err)
+
+	report, err := client.Current(env.ctx, &weatherpb.CurrentRequest{Location: "Paris", Unit: "imperial"})
+	require.NoError(t, err)
+	assert.Equal(t, "current", report.Kind)
+	assert.Equal(t, "fahrenheit", report.Units.Temperature)
+	require.Len(t, report.Data, 1)
+	assert.InDelta(t, 68, report.Data[0].Temperature, 1e-9)
+	assert.InDelta(t, 6.21, report.Data[0].WindSpeed, 0.01)
+	assert.Equal(t, "clear", report.Data[0].Condition)
+	require.Len(t, report.Alerts, 1)
+	assert.Equal(t, "severe", report.Alerts[0].Severity)
+
+	report, err = client.Forecast(env.ctx, &weatherpb.ForecastRequest{Location: "51.5,-0.12"})
+	require.NoError(t, err)
+	assert.Equal(t, "7 days", report.Period)
+	assert.Len(t, report.Data, 7)
+	require.NotNil(t, report.Location.Latitude)
+	assert.Equal(t, 51.5, *report.Location.Latitude)
+
+	_, err = client.Forecast(env.ctx, &weatherpb.ForecastRequest{Location: "Paris", Days: 31})
+	requireCode(t, codes.InvalidArgument, err)
+	_, err = client.Current(env.ctx, &weatherpb.CurrentRequest{Location: "auto"})
+	requireCode(t, codes.InvalidArgument, err)
+
+	alerts, err := client.Alerts(env.ctx, &weatherpb.AlertsRequest{Location: "Paris"})
+	require.NoError(t, err)
+	require.Len(t, alerts.Alerts, 1)
+	assert.Equal(t, "Gales", alerts.Alerts[0].Description)
+
+	env.provider.err = assert.AnError
+	_, err = client.Current(env.ctx, &weatherpb.CurrentRequest{Location: "Paris"})
+	requireCode(t, codes.Unavailable, err)
+	_, err = client.Alerts(env.ctx, &weatherpb.AlertsRequest{Location: "Paris"})
+	requireCode(t, codes.Unavailable, err)
+}
+
+// TestWatch checks the stream sends at once, skips unchanged conditions
+// and sends again when they change.
+func TestWatch(t *testing.T) {
+	env := newEnv(t)
+	client := weatherpb.NewWeatherServiceClient(env.conn)
+
+	stream, err := client.Watch(env.ctx, &weatherpb.WatchRequest{Location: "Paris", Interval: durationpb.New(time.Second)})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	requireCode(t, codes.InvalidArgument, err)
+
+	orig := minWatchInterval
+	minWatchInterval = 10 * time.Millisecond
+	t.Cleanup(func() { minWatchInterval = orig })
+	ctx, cancel := context.WithCancel(env.ctx)
+	defer cancel()
+	stream, err = client.Watch(ctx, &weatherpb.WatchRequest{Location: "Paris", Interval: durationpb.New(10 * time.Millisecond)})
+	require.NoError(t, err)
+
+	first, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, 20.0, first.Data[0].Temperature)
+
+	require.Eventually(t, func() bool {
+		env.provider.mu.Lock()
+		defer env.provider.mu.Unlock()
+		return env.provider.calls >= 3
+	}, time.Second, 5*time.Millisecond)
+	// A failing provider does not end the stream; it resumes once the
+	// provider recovers.
+	env.provider.setErr(assert.AnError)
+	require.Eventually(t, func() bool {
+		env.provider.mu.Lock()
+		defer env.provider.mu.Unlock()
+		return env.provider.calls >= 3
+	}, time.Second, 5*time.Millisecond)
+	env.provider.setErr(nil)
+	env.provider.setTemp(25)
+
+	next, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, 25.0, next.Data[0].Temperature)
+	cancel()
+}
+
+// springfields finds two places for every query.
+type springfields struct{}
+
+func (springfields) Geocode(ctx context.Context, q string) ([]geo.Place, error) {
+	return []geo.Place{
+		{Name: "Springfield", Region: "Illinois", Lat: 39.8, Lon: -89.64},
+		{Name: "Springfield", Region: "Missouri", Lat: 37.22, Lon: -93.3},
+	}, nil
+}
+
+// TestPreferences covers reading and partially updating preferences.
+func TestPreferences(t *testing.T) {
+	env := newEnv(t)
+	client := weatherpb.NewPreferencesServiceClient(env.conn)
+
+	prefs, err := client.UpdatePreferences(env.ctx, &weatherpb.UpdatePreferencesRequest{
+		Location: proto.String("Leeds"),
+		Unit:     proto.String("uk, speed=kn"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Leeds", prefs.Location)
+	assert.Equal(t, "uk", prefs.Unit)
+	assert.Equal(t, map[string]string{"speed": "kn"}, prefs.UnitOverrides)
+
+	prefs, err = client.GetPreferences(env.ctx, &weatherpb.GetPreferencesRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "Leeds", prefs.Location)
+
+	_, err = client.UpdatePreferences(env.ctx, &weatherpb.UpdatePreferencesRequest{})
+	requireCode(t, codes.InvalidArgument, err)
+	_, err = client.UpdatePreferences(env.ctx, &weatherpb.UpdatePreferencesRequest{
+		Verbosity: proto.String("verbose"),
+		Forecast:  proto.String("fortnight"),
+	})
+	requireCode(t, codes.InvalidArgument, err)
+	assert.Equal(t, "", env.users[0].Preferences.Verbosity)
+
+	t.Cleanup(func() { geo.InitGeocoder(nil) })
+	geo.InitGeocoder(springfields{})
+	_, err = client.UpdatePreferences(env.ctx, &weatherpb.UpdatePreferencesRequest{Location: proto.String("Springfield")})
+	requireCode(t, codes.InvalidArgument, err)
+	assert.Contains(t, err.Error(), "Springfield, Missouri")
+	assert.Equal(t, "Leeds", env.users[0].Preferences.Location)
+
+	report, err := weatherpb.NewWeatherServiceClient(env.conn).Current(env.ctx, &weatherpb.CurrentRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "Leeds", report.Location.Name)
+	assert.Equal(t, "kn", report.Units.Speed)
+}
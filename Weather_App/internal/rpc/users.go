@@ -0,0 +1,88 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"weatherapp/internal/auth"
+	"weatherapp/internal/rpc/weatherpb"
+	"weatherapp/internal/server"
+	"weatherapp/internal/storage"
+	"weatherapp/internal/user"
+	"weatherapp/models"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type authServer struct {
+	weatherpb.UnimplementedAuthServiceServer
+	tokens *server.Tokens
+}
+
+func (s *authServer) Login(ctx context.Context, req *weatherpb.LoginRequest) (*weatherpb.LoginResponse, error) {
+	if req.GetName() == "" || req.GetPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name and password are required")
+	}
+	u, err := auth.Authenticate(ctx, req.GetName(), req.GetPassword())
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		return nil, status.Error(codes.Unauthenticated, "invalid name or password")
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "logging in", "name", req.GetName(), "error", err)
+		return nil, status.Error(codes.Internal, "login failed")
+	}
+	token, expires := s.tokens.Issue(u.UserID)
+	return &weatherpb.LoginResponse{Token: token, ExpiresAt: timestamppb.New(expires), UserId: u.UserID}, nil
+}
+
+type preferencesServer struct {
+	weatherpb.UnimplementedPreferencesServiceServer
+}
+
+func (s *preferencesServer) GetPreferences(ctx context.Context, _ *weatherpb.GetPreferencesRequest) (*weatherpb.Preferences, error) {
+	return newPreferences(caller(ctx).Preferences), nil
+}
+
+func (s *preferencesServer) UpdatePreferences(ctx context.Context, req *weatherpb.UpdatePreferencesRequest) (*weatherpb.Preferences, error) {
+	changes := user.PreferenceChanges{
+		Location:  req.Location,
+		Unit:      req.Unit,
+		Verbosity: req.Verbosity,
+		Forecast:  req.Forecast,
+		Output:    req.Output,
+		Language:  req.Language,
+	}
+	if changes.Empty() {
+		return nil, status.Error(codes.InvalidArgument, "no preferences to change")
+	}
+	if err := changes.ResolveLocation(ctx); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	u := caller(ctx)
+	if err := changes.Apply(&u.Preferences); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := storage.UpdateUser(ctx, u); err != nil {
+		return nil, status.Error(codes.Internal, "saving preferences failed")
+	}
+	return newPreferences(u.Preferences), nil
+}
+
+func newPreferences(p models.Preferences) *weatherpb.Preferences {
+	pb := &weatherpb.Preferences{
+		Location:      p.Location,
+		Unit:          p.Unit,
+		UnitOverrides: p.UnitOverrides,
+		Verbosity:     p.Verbosity,
+		Forecast:      p.Forecast,
+		Output:        p.Output,
+		Language:      p.Language,
+	}
+	if p.Coords != nil {
+		pb.Latitude, pb.Longitude = &p.Coords.Lat, &p.Coords.Lon
+	}
+	return pb
+}
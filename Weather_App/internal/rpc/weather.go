@@ -0,0 +1,212 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"weatherapp/internal/geo"
+	"weatherapp/internal/rpc/weatherpb"
+	"weatherapp/internal/units"
+	"weatherapp/internal/weather"
+	"weatherapp/models"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Watch intervals: the default, and the shortest a client may ask for so
+// that streams cannot exhaust the provider's quota.
+const (
+	DefaultWatchInterval = 10 * time.Minute
+	MinWatchInterval     = time.Minute
+)
+
+// minWatchInterval is the enforced minimum; tests lower it.
+var minWatchInterval = MinWatchInterval
+
+type weatherServer struct {
+	weatherpb.UnimplementedWeatherServiceServer
+}
+
+func (s *weatherServer) Current(ctx context.Context, req *weatherpb.CurrentRequest) (*weatherpb.Report, error) {
+	loc, sys, err := target(caller(ctx), req.GetLocation(), req.GetUnit())
+	if err != nil {
+		return nil, err
+	}
+	return fetch(ctx, loc, sys, 0, caller(ctx).Preferences.Language)
+}
+
+func (s *weatherServer) Forecast(ctx context.Context, req *weatherpb.ForecastRequest) (*weatherpb.Report, error) {
+	days := int(req.GetDays())
+	switch {
+	case days == 0:
+		days = 7
+	case days < 1 || days > 30:
+		return nil, status.Error(codes.InvalidArgument, "days must be from 1 to 30")
+	}
+	loc, sys, err := target(caller(ctx), req.GetLocation(), req.GetUnit())
+	if err != nil {
+		return nil, err
+	}
+	return fetch(ctx, loc, sys, days, caller(ctx).Preferences.Language)
+}
+
+func (s *weatherServer) Alerts(ctx context.Context, req *weatherpb.AlertsRequest) (*weatherpb.AlertsResponse, error) {
+	loc, _, err := target(caller(ctx), req.GetLocation(), "")
+	if err != nil {
+		return nil, err
+	}
+	alerts, err := weather.FetchAlerts(ctx, loc)
+	if errors.Is(err, weather.ErrAlertsUnsupported) {
+		return nil, status.Error(codes.Unimplemented, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return &weatherpb.AlertsResponse{Alerts: newAlerts(alerts)}, nil
+}
+
+func (s *weatherServer) Watch(req *weatherpb.WatchRequest, stream grpc.ServerStreamingServer[weatherpb.Report]) error {
+	ctx := stream.Context()
+	loc, sys, err := target(caller(ctx), req.GetLocation(), req.GetUnit())
+	if err != nil {
+		return err
+	}
+	interval := DefaultWatchInterval
+	if req.Interval != nil {
+		if err := req.Interval.CheckValid(); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		interval = req.Interval.AsDuration()
+	}
+	if interval < minWatchInterval {
+		return status.Errorf(codes.InvalidArgument, "interval must be at least %v", minWatchInterval)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var last *weatherpb.Report
+	for {
+		// A failed fetch is only logged: the next tick tries again, so one
+		// provider hiccup does not end the client's stream.
+		report, err := fetch(ctx, loc, sys, 0, caller(ctx).Preferences.Language)
+		if err != nil {
+			slog.WarnContext(ctx, "watch: fetching weather", "location", loc.String(), "error", err)
+		} else if key := withoutTimes(report); last == nil || !proto.Equal(key, last) {
+			if err := stream.Send(report); err != nil {
+				return err
+			}
+			last = key
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// target resolves the location and units of a request as a gRPC status.
+func target(u models.User, location, unit string) (geo.Location, units.System, error) {
+	loc, sys, err := weather.RequestTarget(u.Preferences, location, unit)
+	if err != nil {
+		return geo.Location{}, units.System{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return loc, sys, nil
+}
+
+// fetch returns current conditions when days is 0, or a forecast, with
+// descriptions in lang.
+func fetch(ctx context.Context, loc geo.Location, sys units.System, days int, lang string) (*weatherpb.Report, error) {
+	report, err := weather.FetchReport(ctx, loc, days, lang)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, fmt.Sprintf("weather provider: %v", err))
+	}
+	report.Units = sys
+	if days > 0 {
+		report.Period = strconv.Itoa(days) + " days"
+	}
+	return newReport(report), nil
+}
+
+// withoutTimes returns a copy of r without the times that change on every
+// fetch, for telling whether conditions have changed.
+func withoutTimes(r *weatherpb.Report) *weatherpb.Report {
+	c := proto.Clone(r).(*weatherpb.Report)
+	c.Generated = nil
+	for _, d := range c.Data {
+		d.Time = nil
+	}
+	return c
+}
+
+func newReport(r weather.Report) *weatherpb.Report {
+	sys := r.Units
+	pb := &weatherpb.Report{
+		Location:  &weatherpb.Place{Name: r.Location.String(), TimeZone: r.Zone.String()},
+		Kind:      r.Kind,
+		Period:    r.Period,
+		Generated: timestamp(r.Generated),
+		Units: &weatherpb.Units{
+			Temperature:   string(sys.Temperature),
+			Speed:         string(sys.Speed),
+			Pressure:      string(sys.Pressure),
+			Precipitation: string(sys.Precipitation),
+			Distance:      string(sys.Distance),
+		},
+		Alerts: newAlerts(r.Alerts),
+	}
+	if lat, lon, ok := geo.Position(r.Location); ok {
+		pb.Location.Latitude, pb.Location.Longitude = &lat, &lon
+	}
+	for _, d := range r.Data {
+		pb.Data = append(pb.Data, &weatherpb.Conditions{
+			Time:              timestamp(d.Time),
+			Condition:         d.Condition.String(),
+			Description:       d.Description,
+			Temperature:       d.Temperature.In(sys.Temperature),
+			FeelsLike:         d.FeelsLike.In(sys.Temperature),
+			MinTemp:           d.MinTemp.In(sys.Temperature),
+			MaxTemp:           d.MaxTemp.In(sys.Temperature),
+			Humidity:          d.Humidity,
+			WindSpeed:         d.WindSpeed.In(sys.Speed),
+			WindDir:           d.WindDir,
+			PrecipProbability: d.PrecipProbability,
+			Pressure:          d.Pressure.In(sys.Pressure),
+			Precipitation:     d.Precipitation.In(sys.Precipitation),
+			Visibility:        d.Visibility.In(sys.Distance),
+			Sunrise:           timestamp(d.Sunrise),
+			Sunset:            timestamp(d.Sunset),
+		})
+	}
+	return pb
+}
+
+func newAlerts(alerts []weather.Alert) []*weatherpb.Alert {
+	var out []*weatherpb.Alert
+	for _, a := range alerts {
+		out = append(out, &weatherpb.Alert{
+			Severity:    a.Severity.String(),
+			Category:    a.Category,
+			Start:       timestamp(a.Start),
+			End:         timestamp(a.End),
+			Description: a.Description,
+		})
+	}
+	return out
+}
+
+// timestamp converts t, leaving the zero time unset.
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
@@ -0,0 +1,300 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"weatherapp/internal/geo"
+	"weatherapp/internal/units"
+	"weatherapp/internal/weather"
+	"weatherapp/models"
+)
+
+// Metrics lists the forecast fields a rule can test.
+var Metrics = []string{
+	"temp", "temp_max", "temp_min", "feels_like",
+	"humidity", "wind_speed", "precip_probability", "condition",
+}
+
+// metricAliases maps friendly names onto canonical metric names.
+var metricAliases = map[string]string{
+	"max":  "temp_max",
+	"min":  "temp_min",
+	"rain": "precip_probability",
+	"wind": "wind_speed",
+}
+
+// now is the clock the forecast's dates are counted from; tests replace it.
+var now = time.Now
+
+// Trigger records a rule whose condition held for a User's forecast. Date
+// is the forecast day it held on, at midnight in the location's zone.
+type Trigger struct {
+	UserID   string
+	Name     string
+	Location string
+	Rule     models.AlertRule
+	Value    float64
+	Units    units.System
+	Date     time.Time
+}
+
+// String formats a Trigger as a single log-friendly line.
+func (t Trigger) String() string {
+	return fmt.Sprintf("%s (%s) %s: %s was %s",
+		t.Name, t.UserID, t.Location, Describe(t.Rule), formatValue(t.Rule.Metric, t.Value, t.Units))
+}
+
+// Key identifies the alert for deduplication: the same rule firing for the
+// same user and local forecast date yields the same key.
+func (t Trigger) Key() string {
+	return fmt.Sprintf("%s|%s|%s", t.UserID, Describe(t.Rule), t.Date.Format("2006-01-02"))
+}
+
+// Parse reads a rule such as "temp_max > 35 tomorrow" or "rain >= 60", or
+// a condition rule such as "condition = thunder" or "condition != clear".
+// The optional trailing day is "today", "tomorrow" or "dayN" (N days ahead).
+func Parse(s string) (models.AlertRule, error) {
+	fields := strings.Fields(strings.ToLower(s))
+	if len(fields) != 3 && len(fields) != 4 {
+		return models.AlertRule{}, fmt.Errorf("rule must look like \"<metric> <op> <value> [day]\": %q", s)
+	}
+	metric := fields[0]
+	if canonical, ok := metricAliases[metric]; ok {
+		metric = canonical
+	}
+	if !validMetric(metric) {
+		return models.AlertRule{}, fmt.Errorf("unknown metric %q (want one of %s)", fields[0], strings.Join(Metrics, ", "))
+	}
+	rule := models.AlertRule{Metric: metric, Operator: fields[1]}
+	if metric == "condition" {
+		if rule.Operator == "==" {
+			rule.Operator = "="
+		}
+		if rule.Operator != "=" && rule.Operator != "!=" {
+			return models.AlertRule{}, fmt.Errorf("unknown operator %q for condition (want = or !=)", fields[1])
+		}
+		c, err := weather.ParseCondition(fields[2])
+		if err != nil {
+			return models.AlertRule{}, err
+		}
+		rule.Condition = c.String()
+	} else {
+		switch rule.Operator {
+		case ">", ">=", "<", "<=":
+		default:
+			return models.AlertRule{}, fmt.Errorf("unknown operator %q", rule.Operator)
+		}
+		threshold, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			return models.AlertRule{}, fmt.Errorf("invalid threshold %q", fields[2])
+		}
+		rule.Threshold = threshold
+	}
+	if len(fields) == 4 {
+		day, err := parseDay(fields[3])
+		if err != nil {
+			return models.AlertRule{}, err
+		}
+		rule.Day = day
+	}
+	return rule, nil
+}
+
+// InUnits records on r the units in sys as those its threshold is written
+// in, so the rule keeps its meaning if the User's units change later.
+func InUnits(r models.AlertRule, sys units.System) models.AlertRule {
+	switch {
+	case isTemperature(r.Metric):
+		r.Unit = string(sys.Temperature)
+	case r.Metric == "wind_speed":
+		r.Unit = string(sys.Speed)
+	}
+	return r
+}
+
+// Describe renders a rule back into the syntax accepted by Parse.
+func Describe(r models.AlertRule) string {
+	if r.Metric == "condition" {
+		return fmt.Sprintf("%s %s %s %s", r.Metric, r.Operator, r.Condition, dayName(r.Day))
+	}
+	return fmt.Sprintf("%s %s %g %s", r.Metric, r.Operator, r.Threshold, dayName(r.Day))
+}
+
+// Evaluate returns the rules whose condition holds for the forecast, where
+// forecast[0] is today. Temperatures and wind speeds are converted to the
+// rule's unit first, or to the units in sys for a rule saved without one.
+// Rules for days the provider did not forecast never fire.
+func Evaluate(u models.User, sys units.System, forecast []weather.WeatherData) []Trigger {
+	var out []Trigger
+	for _, r := range u.Rules {
+		if r.Day < 0 || r.Day >= len(forecast) || forecast[r.Day].Unavailable {
+			continue
+		}
+		rs := ruleUnits(r, sys)
+		v, ok := value(forecast[r.Day], r.Metric, rs)
+		if !ok || !holds(r, v) {
+			continue
+		}
+		out = append(out, Trigger{
+			UserID:   u.UserID,
+			Name:     u.Name,
+			Location: u.Preferences.Location,
+			Rule:     r,
+			Value:    v,
+			Units:    rs,
+		})
+	}
+	return out
+}
+
+// Check fetches enough forecast days to cover a User's rules and evaluates them.
+func Check(ctx context.Context, u models.User, p weather.WeatherProvider) ([]Trigger, error) {
+	if len(u.Rules) == 0 || u.Preferences.Location == "" {
+		return nil, nil
+	}
+	sys, err := units.FromPreferences(u.Preferences)
+	if err != nil {
+		return nil, err
+	}
+	days := 1
+	for _, r := range u.Rules {
+		if r.Day+1 > days {
+			days = r.Day + 1
+		}
+	}
+	loc, err := geo.Locate(ctx, geo.FromPreferences(u.Preferences))
+	if err != nil {
+		return nil, err
+	}
+	forecast, err := p.Forecast(ctx, loc, days)
+	if err != nil {
+		return nil, err
+	}
+	zoneName := ""
+	if len(forecast) > 0 {
+		zoneName = forecast[0].TimeZone
+	}
+	local := now().In(geo.Zone(loc, zoneName))
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	triggers := Evaluate(u, sys, forecast)
+	for i := range triggers {
+		triggers[i].Date = today.AddDate(0, 0, triggers[i].Rule.Day)
+	}
+	return triggers, nil
+}
+
+// ruleUnits returns sys with r's own unit, if it has one, in place of the
+// User's for r's metric.
+func ruleUnits(r models.AlertRule, sys units.System) units.System {
+	switch {
+	case r.Unit == "":
+	case isTemperature(r.Metric):
+		sys.Temperature = units.TemperatureUnit(r.Unit)
+	case r.Metric == "wind_speed":
+		sys.Speed = units.SpeedUnit(r.Unit)
+	}
+	return sys
+}
+
+func validMetric(m string) bool {
+	for _, v := range Metrics {
+		if v == m {
+			return true
+		}
+	}
+	return false
+}
+
+func parseDay(s string) (int, error) {
+	switch s {
+	case "today":
+		return 0, nil
+	case "tomorrow":
+		return 1, nil
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(s, "day")); err == nil && n >= 0 {
+		return n, nil
+	}
+	return 0, fmt.Errorf("invalid day %q (want today, tomorrow or dayN)", s)
+}
+
+func dayName(d int) string {
+	switch d {
+	case 0:
+		return "today"
+	case 1:
+		return "tomorrow"
+	default:
+		return fmt.Sprintf("day%d", d)
+	}
+}
+
+func isTemperature(metric string) bool {
+	return strings.HasPrefix(metric, "temp") || metric == "feels_like"
+}
+
+func value(d weather.WeatherData, metric string, sys units.System) (float64, bool) {
+	switch metric {
+	case "temp":
+		return d.Temperature.In(sys.Temperature), true
+	case "temp_max":
+		return d.MaxTemp.In(sys.Temperature), true
+	case "temp_min":
+		return d.MinTemp.In(sys.Temperature), true
+	case "feels_like":
+		return d.FeelsLike.In(sys.Temperature), true
+	case "humidity":
+		return d.Humidity, true
+	case "wind_speed":
+		return d.WindSpeed.In(sys.Speed), true
+	case "precip_probability":
+		return d.PrecipProbability, true
+	case "condition":
+		if d.Condition == weather.ConditionUnknown {
+			return float64(weather.ConditionFromText(d.Description)), true
+		}
+		return float64(d.Condition), true
+	}
+	return 0, false
+}
+
+// holds reports whether a rule's condition is true for the metric's value.
+func holds(r models.AlertRule, v float64) bool {
+	if r.Metric == "condition" {
+		is := weather.Condition(v).String() == r.Condition
+		return is == (r.Operator == "=")
+	}
+	return compare(v, r.Operator, r.Threshold)
+}
+
+func compare(v float64, op string, threshold float64) bool {
+	switch op {
+	case ">":
+		return v > threshold
+	case ">=":
+		return v >= threshold
+	case "<":
+		return v < threshold
+	case "<=":
+		return v <= threshold
+	}
+	return false
+}
+
+// formatValue renders a metric's value, already in sys, with its unit.
+func formatValue(metric string, v float64, sys units.System) string {
+	switch {
+	case isTemperature(metric):
+		return sys.Temperature.Format(v)
+	case metric == "wind_speed":
+		return sys.Speed.Format(v)
+	case metric == "condition":
+		return weather.Condition(v).String()
+	default:
+		return fmt.Sprintf("%.0f%%", v)
+	}
+}
@@ -0,0 +1,207 @@
+package rules
+
+import (
+	"context"
+	"testing"
+	"time"
+	"weatherapp/internal/geo"
+	"weatherapp/internal/units"
+	"weatherapp/internal/weather"
+	"weatherapp/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProvider returns a fixed forecast and records the days requested.
+type fakeProvider struct {
+	forecast []weather.WeatherData
+	days     int
+}
+
+func (f *fakeProvider) Current(ctx context.Context, loc geo.Location) (*weather.WeatherData, error) {
+	return &f.forecast[0], nil
+}
+
+func (f *fakeProvider) Forecast(ctx context.Context, loc geo.Location, days int) ([]weather.WeatherData, error) {
+	f.days = days
+	return f.forecast, nil
+}
+
+// TestParse covers valid rules, aliases, day words and rejected input.
+func TestParse(t *testing.T) {
+	r, err := Parse("temp_max > 35 tomorrow")
+	require.NoError(t, err)
+	assert.Equal(t, models.AlertRule{Metric: "temp_max", Operator: ">", Threshold: 35, Day: 1}, r)
+
+	r, err = Parse("rain >= 60")
+	require.NoError(t, err)
+	assert.Equal(t, models.AlertRule{Metric: "precip_probability", Operator: ">=", Threshold: 60}, r)
+
+	r, err = Parse("wind < 5 day3")
+	require.NoError(t, err)
+	assert.Equal(t, 3, r.Day)
+
+	for _, bad := range []string{"", "snow > 3", "temp == 3", "temp > hot", "temp > 3 someday"} {
+		_, err := Parse(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+// TestCheck verifies rules are evaluated against the right forecast day and unit.
+func TestCheck(t *testing.T) {
+	p := &fakeProvider{forecast: []weather.WeatherData{
+		{MaxTemp: 30, PrecipProbability: 10},
+		{MaxTemp: 36, PrecipProbability: 80},
+	}}
+	u := models.User{
+		UserID:      "u1",
+		Name:        "asha",
+		Preferences: models.Preferences{Location: "Chennai", Unit: "fahrenheit"},
+		Rules: []models.AlertRule{
+			{Metric: "temp_max", Operator: ">", Threshold: 95, Day: 1},
+			{Metric: "temp_max", Operator: ">", Threshold: 95, Day: 0},
+			{Metric: "precip_probability", Operator: ">", Threshold: 60, Day: 1},
+		},
+	}
+
+	triggers, err := Check(context.Background(), u, p)
+	require.NoError(t, err)
+	assert.Equal(t, 2, p.days)
+	require.Len(t, triggers, 2)
+	assert.InDelta(t, 96.8, triggers[0].Value, 0.001)
+	assert.Equal(t, "asha (u1) Chennai: temp_max > 95 tomorrow was 97°F", triggers[0].String())
+	assert.Equal(t, "precip_probability", triggers[1].Rule.Metric)
+}
+
+// TestCheck_Unavailable verifies days the provider pads the forecast with
+// are not mistaken for zero readings or an unknown condition.
+func TestCheck_Unavailable(t *testing.T) {
+	p := &fakeProvider{forecast: []weather.WeatherData{
+		{MaxTemp: 12, WindSpeed: 20, Condition: weather.ConditionClear},
+		{Description: "Forecast unavailable", Unavailable: true},
+	}}
+	u := models.User{
+		UserID:      "u1",
+		Name:        "asha",
+		Preferences: models.Preferences{Location: "Oslo", Unit: "metric"},
+		Rules: []models.AlertRule{
+			{Metric: "temp_max", Operator: "<", Threshold: 5, Day: 1},
+			{Metric: "wind_speed", Operator: "<", Threshold: 5, Day: 1},
+			{Metric: "condition", Operator: "!=", Condition: "clear", Day: 1},
+			{Metric: "temp_max", Operator: ">", Threshold: 10, Day: 0},
+		},
+	}
+
+	triggers, err := Check(context.Background(), u, p)
+	require.NoError(t, err)
+	require.Len(t, triggers, 1)
+	assert.Equal(t, 0, triggers[0].Rule.Day)
+}
+
+// TestCheck_UnitProfile verifies wind thresholds are read in the User's speed
+// unit, and an invalid unit preference is reported rather than ignored.
+func TestCheck_UnitProfile(t *testing.T) {
+	p := &fakeProvider{forecast: []weather.WeatherData{{WindSpeed: 48.3}}}
+	u := models.User{
+		UserID:      "u1",
+		Name:        "asha",
+		Preferences: models.Preferences{Location: "Leeds", Unit: "uk"},
+		Rules:       []models.AlertRule{{Metric: "wind_speed", Operator: ">=", Threshold: 30}},
+	}
+
+	triggers, err := Check(context.Background(), u, p)
+	require.NoError(t, err)
+	require.Len(t, triggers, 1)
+	assert.Equal(t, "asha (u1) Leeds: wind_speed >= 30 today was 30 mph", triggers[0].String())
+
+	u.Preferences.Unit = "metric"
+	triggers, err = Check(context.Background(), u, p)
+	require.NoError(t, err)
+	assert.Len(t, triggers, 1)
+	assert.Equal(t, "asha (u1) Leeds: wind_speed >= 30 today was 48 km/h", triggers[0].String())
+
+	u.Preferences.UnitOverrides = map[string]string{"speed": "furlongs"}
+	_, err = Check(context.Background(), u, p)
+	assert.Error(t, err)
+}
+
+// TestCheck_RuleUnit verifies a threshold keeps the unit it was saved in
+// after the User switches units.
+func TestCheck_RuleUnit(t *testing.T) {
+	p := &fakeProvider{forecast: []weather.WeatherData{{MaxTemp: 25}}}
+	r, err := Parse("temp_max > 30")
+	require.NoError(t, err)
+	r = InUnits(r, units.Metric)
+	assert.Equal(t, "celsius", r.Unit)
+	u := models.User{
+		UserID:      "u1",
+		Name:        "asha",
+		Preferences: models.Preferences{Location: "Chennai", Unit: "imperial"},
+		Rules:       []models.AlertRule{r},
+	}
+
+	triggers, err := Check(context.Background(), u, p)
+	require.NoError(t, err)
+	assert.Empty(t, triggers)
+
+	p.forecast[0].MaxTemp = 31
+	triggers, err = Check(context.Background(), u, p)
+	require.NoError(t, err)
+	require.Len(t, triggers, 1)
+	assert.Equal(t, "asha (u1) Chennai: temp_max > 30 today was 31°C", triggers[0].String())
+}
+
+// TestTrigger_Key verifies the dedup key carries the forecast date in the
+// location's zone, not the server's.
+func TestTrigger_Key(t *testing.T) {
+	now = func() time.Time { return time.Date(2026, 10, 19, 20, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { now = time.Now })
+	p := &fakeProvider{forecast: []weather.WeatherData{{MaxTemp: 36, TimeZone: "Asia/Kolkata"}, {MaxTemp: 36}}}
+	u := models.User{
+		UserID:      "u1",
+		Preferences: models.Preferences{Location: "Chennai", Unit: "metric"},
+		Rules: []models.AlertRule{
+			{Metric: "temp_max", Operator: ">", Threshold: 35},
+			{Metric: "temp_max", Operator: ">", Threshold: 35, Day: 1},
+		},
+	}
+
+	triggers, err := Check(context.Background(), u, p)
+	require.NoError(t, err)
+	require.Len(t, triggers, 2)
+	assert.Equal(t, "u1|temp_max > 35 today|2026-10-20", triggers[0].Key())
+	assert.Equal(t, "u1|temp_max > 35 tomorrow|2026-10-21", triggers[1].Key())
+}
+
+// TestCheck_Condition verifies condition rules match across providers'
+// wordings, including descriptions without a condition code.
+func TestCheck_Condition(t *testing.T) {
+	r, err := Parse("condition == Heavy_Rain tomorrow")
+	require.NoError(t, err)
+	assert.Equal(t, models.AlertRule{Metric: "condition", Operator: "=", Condition: "heavy-rain", Day: 1}, r)
+	for _, bad := range []string{"condition > thunder", "condition = sunny"} {
+		_, err := Parse(bad)
+		assert.Error(t, err, bad)
+	}
+
+	p := &fakeProvider{forecast: []weather.WeatherData{
+		{Description: "Intermittent clouds", Condition: weather.ConditionPartlyCloudy},
+		{Description: "Moderate or heavy snow with thunder"},
+	}}
+	u := models.User{
+		UserID:      "u1",
+		Name:        "asha",
+		Preferences: models.Preferences{Location: "Oslo", Unit: "metric"},
+		Rules: []models.AlertRule{
+			{Metric: "condition", Operator: "=", Condition: "thunder", Day: 1},
+			{Metric: "condition", Operator: "!=", Condition: "partly-cloudy", Day: 0},
+			{Metric: "condition", Operator: "!=", Condition: "clear", Day: 0},
+		},
+	}
+	triggers, err := Check(context.Background(), u, p)
+	require.NoError(t, err)
+	require.Len(t, triggers, 2)
+	assert.Equal(t, "asha (u1) Oslo: condition = thunder tomorrow was thunder", triggers[0].String())
+	assert.Equal(t, "condition != clear today", Describe(triggers[1].Rule))
+}
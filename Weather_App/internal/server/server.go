@@ -0,0 +1,148 @@
+// Package server exposes the app over HTTP/JSON: registration, login with
+// bearer tokens, preferences, and weather for any location. The API is
+// described by the embedded OpenAPI document served at /openapi.yaml.
+package server
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+
+	"weatherapp/internal/storage"
+	"weatherapp/models"
+)
+
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// maxBodyBytes caps request bodies; every request we accept is tiny.
+const maxBodyBytes = 1 << 20
+
+// Server holds the dependencies of the HTTP handlers.
+type Server struct {
+	Tokens *Tokens
+}
+
+// New creates a Server that authenticates requests with tokens.
+func New(tokens *Tokens) *Server {
+	return &Server{Tokens: tokens}
+}
+
+// Handler returns the HTTP handler serving every route.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /openapi.yaml", serveSpec)
+	mux.HandleFunc("POST /v1/users", s.register)
+	mux.HandleFunc("POST /v1/login", s.login)
+	mux.Handle("GET /v1/me/preferences", s.authenticated(s.getPreferences))
+	mux.Handle("PATCH /v1/me/preferences", s.authenticated(s.updatePreferences))
+	mux.Handle("GET /v1/weather/current", s.authenticated(s.current))
+	mux.Handle("GET /v1/weather/forecast", s.authenticated(s.forecast))
+	mux.Handle("GET /v1/locations", s.authenticated(s.searchLocations))
+	return mux
+}
+
+func serveSpec(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	if _, err := w.Write(openAPISpec); err != nil {
+		slog.Debug("writing response", "error", err)
+	}
+}
+
+// authedHandler is a handler for a request with a verified bearer token.
+type authedHandler func(w http.ResponseWriter, r *http.Request, u models.User)
+
+// authenticated rejects requests without a valid bearer token for an
+// existing user, and passes that user on to next.
+func (s *Server) authenticated(next authedHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="weatherapp"`)
+			writeError(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+		userID, err := s.Tokens.Verify(strings.TrimSpace(token))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="weatherapp", error="invalid_token"`)
+			writeError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		u, ok, err := FindUser(r.Context(), userID)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "loading user", "user", userID, "error", err)
+			writeError(w, http.StatusInternalServerError, "loading user failed")
+			return
+		}
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "user no longer exists")
+			return
+		}
+		next(w, r, u)
+	})
+}
+
+// FindUser returns the User with userID, or false if there is none. The
+// REST, gRPC and web front ends all load the authenticated user with it.
+func FindUser(ctx context.Context, userID string) (models.User, bool, error) {
+	u, err := storage.GetUserByID(ctx, userID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return models.User{}, false, nil
+	}
+	if err != nil {
+		return models.User{}, false, err
+	}
+	return *u, true, nil
+}
+
+// errorBody is the JSON body of every error response.
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Debug("writing response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorBody{Error: msg})
+}
+
+// decodeJSON reads a single JSON object from the request body into v,
+// rejecting other content types, unknown fields and oversized bodies. On
+// failure it writes the error response and returns false.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		if mt, _, err := mime.ParseMediaType(ct); err != nil || mt != "application/json" {
+			writeError(w, http.StatusUnsupportedMediaType, "request body must be application/json")
+			return false
+		}
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	if err == nil && dec.Decode(&struct{}{}) != io.EOF {
+		err = errors.New("request body must contain a single JSON object")
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "request body too large")
+		} else {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		}
+		return false
+	}
+	return true
+}
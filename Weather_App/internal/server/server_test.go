@@ -0,0 +1,393 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"weatherapp/internal/geo"
+	"weatherapp/internal/storage"
+	"weatherapp/internal/weather"
+	"weatherapp/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// fakeProvider returns fixed data and records the last request.
+type fakeProvider struct {
+	location geo.Location
+	days     int
+	err      error
+}
+
+func (f *fakeProvider) Current(ctx context.Context, loc geo.Location) (*weather.WeatherData, error) {
+	f.location = loc
+	return &weather.WeatherData{Description: "Sunny", Temperature: 25}, f.err
+}
+
+func (f *fakeProvider) Forecast(ctx context.Context, loc geo.Location, days int) ([]weather.WeatherData, error) {
+	f.location, f.days = loc, days
+	return make([]weather.WeatherData, days), f.err
+}
+
+// stubGeocoder knows one Paris and two Springfields.
+type stubGeocoder struct{}
+
+func (stubGeocoder) Geocode(ctx context.Context, q string) ([]geo.Place, error) {
+	switch {
+	case strings.HasPrefix(q, "Paris"):
+		return []geo.Place{{Name: "Paris", Region: "Texas", Country: "United States", Lat: 33.66, Lon: -95.56}}, nil
+	case q == "Springfield":
+		return []geo.Place{
+			{Name: "Springfield", Region: "Illinois", Country: "United States", Lat: 39.8, Lon: -89.64},
+			{Name: "Springfield", Region: "Missouri", Country: "United States", Lat: 37.22, Lon: -93.3},
+		}, nil
+	}
+	return nil, nil
+}
+
+// apiClient talks to a test server backed by in-memory users.
+type apiClient struct {
+	t        *testing.T
+	srv      *httptest.Server
+	token    string
+	users    []models.User
+	provider *fakeProvider
+}
+
+func newAPI(t *testing.T) *apiClient {
+	c := &apiClient{t: t, provider: &fakeProvider{}}
+	origLoad, origGet, origSave, origUpdate := storage.LoadUsers, storage.GetUserByID, storage.CreateUser, storage.UpdateUser
+	origGeocoder := geo.Active()
+	t.Cleanup(func() {
+		storage.LoadUsers, storage.GetUserByID, storage.CreateUser, storage.UpdateUser = origLoad, origGet, origSave, origUpdate
+		geo.InitGeocoder(origGeocoder)
+	})
+	storage.LoadUsers = func(context.Context) ([]models.User, error) { return c.users, nil }
+	storage.GetUserByID = func(ctx context.Context, userID string) (*models.User, error) {
+		users, err := storage.LoadUsers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			if u.UserID == userID {
+				return &u, nil
+			}
+		}
+		return nil, storage.ErrUserNotFound
+	}
+	storage.CreateUser = func(_ context.Context, u models.User) error {
+		for _, existing := range c.users {
+			if existing.UserID == u.UserID || existing.Name == u.Name {
+				return storage.ErrUserExists
+			}
+		}
+		c.users = append(c.users, u)
+		return nil
+	}
+	storage.UpdateUser = func(_ context.Context, u models.User) error {
+		for i := range c.users {
+			if c.users[i].UserID == u.UserID {
+				c.users[i] = u
+			}
+		}
+		return nil
+	}
+	weather.InitProvider(c.provider)
+	geo.InitGeocoder(stubGeocoder{})
+
+	c.srv = httptest.NewServer(New(NewTokens([]byte("test-secret"), time.Hour)).Handler())
+	t.Cleanup(c.srv.Close)
+	return c
+}
+
+// do sends a request with an optional JSON body and decodes a JSON reply
+// into out, returning the status code.
+func (c *apiClient) do(method, path string, body any, out any) int {
+	c.t.Helper()
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		require.NoError(c.t, err)
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, c.srv.URL+path, r)
+	require.NoError(c.t, err)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(c.t, err)
+	defer resp.Body.Close()
+	if out != nil {
+		require.NoError(c.t, json.NewDecoder(resp.Body).Decode(out))
+	}
+	return resp.StatusCode
+}
+
+// login registers asha and stores her token.
+func (c *apiClient) login() {
+	c.t.Helper()
+	require.Equal(c.t, http.StatusCreated, c.do("POST", "/v1/users",
+		map[string]string{"user_id": "u1", "name": "asha", "password": "correct horse"}, nil))
+	var lr loginResponse
+	require.Equal(c.t, http.StatusOK, c.do("POST", "/v1/login",
+		map[string]string{"name": "asha", "password": "correct horse"}, &lr))
+	require.NotEmpty(c.t, lr.Token)
+	c.token = lr.Token
+}
+
+// TestRegisterAndLogin covers validation, conflicts and credential checks.
+func TestRegisterAndLogin(t *testing.T) {
+	c := newAPI(t)
+	var u userResponse
+	require.Equal(t, http.StatusCreated, c.do("POST", "/v1/users",
+		map[string]string{"user_id": "u1", "name": "asha", "password": "correct horse"}, &u))
+	assert.Equal(t, userResponse{UserID: "u1", Name: "asha"}, u)
+	require.Len(t, c.users, 1)
+	assert.NotEqual(t, "correct horse", c.users[0].Password)
+
+	var e errorBody
+	assert.Equal(t, http.StatusConflict, c.do("POST", "/v1/users",
+		map[string]string{"user_id": "u2", "name": "asha", "password": "whatever1"}, &e))
+	assert.Equal(t, "user already exists", e.Error)
+
+	for name, body := range map[string]any{
+		"bad id":         map[string]string{"user_id": "a/b", "name": "x", "password": "whatever1"},
+		"short password": map[string]string{"user_id": "u3", "name": "x", "password": "short"},
+		"unknown field":  map[string]string{"user_id": "u3", "name": "x", "password": "whatever1", "admin": "yes"},
+	} {
+		assert.Equal(t, http.StatusBadRequest, c.do("POST", "/v1/users", body, &e), name)
+		assert.NotEmpty(t, e.Error, name)
+	}
+
+	var lr loginResponse
+	require.Equal(t, http.StatusOK, c.do("POST", "/v1/login", map[string]string{"name": "asha", "password": "correct horse"}, &lr))
+	assert.Equal(t, "Bearer", lr.TokenType)
+	assert.Equal(t, "u1", lr.UserID)
+	assert.True(t, lr.ExpiresAt.After(time.Now()))
+
+	assert.Equal(t, http.StatusUnauthorized, c.do("POST", "/v1/login", map[string]string{"name": "asha", "password": "wrong"}, &e))
+	assert.Equal(t, http.StatusBadRequest, c.do("POST", "/v1/login", map[string]string{"name": "asha"}, &e))
+}
+
+// TestRequestValidation covers content type, malformed bodies and methods.
+func TestRequestValidation(t *testing.T) {
+	c := newAPI(t)
+	resp, err := http.Post(c.srv.URL+"/v1/login", "text/plain", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+
+	resp, err = http.Post(c.srv.URL+"/v1/login", "application/json", strings.NewReader(`{"name":"a","password":"b"} {}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Get(c.srv.URL + "/v1/login")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+// TestAuthentication checks protected routes need a valid token.
+func TestAuthentication(t *testing.T) {
+	c := newAPI(t)
+	var e errorBody
+	assert.Equal(t, http.StatusUnauthorized, c.do("GET", "/v1/me/preferences", nil, &e))
+	assert.Equal(t, "missing bearer token", e.Error)
+
+	c.token = "forged.token"
+	assert.Equal(t, http.StatusUnauthorized, c.do("GET", "/v1/weather/current?location=Paris", nil, &e))
+
+	c.token = ""
+	c.login()
+	c.users = nil
+	assert.Equal(t, http.StatusUnauthorized, c.do("GET", "/v1/me/preferences", nil, &e))
+}
+
+// TestAuthenticated_LoadsOneUser checks an authenticated request fetches
+// only the caller's document rather than the whole users collection.
+func TestAuthenticated_LoadsOneUser(t *testing.T) {
+	c := newAPI(t)
+	c.login()
+	users := c.users
+	storage.LoadUsers = func(context.Context) ([]models.User, error) {
+		t.Error("LoadUsers called")
+		return users, nil
+	}
+	storage.GetUserByID = func(_ context.Context, userID string) (*models.User, error) {
+		if userID != "u1" {
+			return nil, storage.ErrUserNotFound
+		}
+		return &users[0], nil
+	}
+	assert.Equal(t, http.StatusOK, c.do("GET", "/v1/me/preferences", nil, nil))
+}
+
+// TestStorageErrors checks a failing user store is reported as a server
+// error rather than as a missing user or wrong password.
+func TestStorageErrors(t *testing.T) {
+	c := newAPI(t)
+	c.login()
+	storage.LoadUsers = func(context.Context) ([]models.User, error) { return nil, errors.New("firestore unavailable") }
+	storage.CreateUser = func(context.Context, models.User) error { return errors.New("firestore unavailable") }
+
+	var e errorBody
+	assert.Equal(t, http.StatusInternalServerError, c.do("GET", "/v1/me/preferences", nil, &e))
+	assert.Equal(t, "loading user failed", e.Error)
+	assert.Equal(t, http.StatusInternalServerError, c.do("POST", "/v1/login",
+		map[string]string{"name": "asha", "password": "correct horse"}, &e))
+	assert.Equal(t, http.StatusInternalServerError, c.do("POST", "/v1/users",
+		map[string]string{"user_id": "u2", "name": "ravi", "password": "correct horse"}, &e))
+}
+
+// TestPreferences covers reading and partially updating preferences.
+func TestPreferences(t *testing.T) {
+	c := newAPI(t)
+	c.login()
+
+	var p preferencesBody
+	require.Equal(t, http.StatusOK, c.do("PATCH", "/v1/me/preferences",
+		map[string]string{"location": "Leeds", "unit": "uk,speed=kn", "forecast": "week"}, &p))
+	assert.Equal(t, "Leeds", *p.Location)
+	assert.Equal(t, "uk", *p.Unit)
+	assert.Equal(t, map[string]string{"speed": "kn"}, p.UnitOverrides)
+	assert.Equal(t, "week", c.users[0].Preferences.Forecast)
+
+	p = preferencesBody{}
+	require.Equal(t, http.StatusOK, c.do("PATCH", "/v1/me/preferences", map[string]string{"verbosity": "verbose"}, &p))
+	assert.Equal(t, "Leeds", *p.Location)
+	assert.Equal(t, "verbose", *p.Verbosity)
+
+	p = preferencesBody{}
+	require.Equal(t, http.StatusOK, c.do("GET", "/v1/me/preferences", nil, &p))
+	assert.Equal(t, "week", *p.Forecast)
+
+	var e errorBody
+	for name, body := range map[string]any{
+		"empty":        map[string]string{},
+		"bad unit":     map[string]string{"unit": "furlongs"},
+		"bad output":   map[string]string{"output": "xml"},
+		"read-only":    map[string]any{"unit_overrides": map[string]string{"speed": "kn"}},
+		"no location":  map[string]string{"location": " "},
+		"half a place": map[string]float64{"latitude": 1},
+		"off the map":  map[string]float64{"latitude": 91, "longitude": 0},
+	} {
+		assert.Equal(t, http.StatusBadRequest, c.do("PATCH", "/v1/me/preferences", body, &e), name)
+	}
+	assert.Equal(t, http.StatusBadRequest, c.do("PATCH", "/v1/me/preferences", map[string]string{"location": "Springfield"}, &e))
+	assert.Contains(t, e.Error, "Springfield, Missouri, United States (37.2200,-93.3000)")
+	assert.Equal(t, "week", c.users[0].Preferences.Forecast)
+}
+
+// TestPreferences_Location checks a new location is stored with the
+// coordinates it resolves to, or with the coordinates given.
+func TestPreferences_Location(t *testing.T) {
+	c := newAPI(t)
+	c.login()
+
+	var p preferencesBody
+	require.Equal(t, http.StatusOK, c.do("PATCH", "/v1/me/preferences", map[string]string{"location": "Paris"}, &p))
+	assert.Equal(t, "Paris, Texas, United States", *p.Location)
+	assert.Equal(t, &models.Coordinates{Lat: 33.66, Lon: -95.56}, c.users[0].Preferences.Coords)
+
+	p = preferencesBody{}
+	require.Equal(t, http.StatusOK, c.do("PATCH", "/v1/me/preferences",
+		map[string]any{"location": "Springfield", "latitude": 37.22, "longitude": -93.3}, &p))
+	assert.Equal(t, "Springfield", *p.Location)
+	assert.Equal(t, 37.22, *p.Latitude)
+	assert.Equal(t, &models.Coordinates{Lat: 37.22, Lon: -93.3}, c.users[0].Preferences.Coords)
+
+	p = preferencesBody{}
+	require.Equal(t, http.StatusOK, c.do("PATCH", "/v1/me/preferences",
+		map[string]any{"latitude": 51.5, "longitude": -0.12}, &p))
+	assert.Equal(t, "51.5000,-0.1200", *p.Location)
+}
+
+// TestWeather covers current conditions, forecasts and provider failures.
+func TestWeather(t *testing.T) {
+	c := newAPI(t)
+	c.login()
+
+	var e errorBody
+	assert.Equal(t, http.StatusBadRequest, c.do("GET", "/v1/weather/current", nil, &e))
+	assert.Contains(t, e.Error, "location is required")
+
+	var report struct {
+		Kind   string
+		Period string
+		Units  struct{ Temperature string }
+		Data   []struct{ Temperature float64 }
+	}
+	require.Equal(t, http.StatusOK, c.do("GET", "/v1/weather/current?location=Paris&unit=f", nil, &report))
+	assert.Equal(t, "current", report.Kind)
+	assert.Equal(t, "fahrenheit", report.Units.Temperature)
+	require.Len(t, report.Data, 1)
+	assert.Equal(t, 77.0, report.Data[0].Temperature)
+	assert.Equal(t, "Paris", c.provider.location.Query)
+
+	c.do("PATCH", "/v1/me/preferences", map[string]string{"location": "Oslo"}, nil)
+	require.Equal(t, http.StatusOK, c.do("GET", "/v1/weather/forecast?days=3", nil, &report))
+	assert.Equal(t, "forecast", report.Kind)
+	assert.Equal(t, "3 days", report.Period)
+	assert.Len(t, report.Data, 3)
+	assert.Equal(t, "Oslo", c.provider.location.Query)
+
+	assert.Equal(t, http.StatusBadRequest, c.do("GET", "/v1/weather/forecast?days=45", nil, &e))
+	assert.Equal(t, http.StatusBadRequest, c.do("GET", "/v1/weather/current?location=auto", nil, &e))
+	assert.Equal(t, http.StatusBadRequest, c.do("GET", "/v1/weather/current?unit=furlongs", nil, &e))
+
+	c.provider.err = assert.AnError
+	assert.Equal(t, http.StatusBadGateway, c.do("GET", "/v1/weather/current", nil, &e))
+}
+
+// TestSearchLocations checks geocoder and gazetteer results are combined.
+func TestSearchLocations(t *testing.T) {
+	c := newAPI(t)
+	c.login()
+
+	var resp placesResponse
+	require.Equal(t, http.StatusOK, c.do("GET", "/v1/locations?q=Pariss", nil, &resp))
+	require.NotEmpty(t, resp.Places)
+	assert.Equal(t, "geocoder", resp.Places[0].Source)
+	assert.Equal(t, "Texas", resp.Places[0].Region)
+	assert.Equal(t, "gazetteer", resp.Places[1].Source)
+	assert.Equal(t, "Paris", resp.Places[1].Name)
+
+	assert.Equal(t, http.StatusBadRequest, c.do("GET", "/v1/locations", nil, &errorBody{}))
+}
+
+// TestOpenAPISpec checks the embedded spec is served and documents every route.
+func TestOpenAPISpec(t *testing.T) {
+	c := newAPI(t)
+	resp, err := http.Get(c.srv.URL + "/openapi.yaml")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var spec struct {
+		Paths map[string]map[string]any
+	}
+	require.NoError(t, yaml.NewDecoder(resp.Body).Decode(&spec))
+	for _, route := range []string{
+		"POST /v1/users", "POST /v1/login",
+		"GET /v1/me/preferences", "PATCH /v1/me/preferences",
+		"GET /v1/weather/current", "GET /v1/weather/forecast", "GET /v1/locations",
+	} {
+		method, path, _ := strings.Cut(route, " ")
+		assert.Contains(t, spec.Paths[path], strings.ToLower(method), route)
+	}
+}
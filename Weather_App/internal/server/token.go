@@ -0,0 +1,78 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTokenTTL is how long a login token stays valid.
+const DefaultTokenTTL = 24 * time.Hour
+
+// ErrInvalidToken is returned by Tokens.Verify for a malformed, forged or
+// expired token.
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// Tokens issues and verifies stateless bearer tokens of the form
+// base64(userID|expiry).base64(HMAC-SHA256), so any instance sharing the
+// secret can check them without a session store.
+type Tokens struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// NewTokens creates a token issuer. An empty secret is replaced by a random
+// one, which means tokens do not survive a restart.
+func NewTokens(secret []byte, ttl time.Duration) *Tokens {
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			panic(err)
+		}
+	}
+	if ttl <= 0 {
+		ttl = DefaultTokenTTL
+	}
+	return &Tokens{secret: secret, ttl: ttl, now: time.Now}
+}
+
+// Issue returns a token for userID and the time it expires.
+func (t *Tokens) Issue(userID string) (string, time.Time) {
+	expires := t.now().Add(t.ttl).Truncate(time.Second)
+	payload := base64.RawURLEncoding.EncodeToString([]byte(userID + "|" + strconv.FormatInt(expires.Unix(), 10)))
+	return payload + "." + t.sign(payload), expires
+}
+
+// Verify returns the user ID a valid, unexpired token was issued for.
+func (t *Tokens) Verify(token string) (string, error) {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(t.sign(payload))) {
+		return "", ErrInvalidToken
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	i := strings.LastIndexByte(string(raw), '|')
+	if i < 0 {
+		return "", ErrInvalidToken
+	}
+	userID := string(raw[:i])
+	expires, err := strconv.ParseInt(string(raw[i+1:]), 10, 64)
+	if err != nil || userID == "" || !t.now().Before(time.Unix(expires, 0)) {
+		return "", ErrInvalidToken
+	}
+	return userID, nil
+}
+
+func (t *Tokens) sign(payload string) string {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTokens checks round trips, tampering, other secrets and expiry.
+func TestTokens(t *testing.T) {
+	tokens := NewTokens([]byte("secret"), time.Hour)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	tokens.now = func() time.Time { return now }
+
+	token, expires := tokens.Issue("u1")
+	assert.Equal(t, now.Add(time.Hour), expires)
+	id, err := tokens.Verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, "u1", id)
+
+	_, err = tokens.Verify(token + "x")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = NewTokens([]byte("other"), time.Hour).Verify(token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = tokens.Verify("not-a-token")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	now = now.Add(time.Hour)
+	_, err = tokens.Verify(token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
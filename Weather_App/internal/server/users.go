@@ -0,0 +1,166 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"weatherapp/internal/auth"
+	"weatherapp/internal/geo"
+	"weatherapp/internal/storage"
+	"weatherapp/internal/user"
+	"weatherapp/models"
+)
+
+type registerRequest struct {
+	UserID   string `json:"user_id"`
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+type userResponse struct {
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+}
+
+func (s *Server) register(w http.ResponseWriter, r *http.Request) {
+	var req registerRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if err := auth.ValidateRegistration(req.UserID, req.Name, req.Password); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err := auth.RegisterUser(r.Context(), req.UserID, req.Name, req.Password)
+	switch {
+	case errors.Is(err, auth.ErrUserExists):
+		writeError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		slog.ErrorContext(r.Context(), "saving user", "user", req.UserID, "error", err)
+		writeError(w, http.StatusInternalServerError, "saving user failed")
+	default:
+		w.Header().Set("Location", "/v1/me/preferences")
+		writeJSON(w, http.StatusCreated, userResponse{UserID: req.UserID, Name: req.Name})
+	}
+}
+
+type loginRequest struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+type loginResponse struct {
+	Token     string    `json:"token"`
+	TokenType string    `json:"token_type"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UserID    string    `json:"user_id"`
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Name == "" || req.Password == "" {
+		writeError(w, http.StatusBadRequest, "name and password are required")
+		return
+	}
+	u, err := auth.Authenticate(r.Context(), req.Name, req.Password)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		slog.InfoContext(r.Context(), "login failed", "name", req.Name)
+		writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "loading users", "error", err)
+		writeError(w, http.StatusInternalServerError, "loading users failed")
+		return
+	}
+	token, expires := s.Tokens.Issue(u.UserID)
+	writeJSON(w, http.StatusOK, loginResponse{Token: token, TokenType: "Bearer", ExpiresAt: expires, UserID: u.UserID})
+}
+
+// preferencesBody is the JSON form of Preferences. In a PATCH request
+// omitted fields are left unchanged.
+type preferencesBody struct {
+	Location      *string           `json:"location,omitempty"`
+	Latitude      *float64          `json:"latitude,omitempty"`
+	Longitude     *float64          `json:"longitude,omitempty"`
+	Unit          *string           `json:"unit,omitempty"`
+	UnitOverrides map[string]string `json:"unit_overrides,omitempty"`
+	Verbosity     *string           `json:"verbosity,omitempty"`
+	Forecast      *string           `json:"forecast,omitempty"`
+	Output        *string           `json:"output,omitempty"`
+	Language      *string           `json:"language,omitempty"`
+}
+
+func newPreferencesBody(p models.Preferences) preferencesBody {
+	b := preferencesBody{
+		Location:      &p.Location,
+		Unit:          &p.Unit,
+		UnitOverrides: p.UnitOverrides,
+		Verbosity:     &p.Verbosity,
+		Forecast:      &p.Forecast,
+		Output:        &p.Output,
+		Language:      &p.Language,
+	}
+	if p.Coords != nil {
+		b.Latitude, b.Longitude = &p.Coords.Lat, &p.Coords.Lon
+	}
+	return b
+}
+
+func (s *Server) getPreferences(w http.ResponseWriter, _ *http.Request, u models.User) {
+	writeJSON(w, http.StatusOK, newPreferencesBody(u.Preferences))
+}
+
+func (s *Server) updatePreferences(w http.ResponseWriter, r *http.Request, u models.User) {
+	var req preferencesBody
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.UnitOverrides != nil {
+		writeError(w, http.StatusBadRequest, "set unit instead of unit_overrides")
+		return
+	}
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		writeError(w, http.StatusBadRequest, "latitude and longitude must be set together")
+		return
+	}
+	changes := user.PreferenceChanges{
+		Location:  req.Location,
+		Unit:      req.Unit,
+		Verbosity: req.Verbosity,
+		Forecast:  req.Forecast,
+		Output:    req.Output,
+		Language:  req.Language,
+	}
+	if req.Latitude != nil {
+		changes.Coords = &models.Coordinates{Lat: *req.Latitude, Lon: *req.Longitude}
+		if changes.Location == nil {
+			loc := geo.FormatCoords(*req.Latitude, *req.Longitude)
+			changes.Location = &loc
+		}
+	}
+	if changes.Empty() {
+		writeError(w, http.StatusBadRequest, "no preferences to change")
+		return
+	}
+	if err := changes.ResolveLocation(r.Context()); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error()+"; set latitude and longitude to choose one")
+		return
+	}
+	if err := changes.Apply(&u.Preferences); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := storage.UpdateUser(r.Context(), u); err != nil {
+		slog.ErrorContext(r.Context(), "saving preferences", "user", u.UserID, "error", err)
+		writeError(w, http.StatusInternalServerError, "saving preferences failed")
+		return
+	}
+	writeJSON(w, http.StatusOK, newPreferencesBody(u.Preferences))
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"weatherapp/internal/geo"
+	"weatherapp/internal/weather"
+	"weatherapp/models"
+)
+
+func (s *Server) current(w http.ResponseWriter, r *http.Request, u models.User) {
+	s.writeWeather(w, r, u, 0)
+}
+
+func (s *Server) forecast(w http.ResponseWriter, r *http.Request, u models.User) {
+	days := 7
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 30 {
+			writeError(w, http.StatusBadRequest, "days must be a number from 1 to 30")
+			return
+		}
+		days = n
+	}
+	s.writeWeather(w, r, u, days)
+}
+
+// writeWeather responds with a JSON report for the "location" query
+// parameter, or the user's preferred location, in the "unit" parameter's
+// units or the user's own.
+func (s *Server) writeWeather(w http.ResponseWriter, r *http.Request, u models.User, days int) {
+	q := r.URL.Query()
+	loc, sys, err := weather.RequestTarget(u.Preferences, q.Get("location"), q.Get("unit"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := weather.FetchReport(r.Context(), loc, days, u.Preferences.Language)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "weather provider: "+err.Error())
+		return
+	}
+	report.Units = sys
+	if days > 0 {
+		report.Period = strconv.Itoa(days) + " days"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := (weather.JSONRenderer{}).Render(w, report); err != nil {
+		slog.DebugContext(r.Context(), "writing response", "error", err)
+	}
+}
+
+type placeResponse struct {
+	Name      string  `json:"name"`
+	Region    string  `json:"region,omitempty"`
+	Country   string  `json:"country,omitempty"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	TimeZone  string  `json:"time_zone,omitempty"`
+	// Source is "geocoder" for the configured geocoding service or
+	// "gazetteer" for the built-in city list.
+	Source string `json:"source"`
+}
+
+type placesResponse struct {
+	Query  string          `json:"query"`
+	Places []placeResponse `json:"places"`
+}
+
+// searchLocations lists candidate places for a query, so clients can offer
+// the same disambiguation and typo correction as the CLI.
+func (s *Server) searchLocations(w http.ResponseWriter, r *http.Request, _ models.User) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+	resp := placesResponse{Query: query, Places: []placeResponse{}}
+	if g := geo.Active(); g != nil {
+		places, err := g.Geocode(r.Context(), query)
+		if err != nil {
+			writeError(w, http.StatusBadGateway, "geocoder: "+err.Error())
+			return
+		}
+		for _, p := range places {
+			resp.Places = append(resp.Places, newPlaceResponse(p, "geocoder"))
+		}
+	}
+	for _, m := range geo.Cities().Search(query, 5) {
+		resp.Places = append(resp.Places, newPlaceResponse(m.City.Place(), "gazetteer"))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func newPlaceResponse(p geo.Place, source string) placeResponse {
+	return placeResponse{
+		Name: p.Name, Region: p.Region, Country: p.Country,
+		Latitude: p.Lat, Longitude: p.Lon, TimeZone: p.TimeZone,
+		Source: source,
+	}
+}
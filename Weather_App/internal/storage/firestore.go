@@ -1,74 +1,163 @@
-package storage
-
-import (
-	"context"
-	"log"
-	"os"
-
-	"weatherapp/models"
-
-	"cloud.google.com/go/firestore"
-)
-
-var (
-	Client *firestore.Client
-
-	// SaveUser writes a User into the "users" collection
-	SaveUser = func(u models.User) error {
-		ctx := context.Background()
-		_, err := Client.Collection("users").Doc(u.UserID).Set(ctx, u)
-		return err
-	}
-
-	// LoadUsers reads all users from Firestore
-	LoadUsers = func() []models.User {
-		ctx := context.Background()
-		iter := Client.Collection("users").Documents(ctx)
-		docs, err := iter.GetAll()
-		if err != nil {
-			return []models.User{}
-		}
-		var users []models.User
-		for _, doc := range docs {
-			var u models.User
-			doc.DataTo(&u)
-			users = append(users, u)
-		}
-		return users
-	}
-
-	// UpdateUser overwrites a single user document in Firestore
-	UpdateUser = func(u models.User) error {
-		ctx := context.Background()
-		_, err := Client.Collection("users").Doc(u.UserID).Set(ctx, u)
-		return err
-	}
-)
-
-// InitFirestore initializes the Firestore client
-func InitFirestore() {
-	ctx := context.Background()
-	projectID := os.Getenv("GOOGLE_CLOUD_PROJECT")
-	if projectID == "" {
-		log.Fatal("GOOGLE_CLOUD_PROJECT must be set")
-	}
-	var err error
-	Client, err = firestore.NewClient(ctx, projectID)
-	if err != nil {
-		log.Fatalf("Failed to connect to Firestore: %v", err)
-	}
-}
-
-// GetUserByID fetches one user document by its ID
-func GetUserByID(userID string) (*models.User, error) {
-	ctx := context.Background()
-	doc, err := Client.Collection("users").Doc(userID).Get(ctx)
-	if err != nil {
-		return nil, err
-	}
-	var u models.User
-	if err := doc.DataTo(&u); err != nil {
-		return nil, err
-	}
-	return &u, nil
-}
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"weatherapp/internal/metrics"
+	"weatherapp/models"
+
+	"cloud.google.com/go/firestore"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/api/iterator"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// tracerName is the instrumentation scope of the package's spans.
+const tracerName = "weatherapp/internal/storage"
+
+// ErrUserNotFound is returned by GetUserByID when no user has the ID.
+var ErrUserNotFound = errors.New("user not found")
+
+// ErrUserExists is returned by CreateUser when the ID or name is taken.
+var ErrUserExists = errors.New("user already exists")
+
+var (
+	Client *firestore.Client
+
+	// CreateUser adds a new User to the "users" collection, failing with
+	// ErrUserExists if a user already has its ID or name. Both are checked
+	// in one transaction, so concurrent sign-ups cannot overwrite each other.
+	CreateUser = func(ctx context.Context, u models.User) error {
+		ctx, end := observe(ctx, "create_user", attribute.String("user.id", u.UserID))
+		err := createUser(ctx, u)
+		end(err)
+		return err
+	}
+
+	// LoadUsers reads all users from Firestore
+	LoadUsers = func(ctx context.Context) ([]models.User, error) {
+		ctx, end := observe(ctx, "load_users")
+		users, err := loadUsers(ctx)
+		end(err)
+		return users, err
+	}
+
+	// UpdateUser overwrites a single user document in Firestore
+	UpdateUser = func(ctx context.Context, u models.User) error {
+		ctx, end := observe(ctx, "update_user")
+		err := setUser(ctx, u)
+		end(err)
+		return err
+	}
+
+	// GetUserByID fetches one user document by its ID, returning
+	// ErrUserNotFound if there is none
+	GetUserByID = func(ctx context.Context, userID string) (u *models.User, err error) {
+		ctx, end := observe(ctx, "get_user", attribute.String("user.id", userID))
+		defer func() { end(err) }()
+		doc, err := Client.Collection("users").Doc(userID).Get(ctx)
+		if status.Code(err) == grpccodes.NotFound {
+			return nil, ErrUserNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		u = &models.User{}
+		if err := doc.DataTo(u); err != nil {
+			return nil, err
+		}
+		return u, nil
+	}
+
+	// Ping checks Firestore answers by reading at most one user, for the
+	// readiness probe.
+	Ping = func(ctx context.Context) error {
+		if Client == nil {
+			return errors.New("Firestore is not connected")
+		}
+		ctx, end := observe(ctx, "ping")
+		_, err := Client.Collection("users").Limit(1).Documents(ctx).Next()
+		if errors.Is(err, iterator.Done) {
+			err = nil
+		}
+		end(err)
+		return err
+	}
+)
+
+// observe starts a span for the storage operation op. The returned function
+// ends it, recording err and the operation's latency.
+func observe(ctx context.Context, op string, attrs ...attribute.KeyValue) (context.Context, func(err error)) {
+	start := time.Now()
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "storage."+op, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(append(attrs, semconv.DBSystemKey.String("firestore"), semconv.DBCollectionName("users"))...))
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+		metrics.StorageOperation(op, start, err)
+	}
+}
+
+func setUser(ctx context.Context, u models.User) error {
+	_, err := Client.Collection("users").Doc(u.UserID).Set(ctx, u)
+	return err
+}
+
+func createUser(ctx context.Context, u models.User) error {
+	users := Client.Collection("users")
+	err := Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		taken, err := tx.Documents(users.Where("Name", "==", u.Name).Limit(1)).GetAll()
+		if err != nil {
+			return err
+		}
+		if len(taken) > 0 {
+			return ErrUserExists
+		}
+		return tx.Create(users.Doc(u.UserID), u)
+	})
+	if status.Code(err) == grpccodes.AlreadyExists {
+		return ErrUserExists
+	}
+	return err
+}
+
+func loadUsers(ctx context.Context) ([]models.User, error) {
+	docs, err := Client.Collection("users").Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("loading users: %w", err)
+	}
+	var users []models.User
+	for _, doc := range docs {
+		var u models.User
+		if err := doc.DataTo(&u); err != nil {
+			return nil, fmt.Errorf("reading user %s: %w", doc.Ref.ID, err)
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+// InitFirestore initializes the Firestore client for projectID
+func InitFirestore(projectID string) error {
+	ctx := context.Background()
+	if projectID == "" {
+		return errors.New("no Firestore project; set storage.project_id or GOOGLE_CLOUD_PROJECT")
+	}
+	var err error
+	Client, err = firestore.NewClient(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("connecting to Firestore: %w", err)
+	}
+	return nil
+}
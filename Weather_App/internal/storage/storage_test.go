@@ -1,83 +1,108 @@
-package storage
- 
-import (
-    "context"
-    "errors"
-    "testing"
-    "weatherapp/models"
- 
-    "cloud.google.com/go/firestore"
-    "github.com/stretchr/testify/assert"
-)
- 
-// Mocked Firestore Client Components
- 
-type mockDocRef struct{}
- 
-
-type mockClient struct{}
-
-
- // Set mocks setting data in Firestore (used for testing).
-func (m *mockDocRef) Set(_ context.Context, _ interface{}, _ ...firestore.SetOption) (*firestore.WriteResult, error) {
-    return nil, nil
-}
-
-// Collection mocks retrieving a Firestore collection (used for testing).
-func (m *mockClient) Collection(name string) *firestore.CollectionRef {
-    return &firestore.CollectionRef{}
-}
-
-// TestSaveUser_Fake verifies SaveUser behavior for both success and failure cases.
-func TestSaveUser_Fake(t *testing.T) {
-    Client = &firestore.Client{}
-    SaveUser = func(user models.User) error {
-        if user.UserID == "fail" {
-            return errors.New("simulated Firestore error")
-        }
-        return nil
-    }
-
-    t.Run("Successful save", func(t *testing.T) {
-        err := SaveUser(models.User{UserID: "123"})
-        assert.NoError(t, err)
-    })
-
-    t.Run("Simulated Firestore error", func(t *testing.T) {
-        err := SaveUser(models.User{UserID: "fail"})
-        assert.Error(t, err)
-    })
-}
-
-// TestLoadUsers_Fake verifies mock LoadUsers returns expected user data.
-func TestLoadUsers_Fake(t *testing.T) {
-    LoadUsers = func() []models.User {
-        return []models.User{
-            {UserID: "u1", Name: "Test User"},
-        }
-    }
-
-    users := LoadUsers()
-    assert.Len(t, users, 1)
-    assert.Equal(t, "u1", users[0].UserID)
-}
-
-// TestUpdateUser_Fake checks UpdateUser handling for success and failure.
-func TestUpdateUser_Fake(t *testing.T) {
-    UpdateUser = func(user models.User) error {
-        if user.UserID == "fail" {
-            return errors.New("update failed")
-        }
-        return nil
-    }
-
-    t.Run("Update success", func(t *testing.T) {
-        err := UpdateUser(models.User{UserID: "ok"})
-        assert.NoError(t, err)
-    })
-
-    t.Run("Update failure", func(t *testing.T) {
-        err := UpdateUser(models.User{UserID: "fail"})
-        assert.Error(t, err)
-    })
-}
\ No newline at end of file
+package storage
+ 
+import (
+    "context"
+    "errors"
+    "testing"
+    "weatherapp/internal/tracing/tracingtest"
+    "weatherapp/models"
+ 
+    "cloud.google.com/go/firestore"
+    "github.com/stretchr/testify/assert"
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/codes"
+)
+ 
+// Mocked Firestore Client Components
+ 
+type mockDocRef struct{}
+ 
+
+type mockClient struct{}
+
+
+ // Set mocks setting data in Firestore (used for testing).
+func (m *mockDocRef) Set(_ context.Context, _ interface{}, _ ...firestore.SetOption) (*firestore.WriteResult, error) {
+    return nil, nil
+}
+
+// Collection mocks retrieving a Firestore collection (used for testing).
+func (m *mockClient) Collection(name string) *firestore.CollectionRef {
+    return &firestore.CollectionRef{}
+}
+
+// TestCreateUser_Fake verifies CreateUser behavior for both success and failure cases.
+func TestCreateUser_Fake(t *testing.T) {
+    Client = &firestore.Client{}
+    CreateUser = func(_ context.Context, user models.User) error {
+        if user.UserID == "fail" {
+            return errors.New("simulated Firestore error")
+        }
+        return nil
+    }
+
+    t.Run("Successful save", func(t *testing.T) {
+        err := CreateUser(context.Background(), models.User{UserID: "123"})
+        assert.NoError(t, err)
+    })
+
+    t.Run("Simulated Firestore error", func(t *testing.T) {
+        err := CreateUser(context.Background(), models.User{UserID: "fail"})
+        assert.Error(t, err)
+    })
+}
+
+// TestLoadUsers_Fake verifies mock LoadUsers returns expected user data.
+func TestLoadUsers_Fake(t *testing.T) {
+    LoadUsers = func(context.Context) ([]models.User, error) {
+        return []models.User{
+            {UserID: "u1", Name: "Test User"},
+        }, nil
+    }
+
+    users, err := LoadUsers(context.Background())
+    assert.NoError(t, err)
+    assert.Len(t, users, 1)
+    assert.Equal(t, "u1", users[0].UserID)
+}
+
+// TestUpdateUser_Fake checks UpdateUser handling for success and failure.
+func TestUpdateUser_Fake(t *testing.T) {
+    UpdateUser = func(_ context.Context, user models.User) error {
+        if user.UserID == "fail" {
+            return errors.New("update failed")
+        }
+        return nil
+    }
+
+    t.Run("Update success", func(t *testing.T) {
+        err := UpdateUser(context.Background(), models.User{UserID: "ok"})
+        assert.NoError(t, err)
+    })
+
+    t.Run("Update failure", func(t *testing.T) {
+        err := UpdateUser(context.Background(), models.User{UserID: "fail"})
+        assert.Error(t, err)
+    })
+}
+// TestObserve checks storage operations are traced as children of the
+// caller's span and failures are marked on the span.
+func TestObserve(t *testing.T) {
+    spans, restore := tracingtest.UseMemory()
+    defer restore()
+
+    ctx, parent := otel.Tracer("test").Start(context.Background(), "view weather")
+    _, end := observe(ctx, "get_user", attribute.String("user.id", "u1"))
+    end(errors.New("not found"))
+    parent.End()
+
+    got := spans.GetSpans()
+    assert.Len(t, got, 2)
+    span := got[0]
+    assert.Equal(t, "storage.get_user", span.Name)
+    assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+    assert.Equal(t, codes.Error, span.Status.Code)
+    assert.Contains(t, span.Attributes, attribute.String("user.id", "u1"))
+    assert.Contains(t, span.Attributes, attribute.String("db.system", "firestore"))
+}
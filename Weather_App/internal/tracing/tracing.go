@@ -0,0 +1,58 @@
+// Package tracing sets up OpenTelemetry tracing. Packages start spans from
+// their own otel.Tracer, which is a no-op until Setup installs an exporter,
+// and pass the span's context down so that one operation, e.g. "View My
+// Weather", is one trace from the user store to the provider's HTTP calls.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// ServiceName identifies the app's spans.
+const ServiceName = "weatherapp"
+
+// Propagator sends and receives the W3C trace context and baggage headers.
+var Propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// Setup installs the tracer provider and the W3C trace context propagator.
+// Spans go to exporter, the tracing.exporter setting: "none" or empty,
+// "stdout", which writes spans to w as JSON, or "otlp", which sends them
+// over OTLP/HTTP to the collector configured by the standard
+// OTEL_EXPORTER_OTLP_* variables. The returned function flushes buffered
+// spans and must be called before exit.
+func Setup(ctx context.Context, exporter string, w io.Writer) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(Propagator)
+	var exp sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	case "otlp":
+		exp, err = otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("tracing.exporter: unknown exporter %q (want none, stdout or otlp)", exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tracing.exporter: %w", err)
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(Resource()))
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// Resource describes the process that emits the spans.
+func Resource() *resource.Resource {
+	return resource.NewSchemaless(semconv.ServiceName(ServiceName))
+}
@@ -0,0 +1,33 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+)
+
+// TestSetup checks the exporter is chosen by name and buffered spans are
+// written when the returned function flushes them.
+func TestSetup(t *testing.T) {
+	orig := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(orig) })
+	_, err := Setup(context.Background(), "zipkin", nil)
+	assert.ErrorContains(t, err, `unknown exporter "zipkin"`)
+
+	shutdown, err := Setup(context.Background(), "", nil)
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	var buf bytes.Buffer
+	shutdown, err = Setup(context.Background(), "stdout", &buf)
+	require.NoError(t, err)
+	_, span := otel.Tracer("test").Start(context.Background(), "view weather")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+	assert.Contains(t, buf.String(), `"Name":"view weather"`)
+	assert.Contains(t, buf.String(), ServiceName)
+}
@@ -0,0 +1,27 @@
+// Package tracingtest records spans in memory so tests can check what
+// was traced.
+package tracingtest
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"weatherapp/internal/tracing"
+)
+
+// UseMemory installs a tracer provider recording every span in memory, and
+// the propagator. The returned function restores the previous provider.
+func UseMemory() (*tracetest.InMemoryExporter, func()) {
+	otel.SetTextMapPropagator(tracing.Propagator)
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp), sdktrace.WithResource(tracing.Resource()))
+	orig := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	return exp, func() {
+		otel.SetTracerProvider(orig)
+		_ = tp.Shutdown(context.Background())
+	}
+}
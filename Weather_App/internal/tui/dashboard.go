@@ -0,0 +1,319 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"weatherapp/internal/geo"
+	"weatherapp/internal/logging"
+	"weatherapp/internal/units"
+	"weatherapp/internal/weather"
+	"weatherapp/models"
+)
+
+var errNoLocation = errors.New("no location set; press p to choose one")
+
+// weatherMsg is the result of fetching the shown location. forecast is
+// empty when the Forecast preference is "day".
+type weatherMsg struct {
+	seq         int
+	at          time.Time
+	sys         units.System
+	current     weather.Report
+	forecast    weather.Report
+	err         error
+	forecastErr error
+}
+
+// savedRow is the current conditions at one saved location.
+type savedRow struct {
+	description string
+	temperature string
+	err         error
+}
+
+type savedMsg struct {
+	seq  int
+	rows []savedRow
+}
+
+// refresh starts fetching the shown location and the saved locations'
+// current conditions.
+func (m *model) refresh() tea.Cmd {
+	m.seq++
+	m.loading = true
+	prefs := m.user.Preferences
+	if m.target >= 0 && m.target < len(prefs.SavedLocations) {
+		s := prefs.SavedLocations[m.target]
+		prefs.Location, prefs.Coords = s.Location, s.Coords
+	}
+	return tea.Batch(fetchWeather(m.seq, prefs), fetchSaved(m.seq, prefs))
+}
+
+func fetchWeather(seq int, prefs models.Preferences) tea.Cmd {
+	return func() tea.Msg {
+		msg := weatherMsg{seq: seq, at: time.Now(), sys: systemFor(prefs)}
+		if prefs.Location == "" {
+			msg.err = errNoLocation
+			return msg
+		}
+		ctx := logging.Start(context.Background(), "tui refresh")
+		loc := geo.FromPreferences(prefs)
+		msg.current, msg.err = weather.FetchReport(ctx, loc, 0, prefs.Language)
+		if msg.err != nil {
+			return msg
+		}
+		if days := weather.ForecastDays(prefs.Forecast); days > 0 {
+			msg.forecast, msg.forecastErr = weather.FetchReport(ctx, loc, days, prefs.Language)
+		}
+		return msg
+	}
+}
+
+func fetchSaved(seq int, prefs models.Preferences) tea.Cmd {
+	locs := prefs.SavedLocations
+	if len(locs) == 0 {
+		return func() tea.Msg { return savedMsg{seq: seq} }
+	}
+	sys := systemFor(prefs)
+	return func() tea.Msg {
+		ctx := logging.Start(context.Background(), "tui saved locations")
+		rows := make([]savedRow, len(locs))
+		for i, l := range locs {
+			report, err := weather.FetchReport(ctx, geo.FromSaved(l), 0, prefs.Language)
+			if err != nil {
+				rows[i].err = err
+				continue
+			}
+			rows[i].description = report.Data[0].Description
+			rows[i].temperature = sys.FormatTemperature(report.Data[0].Temperature)
+		}
+		return savedMsg{seq: seq, rows: rows}
+	}
+}
+
+// systemFor is the user's unit system, falling back to metric if the
+// stored preference is invalid.
+func systemFor(p models.Preferences) units.System {
+	sys, err := units.FromPreferences(p)
+	if err != nil {
+		return units.Metric
+	}
+	return sys
+}
+
+var (
+	accent      = lipgloss.AdaptiveColor{Light: "#5A56E0", Dark: "#7D79F6"}
+	muted       = lipgloss.AdaptiveColor{Light: "#8A8A8A", Dark: "#6C6C6C"}
+	titleStyle  = lipgloss.NewStyle().Bold(true).Foreground(accent)
+	mutedStyle  = lipgloss.NewStyle().Foreground(muted)
+	errorStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	alertStyle  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("11"))
+	paneStyle   = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(muted).Padding(0, 1)
+	focusStyle  = paneStyle.BorderForeground(accent)
+	headerStyle = lipgloss.NewStyle().Bold(true)
+)
+
+const helpText = "tab/1-3 pane · ↑/↓ move · enter show saved · esc preferred · r refresh · p preferences · m menus · q quit"
+
+func (m *model) View() string {
+	if m.quitting {
+		return ""
+	}
+	if m.screen == screenDashboard {
+		return m.dashboardView()
+	}
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(formTitles[m.screen]) + "\n\n")
+	if m.status != "" {
+		b.WriteString(errorStyle.Render(m.status) + "\n\n")
+	}
+	if m.form == nil {
+		b.WriteString(mutedStyle.Render("Working…"))
+	} else {
+		b.WriteString(m.form.View())
+	}
+	return b.String()
+}
+
+var formTitles = map[screen]string{
+	screenAuth:        "Weather App",
+	screenPreferences: "Preferences",
+	screenPlaces:      "Choose a place",
+}
+
+func (m *model) dashboardView() string {
+	width := m.width
+	if width <= 0 {
+		width = 80
+	}
+	header := m.header()
+	footer := mutedStyle.Render(helpText)
+	if m.status != "" {
+		footer = m.status + "\n" + footer
+	}
+
+	var top string
+	if width >= 100 {
+		left := width / 2
+		top = lipgloss.JoinHorizontal(lipgloss.Top,
+			m.pane(paneCurrent, "Current", m.currentLines(), left),
+			m.pane(paneSaved, "Saved locations", m.savedLines(), width-left))
+	} else {
+		top = lipgloss.JoinVertical(lipgloss.Left,
+			m.pane(paneCurrent, "Current", m.currentLines(), width),
+			m.pane(paneSaved, "Saved locations", m.savedLines(), width))
+	}
+
+	rows := 7
+	if m.height > 0 {
+		used := lipgloss.Height(header) + lipgloss.Height(top) + lipgloss.Height(footer) + 3
+		rows = max(3, m.height-used)
+	}
+	forecast := m.pane(paneForecast, "Forecast", m.forecastLines(rows), width)
+	return lipgloss.JoinVertical(lipgloss.Left, header, top, forecast, footer)
+}
+
+func (m *model) header() string {
+	parts := []string{titleStyle.Render("Weather App"), m.user.Name}
+	if loc := m.weather.current.Location.String(); m.weather.err == nil && loc != "" {
+		parts = append(parts, loc)
+	}
+	if m.target >= 0 && m.target < len(m.user.Preferences.SavedLocations) {
+		parts = append(parts, "saved: "+m.user.Preferences.SavedLocations[m.target].Name)
+	}
+	switch {
+	case m.loading:
+		parts = append(parts, mutedStyle.Render("refreshing…"))
+	case !m.updated.IsZero():
+		parts = append(parts, mutedStyle.Render("updated "+m.updated.Format("15:04:05")))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// pane draws lines in a bordered box width columns wide, highlighting the
+// border when p has the focus.
+func (m *model) pane(p pane, title string, lines []string, width int) string {
+	style := paneStyle
+	if m.focus == p {
+		style = focusStyle
+	}
+	body := headerStyle.Render(fmt.Sprintf("%d %s", p+1, title)) + "\n" + strings.Join(lines, "\n")
+	return style.Width(max(width-2, 10)).Render(body)
+}
+
+func (m *model) currentLines() []string {
+	w := m.weather
+	switch {
+	case w.err != nil:
+		return []string{errorStyle.Render(w.err.Error())}
+	case len(w.current.Data) == 0:
+		return []string{mutedStyle.Render("Loading…")}
+	}
+	d, sys := w.current.Data[0], w.sys
+	lines := []string{
+		w.current.Location.String(),
+		mutedStyle.Render(d.Time.Format("Mon 02 Jan 15:04 MST")),
+		d.Condition.Icon() + " " + d.Description,
+		fmt.Sprintf("Temperature  %s (feels like %s)", sys.FormatTemperature(d.Temperature), sys.FormatTemperature(d.FeelsLike)),
+		fmt.Sprintf("Humidity     %.0f%%", d.Humidity),
+		"Wind         " + strings.TrimSpace(sys.FormatSpeed(d.WindSpeed)+" "+d.WindDir),
+	}
+	if d.Pressure > 0 {
+		lines = append(lines, "Pressure     "+sys.FormatPressure(d.Pressure))
+	}
+	if d.Precipitation > 0 {
+		lines = append(lines, "Precip.      "+sys.FormatPrecipitation(d.Precipitation))
+	}
+	if d.Visibility > 0 {
+		lines = append(lines, "Visibility   "+sys.FormatDistance(d.Visibility))
+	}
+	if !d.Sunrise.IsZero() && !d.Sunset.IsZero() {
+		lines = append(lines, "Sun          "+d.Sunrise.Format("15:04")+" – "+d.Sunset.Format("15:04"))
+	}
+	for _, a := range w.current.Alerts {
+		lines = append(lines, alertStyle.Render(fmt.Sprintf("! %s %s: %s", a.Severity, a.Category, a.Description)))
+	}
+	return lines
+}
+
+// forecastLines shows up to rows forecast days starting at the scroll
+// position.
+func (m *model) forecastLines(rows int) []string {
+	w := m.weather
+	switch {
+	case w.err != nil:
+		return []string{mutedStyle.Render("No forecast.")}
+	case w.forecastErr != nil:
+		return []string{errorStyle.Render("Could not get the forecast: " + w.forecastErr.Error())}
+	case len(w.current.Data) == 0:
+		return []string{mutedStyle.Render("Loading…")}
+	case len(w.forecast.Data) == 0:
+		return []string{mutedStyle.Render("Your forecast preference is \"day\"; press p to show a week or month.")}
+	}
+	days := w.forecast.Data
+	start := clamp(m.scroll, 0, len(days)-1)
+	end := min(start+rows, len(days))
+	var lines []string
+	if start > 0 {
+		lines = append(lines, mutedStyle.Render(fmt.Sprintf("↑ %d more", start)))
+	}
+	for _, d := range days[start:end] {
+		line := fmt.Sprintf("%-10s  %s %-20s  %s – %s  %s", d.Time.Format("Mon 02 Jan"), d.Condition.Icon(), truncate(d.Description, 20),
+			w.sys.FormatTemperature(d.MinTemp), w.sys.FormatTemperature(d.MaxTemp), w.sys.FormatSpeed(d.WindSpeed))
+		if d.Precipitation > 0 {
+			line += "  " + w.sys.FormatPrecipitation(d.Precipitation)
+		}
+		lines = append(lines, line)
+	}
+	if end < len(days) {
+		lines = append(lines, mutedStyle.Render(fmt.Sprintf("↓ %d more", len(days)-end)))
+	}
+	return lines
+}
+
+func (m *model) savedLines() []string {
+	locs := m.user.Preferences.SavedLocations
+	if len(locs) == 0 {
+		return []string{mutedStyle.Render("No saved locations.")}
+	}
+	lines := make([]string, len(locs))
+	for i, l := range locs {
+		cursor := "  "
+		if m.focus == paneSaved && i == m.selected {
+			cursor = "› "
+		}
+		name := l.Name
+		if l.Default {
+			name += " (default)"
+		}
+		if i == m.target {
+			name = headerStyle.Render(name)
+		}
+		line := cursor + name + "  " + mutedStyle.Render(l.Location)
+		if i < len(m.saved) {
+			switch row := m.saved[i]; {
+			case row.err != nil:
+				line += "  " + errorStyle.Render("unavailable")
+			default:
+				line += "  " + row.temperature + " " + row.description
+			}
+		}
+		lines[i] = line
+	}
+	return lines
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,302 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+
+	"weatherapp/internal/auth"
+	"weatherapp/internal/geo"
+	"weatherapp/internal/i18n"
+	"weatherapp/internal/storage"
+	"weatherapp/internal/units"
+	"weatherapp/internal/user"
+	"weatherapp/internal/weather"
+	"weatherapp/models"
+)
+
+const (
+	modeLogin    = "login"
+	modeRegister = "register"
+)
+
+// authForm holds the values bound to the login and register form.
+type authForm struct {
+	mode     string
+	userID   string
+	name     string
+	password string
+}
+
+// authMsg is the outcome of logging in or registering.
+type authMsg struct {
+	user models.User
+	err  error
+}
+
+func (m *model) openAuth(a *authForm) {
+	m.screen, m.auth = screenAuth, a
+	registering := func() bool { return a.mode == modeRegister }
+	m.form = huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Log in or create an account").
+				Options(huh.NewOption("Log in", modeLogin), huh.NewOption("Register", modeRegister)).
+				Value(&a.mode),
+		),
+		huh.NewGroup(
+			huh.NewInput().Title("User ID").Value(&a.userID).
+				Validate(func(s string) error { return auth.ValidateUserID(strings.TrimSpace(s)) }),
+		).WithHideFunc(func() bool { return !registering() }),
+		huh.NewGroup(
+			huh.NewInput().Title("Name").Value(&a.name).
+				Validate(func(s string) error {
+					if registering() {
+						return auth.ValidateName(strings.TrimSpace(s))
+					}
+					return required("name")(s)
+				}),
+			huh.NewInput().Title("Password").EchoMode(huh.EchoModePassword).Value(&a.password).
+				Validate(func(s string) error {
+					if registering() {
+						return auth.ValidatePassword(s)
+					}
+					return required("password")(s)
+				}),
+		),
+	)
+}
+
+// authenticate logs in, registering the account first if asked to.
+func authenticate(a authForm) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+		name := strings.TrimSpace(a.name)
+		if a.mode == modeRegister {
+			if err := auth.RegisterUser(ctx, strings.TrimSpace(a.userID), name, a.password); err != nil {
+				return authMsg{err: err}
+			}
+		}
+		u, err := auth.Authenticate(ctx, name, a.password)
+		return authMsg{user: u, err: err}
+	}
+}
+
+// authenticated opens the dashboard, or first the preferences form for a
+// user with no location yet. On failure the form is shown again.
+func (m *model) authenticated(msg authMsg) tea.Cmd {
+	if msg.err != nil {
+		switch {
+		case errors.Is(msg.err, auth.ErrInvalidCredentials):
+			m.status = "Invalid name or password."
+		case errors.Is(msg.err, auth.ErrUserExists):
+			m.status = "That user ID or name is already taken."
+		default:
+			m.status = "Error: " + msg.err.Error()
+		}
+		m.openAuth(&authForm{mode: m.auth.mode, userID: m.auth.userID, name: m.auth.name})
+		return m.form.Init()
+	}
+	m.status, m.user = "", msg.user
+	if m.user.Preferences.Location == "" {
+		return m.openPreferences()
+	}
+	m.screen = screenDashboard
+	return m.refresh()
+}
+
+// prefsForm holds the values bound to the preferences form.
+type prefsForm struct {
+	location  string
+	profile   string
+	overrides string
+	verbosity string
+	forecast  string
+	output    string
+	language  string
+}
+
+func newPrefsForm(p models.Preferences) *prefsForm {
+	f := &prefsForm{
+		location:  p.Location,
+		profile:   orDefault(p.Unit, "metric"),
+		verbosity: orDefault(p.Verbosity, "brief"),
+		forecast:  orDefault(p.Forecast, "week"),
+		output:    orDefault(p.Output, "text"),
+		language:  p.Language,
+	}
+	var overrides []string
+	for _, q := range units.Quantities {
+		if unit, ok := p.UnitOverrides[q]; ok {
+			overrides = append(overrides, q+"="+unit)
+		}
+	}
+	f.overrides = strings.Join(overrides, ", ")
+	return f
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return strings.ToLower(s)
+}
+
+func (m *model) openPreferences() tea.Cmd {
+	p := newPrefsForm(m.user.Preferences)
+	m.screen, m.prefs, m.status = screenPreferences, p, ""
+	m.form = huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().Title("Location").
+				Description(`City, "lat,lon", postal code with country, or auto`).
+				Value(&p.location).Validate(required("location")),
+			huh.NewSelect[string]().Title("Units").
+				Options(huh.NewOptions("metric", "imperial", "uk", "si")...).Value(&p.profile),
+			huh.NewInput().Title("Unit overrides").
+				Description("Optional, e.g. speed=kn, pressure=inHg").
+				Value(&p.overrides).Validate(validateOverrides),
+		),
+		huh.NewGroup(
+			huh.NewSelect[string]().Title("Verbosity").
+				Options(huh.NewOptions("brief", "verbose")...).Value(&p.verbosity),
+			huh.NewSelect[string]().Title("Forecast").
+				Options(huh.NewOptions("day", "week", "month")...).Value(&p.forecast),
+			huh.NewSelect[string]().Title("Output format for reports").
+				Options(huh.NewOptions(weather.Formats...)...).Value(&p.output),
+			huh.NewSelect[string]().Title("Language").
+				Options(languageOptions()...).Value(&p.language),
+		),
+	)
+	return m.form.Init()
+}
+
+// languageOptions offers the supported languages after the environment's
+// default.
+func languageOptions() []huh.Option[string] {
+	options := []huh.Option[string]{huh.NewOption("system default", "")}
+	return append(options, huh.NewOptions(i18n.Languages...)...)
+}
+
+func required(field string) func(string) error {
+	return func(s string) error {
+		if strings.TrimSpace(s) == "" {
+			return fmt.Errorf("%s is required", field)
+		}
+		return nil
+	}
+}
+
+// validateOverrides checks the "quantity=unit" list accepted after a unit
+// profile.
+func validateOverrides(s string) error {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	_, _, err := units.ParsePreference("metric, " + s)
+	return err
+}
+
+// resolvedMsg carries validated Preferences whose new location has been
+// looked up.
+type resolvedMsg struct {
+	prefs  models.Preferences
+	places []geo.Place
+	exact  bool
+}
+
+type prefsSavedMsg struct {
+	user models.User
+	err  error
+}
+
+// applyPreferences validates the submitted form and, if the location
+// changed, looks up the places it may mean.
+func (m *model) applyPreferences() tea.Cmd {
+	p := m.prefs
+	unit := p.profile
+	if o := strings.TrimSpace(p.overrides); o != "" {
+		unit += ", " + o
+	}
+	changes := user.PreferenceChanges{Location: &p.location, Unit: &unit, Verbosity: &p.verbosity, Forecast: &p.forecast, Output: &p.output, Language: &p.language}
+	next := m.user.Preferences
+	if err := changes.Apply(&next); err != nil {
+		m.status = "Error: " + err.Error()
+		m.screen = screenDashboard
+		return nil
+	}
+	if next.Location == m.user.Preferences.Location {
+		user.SetLocation(&next, next.Location, m.user.Preferences.Coords)
+		return m.savePreferences(next)
+	}
+	return func() tea.Msg {
+		places, exact := geo.Candidates(context.Background(), next.Location)
+		return resolvedMsg{prefs: next, places: places, exact: exact}
+	}
+}
+
+// resolved stores an exact match or asks which candidate was meant.
+func (m *model) resolved(msg resolvedMsg) tea.Cmd {
+	switch {
+	case msg.exact:
+		user.SetLocation(&msg.prefs, msg.places[0].String(), msg.places[0].Coordinates())
+	case len(msg.places) > 0:
+		return m.openPlaces(msg)
+	}
+	return m.savePreferences(msg.prefs)
+}
+
+// placeForm offers the candidates for an ambiguous or misspelled
+// location; choice -1 keeps it as typed.
+type placeForm struct {
+	prefs  models.Preferences
+	places []geo.Place
+	choice int
+}
+
+func (m *model) openPlaces(msg resolvedMsg) tea.Cmd {
+	p := &placeForm{prefs: msg.prefs, places: msg.places}
+	options := make([]huh.Option[int], 0, len(p.places)+1)
+	for i, place := range p.places {
+		options = append(options, huh.NewOption(fmt.Sprintf("%s (%.2f, %.2f)", place, place.Lat, place.Lon), i))
+	}
+	options = append(options, huh.NewOption(fmt.Sprintf("Keep %q as typed", p.prefs.Location), -1))
+	m.screen, m.places = screenPlaces, p
+	m.form = huh.NewForm(huh.NewGroup(
+		huh.NewSelect[int]().Title(fmt.Sprintf("Several places match %q", p.prefs.Location)).
+			Options(options...).Value(&p.choice),
+	))
+	return m.form.Init()
+}
+
+func (m *model) choosePlace() tea.Cmd {
+	p := m.places
+	if p.choice >= 0 && p.choice < len(p.places) {
+		place := p.places[p.choice]
+		user.SetLocation(&p.prefs, place.String(), place.Coordinates())
+	}
+	return m.savePreferences(p.prefs)
+}
+
+func (m *model) savePreferences(prefs models.Preferences) tea.Cmd {
+	u := m.user
+	u.Preferences = prefs
+	return func() tea.Msg {
+		return prefsSavedMsg{user: u, err: storage.UpdateUser(context.Background(), u)}
+	}
+}
+
+// prefsSaved returns to the dashboard showing the preferred location.
+func (m *model) prefsSaved(msg prefsSavedMsg) tea.Cmd {
+	m.screen = screenDashboard
+	if msg.err != nil {
+		m.status = "Saving preferences failed: " + msg.err.Error()
+		return nil
+	}
+	m.user, m.status = msg.user, "Preferences saved."
+	m.target, m.scroll = -1, 0
+	return m.refresh()
+}
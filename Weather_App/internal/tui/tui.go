@@ -0,0 +1,240 @@
+// Package tui is the full-screen terminal front end: a login form, then a
+// dashboard with panes for current conditions, the forecast and saved
+// locations that refreshes itself on an interval. Alert rules,
+// notifications, editing saved locations and other locations are left to
+// the numbered menus, which the dashboard hands over to with m.
+package tui
+
+import (
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/x/term"
+
+	"weatherapp/models"
+)
+
+// DefaultInterval is how often the dashboard refreshes unless configured.
+const DefaultInterval = 5 * time.Minute
+
+// Available reports whether in and out are both terminals; the numbered
+// menus are used otherwise.
+func Available(in, out *os.File) bool {
+	return term.IsTerminal(in.Fd()) && term.IsTerminal(out.Fd())
+}
+
+// Run shows the terminal UI until the user quits. A non-positive interval
+// means DefaultInterval. If the user asked for the numbered menus instead,
+// it returns their UserID so the menus can carry on without logging in
+// again; otherwise it returns "".
+func Run(interval time.Duration) (string, error) {
+	final, err := tea.NewProgram(newModel(interval), tea.WithAltScreen()).Run()
+	if err != nil {
+		return "", err
+	}
+	if m, ok := final.(*model); ok && m.menus {
+		return m.user.UserID, nil
+	}
+	return "", nil
+}
+
+type screen int
+
+const (
+	screenAuth screen = iota
+	screenDashboard
+	screenPreferences
+	screenPlaces
+)
+
+type pane int
+
+const (
+	paneCurrent pane = iota
+	paneForecast
+	paneSaved
+	paneCount
+)
+
+// model is the Bubble Tea model. It is used through a pointer so the
+// values huh forms are bound to stay put while the form is open.
+type model struct {
+	interval      time.Duration
+	width, height int
+
+	screen screen
+	form   *huh.Form
+	auth   *authForm
+	prefs  *prefsForm
+	places *placeForm
+
+	user  models.User
+	focus pane
+	// target is the saved location being shown, or -1 for the preferred
+	// location; selected is the cursor in the saved pane.
+	target   int
+	selected int
+	scroll   int
+
+	// seq numbers refreshes so results of superseded ones are dropped.
+	seq      int
+	loading  bool
+	weather  weatherMsg
+	saved    []savedRow
+	updated  time.Time
+	status   string
+	quitting bool
+	// menus is set when the user leaves for the numbered menus.
+	menus bool
+}
+
+func newModel(interval time.Duration) *model {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	m := &model{interval: interval, target: -1}
+	m.openAuth(&authForm{mode: modeLogin})
+	return m
+}
+
+// tickMsg triggers an automatic refresh.
+type tickMsg time.Time
+
+func (m *model) tick() tea.Cmd {
+	return tea.Tick(m.interval, func(t time.Time) tea.Msg { return tickMsg(t) })
+}
+
+func (m *model) Init() tea.Cmd {
+	return tea.Batch(m.form.Init(), m.tick())
+}
+
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	case tickMsg:
+		if m.screen == screenDashboard {
+			return m, tea.Batch(m.refresh(), m.tick())
+		}
+		return m, m.tick()
+	case weatherMsg:
+		if msg.seq == m.seq {
+			m.weather, m.loading, m.updated = msg, false, msg.at
+		}
+		return m, nil
+	case savedMsg:
+		if msg.seq == m.seq {
+			m.saved = msg.rows
+		}
+		return m, nil
+	case authMsg:
+		return m, m.authenticated(msg)
+	case resolvedMsg:
+		return m, m.resolved(msg)
+	case prefsSavedMsg:
+		return m, m.prefsSaved(msg)
+	}
+
+	if m.form != nil {
+		return m, m.updateForm(msg)
+	}
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		if m.screen == screenDashboard {
+			return m, m.handleKey(msg)
+		}
+		if msg.String() == "ctrl+c" {
+			m.quitting = true
+			return m, tea.Quit
+		}
+	}
+	return m, nil
+}
+
+// updateForm passes msg to the open form and acts on it being submitted
+// or cancelled.
+func (m *model) updateForm(msg tea.Msg) tea.Cmd {
+	f, cmd := m.form.Update(msg)
+	m.form = f.(*huh.Form)
+	switch m.form.State {
+	case huh.StateCompleted:
+		return m.submit()
+	case huh.StateAborted:
+		return m.cancel()
+	}
+	return cmd
+}
+
+func (m *model) submit() tea.Cmd {
+	m.form = nil
+	switch m.screen {
+	case screenAuth:
+		return authenticate(*m.auth)
+	case screenPreferences:
+		return m.applyPreferences()
+	case screenPlaces:
+		return m.choosePlace()
+	}
+	return nil
+}
+
+func (m *model) cancel() tea.Cmd {
+	m.form = nil
+	if m.screen == screenAuth {
+		m.quitting = true
+		return tea.Quit
+	}
+	m.screen = screenDashboard
+	return nil
+}
+
+func (m *model) handleKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		m.quitting = true
+		return tea.Quit
+	case "tab":
+		m.focus = (m.focus + 1) % paneCount
+	case "shift+tab":
+		m.focus = (m.focus + paneCount - 1) % paneCount
+	case "1", "2", "3":
+		m.focus = pane(msg.String()[0] - '1')
+	case "up", "k":
+		m.move(-1)
+	case "down", "j":
+		m.move(1)
+	case "enter":
+		if m.focus == paneSaved && m.selected < len(m.user.Preferences.SavedLocations) {
+			m.target, m.scroll = m.selected, 0
+			return m.refresh()
+		}
+	case "esc":
+		if m.target >= 0 {
+			m.target, m.scroll = -1, 0
+			return m.refresh()
+		}
+	case "r":
+		return m.refresh()
+	case "p":
+		return m.openPreferences()
+	case "m":
+		m.menus, m.quitting = true, true
+		return tea.Quit
+	}
+	return nil
+}
+
+// move scrolls the forecast or moves the saved-location cursor.
+func (m *model) move(delta int) {
+	switch m.focus {
+	case paneForecast:
+		m.scroll = clamp(m.scroll+delta, 0, len(m.weather.forecast.Data)-1)
+	case paneSaved:
+		m.selected = clamp(m.selected+delta, 0, len(m.user.Preferences.SavedLocations)-1)
+	}
+}
+
+func clamp(v, lo, hi int) int {
+	return max(lo, min(v, hi))
+}
@@ -0,0 +1,286 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/crypto/bcrypt"
+
+	"weatherapp/internal/geo"
+	"weatherapp/internal/storage"
+	"weatherapp/internal/weather"
+	"weatherapp/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProvider returns fixed conditions named after the location and
+// records the locations asked for.
+type fakeProvider struct{ asked []string }
+
+func (f *fakeProvider) Current(ctx context.Context, loc geo.Location) (*weather.WeatherData, error) {
+	f.asked = append(f.asked, loc.String())
+	if loc.String() == "Nowhere" {
+		return nil, errors.New("unknown location")
+	}
+	return &weather.WeatherData{Description: "Sunny in " + loc.String(), Temperature: 20, Humidity: 40}, nil
+}
+
+func (f *fakeProvider) Forecast(ctx context.Context, loc geo.Location, days int) ([]weather.WeatherData, error) {
+	data := make([]weather.WeatherData, days)
+	for i := range data {
+		data[i] = weather.WeatherData{Description: "Cloudy", MinTemp: 10, MaxTemp: 18}
+	}
+	return data, nil
+}
+
+// fakeGeocoder returns fixed candidates.
+type fakeGeocoder struct{ places []geo.Place }
+
+func (f fakeGeocoder) Geocode(ctx context.Context, query string) ([]geo.Place, error) {
+	return f.places, nil
+}
+
+// setup installs a fake provider and in-memory storage holding one user,
+// Ann, with password "secret-pw".
+func setup(t *testing.T) (*fakeProvider, *[]models.User) {
+	origLoad, origSave, origUpdate := storage.LoadUsers, storage.CreateUser, storage.UpdateUser
+	origProvider, origGeocoder := weather.Provider(), geo.Active()
+	t.Cleanup(func() {
+		storage.LoadUsers, storage.CreateUser, storage.UpdateUser = origLoad, origSave, origUpdate
+		weather.InitProvider(origProvider)
+		geo.InitGeocoder(origGeocoder)
+	})
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret-pw"), bcrypt.MinCost)
+	require.NoError(t, err)
+	users := []models.User{{
+		UserID: "ann", Name: "Ann", Password: string(hash),
+		Preferences: models.Preferences{
+			Location: "Paris", Unit: "metric", Verbosity: "brief", Forecast: "week",
+			SavedLocations: []models.SavedLocation{
+				{Name: "Home", Location: "Paris", Default: true},
+				{Name: "Work", Location: "Lyon"},
+			},
+		},
+	}}
+	storage.LoadUsers = func(context.Context) ([]models.User, error) { return users, nil }
+	storage.CreateUser = func(_ context.Context, u models.User) error {
+		for _, existing := range users {
+			if existing.UserID == u.UserID || existing.Name == u.Name {
+				return storage.ErrUserExists
+			}
+		}
+		users = append(users, u)
+		return nil
+	}
+	storage.UpdateUser = func(_ context.Context, u models.User) error {
+		for i := range users {
+			if users[i].UserID == u.UserID {
+				users[i] = u
+			}
+		}
+		return nil
+	}
+	p := &fakeProvider{}
+	weather.InitProvider(p)
+	geo.InitGeocoder(nil)
+	return p, &users
+}
+
+// loggedIn returns a model showing the dashboard for Ann.
+func loggedIn(t *testing.T) *model {
+	m := newModel(time.Hour)
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	m.auth.name, m.auth.password = "Ann", "secret-pw"
+	run(t, m, m.submit())
+	require.Equal(t, screenDashboard, m.screen)
+	return m
+}
+
+// run executes cmd and feeds the messages it produces back into m until
+// no more work is left. Commands still running after a short wait, such
+// as refresh ticks and cursor blinks, are dropped.
+func run(t *testing.T, m *model, cmd tea.Cmd) {
+	t.Helper()
+	for _, msg := range execute(cmd) {
+		_, next := m.Update(msg)
+		run(t, m, next)
+	}
+}
+
+func execute(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	done := make(chan tea.Msg, 1)
+	go func() { done <- cmd() }()
+	select {
+	case msg := <-done:
+		batch, ok := msg.(tea.BatchMsg)
+		if !ok {
+			return []tea.Msg{msg}
+		}
+		var out []tea.Msg
+		for _, c := range batch {
+			out = append(out, execute(c)...)
+		}
+		return out
+	case <-time.After(100 * time.Millisecond):
+		return nil
+	}
+}
+
+func key(s string) tea.KeyMsg {
+	switch s {
+	case "tab":
+		return tea.KeyMsg{Type: tea.KeyTab}
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+// TestDashboard checks the three panes are filled in after logging in.
+func TestDashboard(t *testing.T) {
+	setup(t)
+	m := loggedIn(t)
+
+	view := m.View()
+	assert.Contains(t, view, "☀️ Sunny in Paris")
+	assert.Contains(t, view, "20°C")
+	assert.Contains(t, view, "Cloudy")
+	assert.Contains(t, view, "Home (default)")
+	assert.Contains(t, view, "Sunny in Lyon")
+	assert.Contains(t, view, "updated ")
+	assert.False(t, m.loading)
+}
+
+// TestDashboard_Navigation covers switching panes, showing a saved
+// location and going back to the preferred one.
+func TestDashboard_Navigation(t *testing.T) {
+	p, _ := setup(t)
+	m := loggedIn(t)
+
+	m.Update(key("tab"))
+	assert.Equal(t, paneForecast, m.focus)
+	m.Update(key("j"))
+	assert.Equal(t, 1, m.scroll)
+	assert.Contains(t, m.View(), "↑ 1 more")
+
+	m.Update(key("3"))
+	assert.Equal(t, paneSaved, m.focus)
+	m.Update(key("j"))
+	m.Update(key("j"))
+	assert.Equal(t, 1, m.selected, "cursor stops at the last location")
+
+	p.asked = nil
+	_, cmd := m.Update(key("enter"))
+	run(t, m, cmd)
+	assert.Equal(t, 1, m.target)
+	assert.Equal(t, "Lyon", p.asked[0])
+	assert.Contains(t, m.View(), "saved: Work")
+
+	_, cmd = m.Update(key("esc"))
+	run(t, m, cmd)
+	assert.Equal(t, -1, m.target)
+	assert.Contains(t, m.header(), "Paris")
+
+	_, cmd = m.Update(key("q"))
+	assert.NotNil(t, cmd)
+	assert.True(t, m.quitting)
+	assert.False(t, m.menus)
+}
+
+// TestDashboard_Menus checks m leaves for the numbered menus as the
+// logged-in user.
+func TestDashboard_Menus(t *testing.T) {
+	setup(t)
+	m := loggedIn(t)
+	_, cmd := m.Update(key("m"))
+	assert.NotNil(t, cmd)
+	assert.True(t, m.quitting)
+	assert.True(t, m.menus)
+	assert.NotEmpty(t, m.user.UserID)
+}
+
+// TestDashboard_StaleResults checks results of a superseded refresh are
+// dropped and ticks start a new refresh.
+func TestDashboard_StaleResults(t *testing.T) {
+	setup(t)
+	m := loggedIn(t)
+
+	old := m.seq
+	m.Update(tickMsg(time.Now()))
+	assert.Equal(t, old+1, m.seq)
+	assert.True(t, m.loading)
+
+	m.Update(weatherMsg{seq: old, err: errors.New("stale")})
+	assert.True(t, m.loading)
+	assert.NotContains(t, m.View(), "stale")
+
+	m.Update(weatherMsg{seq: m.seq, err: errors.New("provider down")})
+	assert.False(t, m.loading)
+	assert.Contains(t, m.View(), "provider down")
+}
+
+// TestAuth covers failed logins, registration and the first-run
+// preferences form.
+func TestAuth(t *testing.T) {
+	_, users := setup(t)
+	m := newModel(time.Hour)
+
+	m.Update(authenticate(authForm{mode: modeLogin, name: "Ann", password: "wrong"})())
+	assert.Equal(t, screenAuth, m.screen)
+	assert.Equal(t, "Invalid name or password.", m.status)
+	assert.NotNil(t, m.form)
+
+	m.Update(authenticate(authForm{mode: modeRegister, userID: "bob", name: "Ann", password: "secret-pw"})())
+	assert.Equal(t, "That user ID or name is already taken.", m.status)
+
+	m.Update(authenticate(authForm{mode: modeRegister, userID: "bob", name: " Bob ", password: "secret-pw"})())
+	require.Len(t, *users, 2)
+	assert.Equal(t, "Bob", m.user.Name)
+	assert.Equal(t, screenPreferences, m.screen, "new users choose a location first")
+	assert.Empty(t, m.status)
+	assert.Equal(t, "metric", m.prefs.profile)
+}
+
+// TestPreferences covers validation and picking among several places.
+func TestPreferences(t *testing.T) {
+	_, users := setup(t)
+	m := loggedIn(t)
+
+	assert.NoError(t, validateOverrides(""))
+	assert.NoError(t, validateOverrides("speed=kn, pressure=inHg"))
+	assert.Error(t, validateOverrides("speed=furlongs"))
+	assert.Error(t, required("location")("  "))
+
+	m.Update(key("p"))
+	require.Equal(t, screenPreferences, m.screen)
+	geo.InitGeocoder(fakeGeocoder{places: []geo.Place{
+		{Name: "Springfield", Region: "Illinois", Country: "US", Lat: 39.8, Lon: -89.64},
+		{Name: "Springfield", Region: "Missouri", Country: "US", Lat: 37.21, Lon: -93.29},
+	}})
+	m.prefs.location, m.prefs.overrides, m.prefs.forecast = "Springfield", "speed=kn", "day"
+	run(t, m, m.submit())
+	require.Equal(t, screenPlaces, m.screen)
+	assert.Contains(t, m.View(), "Several places match")
+
+	m.places.choice = 1
+	run(t, m, m.submit())
+	assert.Equal(t, screenDashboard, m.screen)
+	assert.Equal(t, "Preferences saved.", m.status)
+	saved := (*users)[0].Preferences
+	assert.Equal(t, "Springfield, Missouri, US", saved.Location)
+	require.NotNil(t, saved.Coords)
+	assert.Equal(t, 37.21, saved.Coords.Lat)
+	assert.Equal(t, map[string]string{"speed": "kn"}, saved.UnitOverrides)
+	assert.True(t, strings.Contains(m.View(), "Your forecast preference is \"day\""))
+}
@@ -0,0 +1,153 @@
+package units
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"weatherapp/models"
+)
+
+// System is the display unit chosen for each kind of quantity.
+type System struct {
+	Temperature   TemperatureUnit
+	Speed         SpeedUnit
+	Pressure      PressureUnit
+	Precipitation PrecipitationUnit
+	Distance      DistanceUnit
+}
+
+// Preset unit systems.
+var (
+	Metric   = System{Celsius, KilometersPerHour, Hectopascals, Millimeters, Kilometers}
+	Imperial = System{Fahrenheit, MilesPerHour, InchesOfMercury, Inches, Miles}
+	UK       = System{Celsius, MilesPerHour, Hectopascals, Millimeters, Miles}
+	SI       = System{Kelvin, MetersPerSecond, Hectopascals, Millimeters, Kilometers}
+)
+
+// Profiles maps preference profile names onto their System. "celsius",
+// "fahrenheit" and "kelvin" are kept for preferences saved before profiles
+// existed, and "c", "f" and "k" are shorthands for them.
+var Profiles = map[string]System{
+	"metric":     Metric,
+	"imperial":   Imperial,
+	"uk":         UK,
+	"si":         SI,
+	"celsius":    Metric,
+	"fahrenheit": Imperial,
+	"kelvin":     SI,
+	"c":          Metric,
+	"f":          Imperial,
+	"k":          SI,
+}
+
+// Quantities lists the names accepted by System.With.
+var Quantities = []string{"temperature", "speed", "pressure", "precipitation", "distance"}
+
+// ParseProfile returns the System for a profile name. An empty name is Metric.
+func ParseProfile(name string) (System, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return Metric, nil
+	}
+	if s, ok := Profiles[name]; ok {
+		return s, nil
+	}
+	return System{}, fmt.Errorf("unknown unit profile %q (want metric, imperial, uk or si)", name)
+}
+
+// With returns s with the unit for one quantity replaced, e.g.
+// With("speed", "knots").
+func (s System) With(quantity, unit string) (System, error) {
+	var err error
+	switch strings.ToLower(strings.TrimSpace(quantity)) {
+	case "temperature", "temp":
+		s.Temperature, err = ParseTemperatureUnit(unit)
+	case "speed", "wind":
+		s.Speed, err = ParseSpeedUnit(unit)
+	case "pressure":
+		s.Pressure, err = ParsePressureUnit(unit)
+	case "precipitation", "precip", "rain":
+		s.Precipitation, err = ParsePrecipitationUnit(unit)
+	case "distance", "visibility":
+		s.Distance, err = ParseDistanceUnit(unit)
+	default:
+		return s, fmt.Errorf("unknown quantity %q (want one of %s)", quantity, strings.Join(Quantities, ", "))
+	}
+	return s, err
+}
+
+// Resolve builds a System from a profile name and per-quantity overrides.
+func Resolve(profile string, overrides map[string]string) (System, error) {
+	s, err := ParseProfile(profile)
+	if err != nil {
+		return System{}, err
+	}
+	// Apply overrides in a fixed order so errors are deterministic.
+	keys := make([]string, 0, len(overrides))
+	for k := range overrides {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if s, err = s.With(k, overrides[k]); err != nil {
+			return System{}, err
+		}
+	}
+	return s, nil
+}
+
+// FromPreferences returns the System a User's Preferences ask for.
+func FromPreferences(p models.Preferences) (System, error) {
+	return Resolve(p.Unit, p.UnitOverrides)
+}
+
+// FormatTemperature renders t in the System's temperature unit.
+func (s System) FormatTemperature(t Temperature) string {
+	return s.Temperature.Format(t.In(s.Temperature))
+}
+
+// FormatSpeed renders v in the System's speed unit.
+func (s System) FormatSpeed(v Speed) string {
+	return s.Speed.Format(v.In(s.Speed))
+}
+
+// FormatPressure renders p in the System's pressure unit.
+func (s System) FormatPressure(p Pressure) string {
+	return s.Pressure.Format(p.In(s.Pressure))
+}
+
+// FormatPrecipitation renders p in the System's precipitation unit.
+func (s System) FormatPrecipitation(p Precipitation) string {
+	return s.Precipitation.Format(p.In(s.Precipitation))
+}
+
+// FormatDistance renders d in the System's distance unit.
+func (s System) FormatDistance(d Distance) string {
+	return s.Distance.Format(d.In(s.Distance))
+}
+
+// ParsePreference reads a unit preference typed as a profile optionally
+// followed by overrides, e.g. "uk" or "metric, speed=kn, pressure=inHg". It
+// returns the profile name and overrides to store in Preferences.
+func ParsePreference(s string) (profile string, overrides map[string]string, err error) {
+	parts := strings.Split(s, ",")
+	profile = strings.ToLower(strings.TrimSpace(parts[0]))
+	if _, err := ParseProfile(profile); err != nil {
+		return "", nil, err
+	}
+	for _, part := range parts[1:] {
+		q, u, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return "", nil, fmt.Errorf("unit override must look like quantity=unit: %q", part)
+		}
+		if _, err := Metric.With(q, u); err != nil {
+			return "", nil, err
+		}
+		if overrides == nil {
+			overrides = map[string]string{}
+		}
+		overrides[strings.ToLower(strings.TrimSpace(q))] = strings.TrimSpace(u)
+	}
+	return profile, overrides, nil
+}
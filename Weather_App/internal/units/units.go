@@ -0,0 +1,254 @@
+// Package units holds typed weather quantities and the unit systems used to
+// display them. Quantities are stored in a fixed base unit (°C, km/h, hPa,
+// mm, km) and only converted when rendered.
+package units
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Temperature is a temperature in degrees Celsius.
+type Temperature float64
+
+// Speed is a speed in kilometres per hour.
+type Speed float64
+
+// Pressure is an atmospheric pressure in hectopascals (millibars).
+type Pressure float64
+
+// Precipitation is a precipitation depth in millimetres.
+type Precipitation float64
+
+// Distance is a distance in kilometres.
+type Distance float64
+
+// TemperatureUnit is a unit a Temperature can be shown in.
+type TemperatureUnit string
+
+// SpeedUnit is a unit a Speed can be shown in.
+type SpeedUnit string
+
+// PressureUnit is a unit a Pressure can be shown in.
+type PressureUnit string
+
+// PrecipitationUnit is a unit a Precipitation can be shown in.
+type PrecipitationUnit string
+
+// DistanceUnit is a unit a Distance can be shown in.
+type DistanceUnit string
+
+const (
+	Celsius    TemperatureUnit = "celsius"
+	Fahrenheit TemperatureUnit = "fahrenheit"
+	Kelvin     TemperatureUnit = "kelvin"
+
+	KilometersPerHour SpeedUnit = "km/h"
+	MilesPerHour      SpeedUnit = "mph"
+	MetersPerSecond   SpeedUnit = "m/s"
+	Knots             SpeedUnit = "kn"
+
+	Hectopascals         PressureUnit = "hPa"
+	InchesOfMercury      PressureUnit = "inHg"
+	MillimetersOfMercury PressureUnit = "mmHg"
+	Kilopascals          PressureUnit = "kPa"
+
+	Millimeters PrecipitationUnit = "mm"
+	Inches      PrecipitationUnit = "in"
+
+	Kilometers DistanceUnit = "km"
+	Miles      DistanceUnit = "mi"
+)
+
+// In returns t expressed in u.
+func (t Temperature) In(u TemperatureUnit) float64 {
+	switch u {
+	case Fahrenheit:
+		return float64(t)*9/5 + 32
+	case Kelvin:
+		return float64(t) + 273.15
+	default:
+		return float64(t)
+	}
+}
+
+// In returns s expressed in u.
+func (s Speed) In(u SpeedUnit) float64 {
+	switch u {
+	case MilesPerHour:
+		return float64(s) / 1.609344
+	case MetersPerSecond:
+		return float64(s) / 3.6
+	case Knots:
+		return float64(s) / 1.852
+	default:
+		return float64(s)
+	}
+}
+
+// In returns p expressed in u.
+func (p Pressure) In(u PressureUnit) float64 {
+	switch u {
+	case InchesOfMercury:
+		return float64(p) / 33.8639
+	case MillimetersOfMercury:
+		return float64(p) / 1.33322
+	case Kilopascals:
+		return float64(p) / 10
+	default:
+		return float64(p)
+	}
+}
+
+// In returns p expressed in u.
+func (p Precipitation) In(u PrecipitationUnit) float64 {
+	if u == Inches {
+		return float64(p) / 25.4
+	}
+	return float64(p)
+}
+
+// In returns d expressed in u.
+func (d Distance) In(u DistanceUnit) float64 {
+	if u == Miles {
+		return float64(d) / 1.609344
+	}
+	return float64(d)
+}
+
+// Symbol returns the unit's display symbol, e.g. "°F".
+func (u TemperatureUnit) Symbol() string {
+	switch u {
+	case Fahrenheit:
+		return "°F"
+	case Kelvin:
+		return "K"
+	default:
+		return "°C"
+	}
+}
+
+// Symbol returns the unit's display symbol.
+func (u SpeedUnit) Symbol() string { return string(u) }
+
+// Symbol returns the unit's display symbol.
+func (u PressureUnit) Symbol() string { return string(u) }
+
+// Symbol returns the unit's display symbol.
+func (u PrecipitationUnit) Symbol() string { return string(u) }
+
+// Symbol returns the unit's display symbol.
+func (u DistanceUnit) Symbol() string { return string(u) }
+
+// Format renders v, already in u, e.g. "97°F" or "290 K".
+func (u TemperatureUnit) Format(v float64) string {
+	return format(v, 0, u.Symbol())
+}
+
+// Format renders v, already in u, e.g. "12 mph".
+func (u SpeedUnit) Format(v float64) string {
+	return format(v, 0, u.Symbol())
+}
+
+// Format renders v, already in u, e.g. "1013 hPa" or "29.92 inHg".
+func (u PressureUnit) Format(v float64) string {
+	decimals := 0
+	switch u {
+	case InchesOfMercury:
+		decimals = 2
+	case Kilopascals:
+		decimals = 1
+	}
+	return format(v, decimals, u.Symbol())
+}
+
+// Format renders v, already in u, e.g. "2.5 mm" or "0.10 in".
+func (u PrecipitationUnit) Format(v float64) string {
+	if u == Inches {
+		return format(v, 2, u.Symbol())
+	}
+	return format(v, 1, u.Symbol())
+}
+
+// Format renders v, already in u, e.g. "10 km".
+func (u DistanceUnit) Format(v float64) string {
+	return format(v, 0, u.Symbol())
+}
+
+// format joins a rounded value and symbol, leaving no space before a degree sign.
+func format(v float64, decimals int, symbol string) string {
+	s := strconv.FormatFloat(v, 'f', decimals, 64)
+	if s == "-0" {
+		s = "0"
+	}
+	if strings.HasPrefix(symbol, "°") {
+		return s + symbol
+	}
+	return s + " " + symbol
+}
+
+// ParseTemperatureUnit accepts a unit name or symbol such as "f" or "°C".
+func ParseTemperatureUnit(s string) (TemperatureUnit, error) {
+	switch strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "°") {
+	case "c", "celsius":
+		return Celsius, nil
+	case "f", "fahrenheit":
+		return Fahrenheit, nil
+	case "k", "kelvin":
+		return Kelvin, nil
+	}
+	return "", fmt.Errorf("unknown temperature unit %q (want celsius, fahrenheit or kelvin)", s)
+}
+
+// ParseSpeedUnit accepts a unit symbol such as "mph" or "m/s".
+func ParseSpeedUnit(s string) (SpeedUnit, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "km/h", "kmh", "kph":
+		return KilometersPerHour, nil
+	case "mph":
+		return MilesPerHour, nil
+	case "m/s", "ms":
+		return MetersPerSecond, nil
+	case "kn", "kt", "knots":
+		return Knots, nil
+	}
+	return "", fmt.Errorf("unknown speed unit %q (want km/h, mph, m/s or kn)", s)
+}
+
+// ParsePressureUnit accepts a unit symbol such as "hPa" or "inHg".
+func ParsePressureUnit(s string) (PressureUnit, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "hpa", "mb", "mbar":
+		return Hectopascals, nil
+	case "inhg":
+		return InchesOfMercury, nil
+	case "mmhg":
+		return MillimetersOfMercury, nil
+	case "kpa":
+		return Kilopascals, nil
+	}
+	return "", fmt.Errorf("unknown pressure unit %q (want hPa, inHg, mmHg or kPa)", s)
+}
+
+// ParsePrecipitationUnit accepts "mm" or "in".
+func ParsePrecipitationUnit(s string) (PrecipitationUnit, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "mm":
+		return Millimeters, nil
+	case "in", "inch", "inches":
+		return Inches, nil
+	}
+	return "", fmt.Errorf("unknown precipitation unit %q (want mm or in)", s)
+}
+
+// ParseDistanceUnit accepts "km" or "mi".
+func ParseDistanceUnit(s string) (DistanceUnit, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "km":
+		return Kilometers, nil
+	case "mi", "miles":
+		return Miles, nil
+	}
+	return "", fmt.Errorf("unknown distance unit %q (want km or mi)", s)
+}
@@ -0,0 +1,81 @@
+package units
+
+import (
+	"testing"
+
+	"weatherapp/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConversions checks each quantity converts out of its base unit.
+func TestConversions(t *testing.T) {
+	assert.InDelta(t, 212, Temperature(100).In(Fahrenheit), 1e-9)
+	assert.InDelta(t, 273.15, Temperature(0).In(Kelvin), 1e-9)
+	assert.InDelta(t, 10, Speed(36).In(MetersPerSecond), 1e-9)
+	assert.InDelta(t, 62.137, Speed(100).In(MilesPerHour), 1e-3)
+	assert.InDelta(t, 27, Speed(50).In(Knots), 1e-2)
+	assert.InDelta(t, 29.92, Pressure(1013.25).In(InchesOfMercury), 1e-2)
+	assert.InDelta(t, 760, Pressure(1013.25).In(MillimetersOfMercury), 1e-1)
+	assert.InDelta(t, 1, Precipitation(25.4).In(Inches), 1e-9)
+	assert.InDelta(t, 1, Distance(1.609344).In(Miles), 1e-9)
+}
+
+// TestSystemFormat checks symbols, precision and spacing per profile.
+func TestSystemFormat(t *testing.T) {
+	assert.Equal(t, "21°C", Metric.FormatTemperature(21.3))
+	assert.Equal(t, "70°F", Imperial.FormatTemperature(21.3))
+	assert.Equal(t, "294 K", SI.FormatTemperature(21.3))
+	assert.Equal(t, "0°C", Metric.FormatTemperature(-0.2))
+
+	assert.Equal(t, "20 km/h", Metric.FormatSpeed(20))
+	assert.Equal(t, "12 mph", UK.FormatSpeed(20))
+	assert.Equal(t, "6 m/s", SI.FormatSpeed(20))
+
+	assert.Equal(t, "1013 hPa", UK.FormatPressure(1013.25))
+	assert.Equal(t, "29.92 inHg", Imperial.FormatPressure(1013.25))
+	assert.Equal(t, "2.5 mm", Metric.FormatPrecipitation(2.5))
+	assert.Equal(t, "0.10 in", Imperial.FormatPrecipitation(2.5))
+	assert.Equal(t, "6 mi", UK.FormatDistance(10))
+}
+
+// TestResolve covers profiles, legacy unit names and overrides.
+func TestResolve(t *testing.T) {
+	s, err := Resolve("", nil)
+	require.NoError(t, err)
+	assert.Equal(t, Metric, s)
+
+	s, err = Resolve("Fahrenheit", nil)
+	require.NoError(t, err)
+	assert.Equal(t, Imperial, s)
+
+	s, err = FromPreferences(models.Preferences{Unit: "uk", UnitOverrides: map[string]string{"temp": "f", "wind": "kt"}})
+	require.NoError(t, err)
+	assert.Equal(t, System{Fahrenheit, Knots, Hectopascals, Millimeters, Miles}, s)
+
+	_, err = Resolve("rankine", nil)
+	assert.EqualError(t, err, `unknown unit profile "rankine" (want metric, imperial, uk or si)`)
+	_, err = Resolve("metric", map[string]string{"speed": "furlongs"})
+	assert.Error(t, err)
+	_, err = Resolve("metric", map[string]string{"humidity": "%"})
+	assert.Error(t, err)
+}
+
+// TestParsePreference checks the single-line preference syntax used at the prompt.
+func TestParsePreference(t *testing.T) {
+	p, o, err := ParsePreference(" Metric, speed=kn , pressure=inHg\n")
+	require.NoError(t, err)
+	assert.Equal(t, "metric", p)
+	assert.Equal(t, map[string]string{"speed": "kn", "pressure": "inHg"}, o)
+
+	p, o, err = ParsePreference("celsius")
+	require.NoError(t, err)
+	assert.Equal(t, "celsius", p)
+	assert.Nil(t, o)
+
+	for _, bad := range []string{"furlongs", "metric, speed", "metric, speed=warp"} {
+		_, _, err := ParsePreference(bad)
+		assert.Error(t, err, bad)
+	}
+}
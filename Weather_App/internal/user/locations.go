@@ -0,0 +1,182 @@
+package user
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"weatherapp/internal/geo"
+	"weatherapp/internal/i18n"
+	"weatherapp/internal/logging"
+	"weatherapp/internal/storage"
+	"weatherapp/internal/units"
+	"weatherapp/internal/weather"
+	"weatherapp/models"
+)
+
+// ManageLocations lets a User add, remove, reorder and view their saved locations via CLI
+func ManageLocations(reader *bufio.Reader, userID string) {
+	ctx := context.Background()
+	var u *models.User
+	users, err := storage.LoadUsers(ctx)
+	if err != nil {
+		i18n.Println("Error loading users: %v", err)
+		return
+	}
+	for i := range users {
+		if users[i].UserID == userID {
+			u = &users[i]
+		}
+	}
+	if u == nil {
+		i18n.Println("User not found")
+		return
+	}
+	p := &u.Preferences
+	if len(p.SavedLocations) == 0 && p.Location != "" {
+		p.SavedLocations = []models.SavedLocation{{Name: "Home", Location: p.Location, Coords: p.Coords, Default: true}}
+	}
+
+	for {
+		fmt.Println()
+		i18n.Println("=== Saved Locations ===")
+		if len(p.SavedLocations) == 0 {
+			i18n.Println("(none)")
+		}
+		for i, l := range p.SavedLocations {
+			mark := ""
+			if l.Default {
+				mark = " " + i18n.Sprintf("(default)")
+			}
+			fmt.Printf("%d. %s: %s%s\n", i+1, l.Name, l.Location, mark)
+		}
+		i18n.Printf("v = view all, a = add, r = remove, m = move, d = set default, b = back: ")
+		choice, _ := reader.ReadString('\n')
+
+		var err error
+		switch strings.TrimSpace(choice) {
+		case "v":
+			sys, err := units.FromPreferences(*p)
+			if err != nil {
+				i18n.Println("Error: %v", err)
+				continue
+			}
+			weather.ShowSavedLocations(logging.Start(context.Background(), "saved locations"), p.SavedLocations, sys, p.Language)
+			continue
+		case "a":
+			name := prompt(reader, "Name (e.g. Home, Office): ")
+			l := models.SavedLocation{Name: name, Location: geo.Suggest(reader, prompt(reader, "Location: "))}
+			if place, ok := geo.Resolve(reader, l.Location); ok {
+				l.Location, l.Coords = place.String(), place.Coordinates()
+			}
+			err = addLocation(p, l)
+		case "r":
+			err = removeLocation(p, promptIndex(reader, "Number to remove: "))
+		case "m":
+			from := promptIndex(reader, "Number to move: ")
+			err = moveLocation(p, from, promptIndex(reader, "New position: "))
+		case "d":
+			err = setDefaultLocation(p, promptIndex(reader, "Number to make default: "))
+		case "b", "":
+			return
+		default:
+			i18n.Println("Invalid choice")
+			continue
+		}
+		if err != nil {
+			i18n.Println("Error: %v", err)
+			continue
+		}
+		if err := storage.UpdateUser(ctx, *u); err != nil {
+			i18n.Println("Error saving locations: %v", err)
+		}
+	}
+}
+
+// addLocation appends a named location; the first one becomes the default.
+func addLocation(p *models.Preferences, l models.SavedLocation) error {
+	if l.Name == "" || l.Location == "" {
+		return fmt.Errorf("name and location are required")
+	}
+	for _, existing := range p.SavedLocations {
+		if strings.EqualFold(existing.Name, l.Name) {
+			return fmt.Errorf("a location named %q already exists", l.Name)
+		}
+	}
+	l.Default = false
+	p.SavedLocations = append(p.SavedLocations, l)
+	if len(p.SavedLocations) == 1 {
+		return setDefaultLocation(p, 0)
+	}
+	return nil
+}
+
+// removeLocation deletes entry i; removing the default promotes the first
+// remaining one, and removing the last clears Location and Coords.
+func removeLocation(p *models.Preferences, i int) error {
+	if i < 0 || i >= len(p.SavedLocations) {
+		return fmt.Errorf("no location number %d", i+1)
+	}
+	wasDefault := p.SavedLocations[i].Default
+	p.SavedLocations = append(p.SavedLocations[:i], p.SavedLocations[i+1:]...)
+	if len(p.SavedLocations) == 0 {
+		p.Location, p.Coords = "", nil
+		return nil
+	}
+	if wasDefault {
+		return setDefaultLocation(p, 0)
+	}
+	return nil
+}
+
+// moveLocation moves entry from to position to, shifting the others.
+func moveLocation(p *models.Preferences, from, to int) error {
+	n := len(p.SavedLocations)
+	if from < 0 || from >= n || to < 0 || to >= n {
+		return fmt.Errorf("positions must be between 1 and %d", n)
+	}
+	l := p.SavedLocations[from]
+	rest := append(p.SavedLocations[:from:from], p.SavedLocations[from+1:]...)
+	p.SavedLocations = append(rest[:to:to], append([]models.SavedLocation{l}, rest[to:]...)...)
+	return nil
+}
+
+// setDefaultLocation marks entry i as default and mirrors it into Preferences.Location and Coords.
+func setDefaultLocation(p *models.Preferences, i int) error {
+	if i < 0 || i >= len(p.SavedLocations) {
+		return fmt.Errorf("no location number %d", i+1)
+	}
+	for j := range p.SavedLocations {
+		p.SavedLocations[j].Default = j == i
+	}
+	p.Location = p.SavedLocations[i].Location
+	p.Coords = p.SavedLocations[i].Coords
+	return nil
+}
+
+// SetLocation sets Location and Coords and moves the default saved location
+// with them, keeping the two the same.
+func SetLocation(p *models.Preferences, loc string, coords *models.Coordinates) {
+	p.Location, p.Coords = loc, coords
+	for i := range p.SavedLocations {
+		if p.SavedLocations[i].Default {
+			p.SavedLocations[i].Location, p.SavedLocations[i].Coords = loc, coords
+		}
+	}
+}
+
+func prompt(reader *bufio.Reader, label string) string {
+	i18n.Printf(label)
+	s, _ := reader.ReadString('\n')
+	return strings.TrimSpace(s)
+}
+
+// promptIndex reads a 1-based number and returns it 0-based (-1 if invalid).
+func promptIndex(reader *bufio.Reader, label string) int {
+	n, err := strconv.Atoi(prompt(reader, label))
+	if err != nil {
+		return -1
+	}
+	return n - 1
+}
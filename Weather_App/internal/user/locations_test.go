@@ -0,0 +1,92 @@
+package user
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"testing"
+	"weatherapp/internal/storage"
+	"weatherapp/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func names(p models.Preferences) []string {
+	var out []string
+	for _, l := range p.SavedLocations {
+		out = append(out, l.Name)
+	}
+	return out
+}
+
+// TestLocationEditing covers add, reorder, default switching and removal.
+func TestLocationEditing(t *testing.T) {
+	var p models.Preferences
+	require.NoError(t, addLocation(&p, models.SavedLocation{Name: "Home", Location: "Pune"}))
+	require.NoError(t, addLocation(&p, models.SavedLocation{Name: "Office", Location: "Mumbai"}))
+	require.NoError(t, addLocation(&p, models.SavedLocation{Name: "Cabin", Location: "Manali", Coords: &models.Coordinates{Lat: 32.24, Lon: 77.19}}))
+	assert.Error(t, addLocation(&p, models.SavedLocation{Name: "home", Location: "Nashik"}))
+	assert.Equal(t, "Pune", p.Location)
+
+	require.NoError(t, moveLocation(&p, 2, 0))
+	assert.Equal(t, []string{"Cabin", "Home", "Office"}, names(p))
+	assert.Error(t, moveLocation(&p, 0, 3))
+
+	require.NoError(t, setDefaultLocation(&p, 2))
+	assert.Equal(t, "Mumbai", p.Location)
+	assert.False(t, p.SavedLocations[1].Default)
+
+	require.NoError(t, removeLocation(&p, 2))
+	assert.Equal(t, []string{"Cabin", "Home"}, names(p))
+	assert.True(t, p.SavedLocations[0].Default)
+	assert.Equal(t, "Manali", p.Location)
+	assert.Equal(t, &models.Coordinates{Lat: 32.24, Lon: 77.19}, p.Coords)
+
+	require.NoError(t, removeLocation(&p, 1))
+	require.NoError(t, removeLocation(&p, 0))
+	assert.Empty(t, p.Location)
+	assert.Nil(t, p.Coords)
+}
+
+// TestApply_DefaultLocation checks a new location moves the default saved
+// location with it, without changing the preferences it was copied from.
+func TestApply_DefaultLocation(t *testing.T) {
+	var p models.Preferences
+	require.NoError(t, addLocation(&p, models.SavedLocation{Name: "Home", Location: "Pune", Coords: &models.Coordinates{Lat: 18.52, Lon: 73.86}}))
+	require.NoError(t, addLocation(&p, models.SavedLocation{Name: "Office", Location: "Mumbai"}))
+
+	next := p
+	loc := "Nashik"
+	require.NoError(t, PreferenceChanges{Location: &loc}.Apply(&next))
+	assert.Equal(t, "Nashik", next.Location)
+	assert.Nil(t, next.Coords)
+	assert.Equal(t, models.SavedLocation{Name: "Home", Location: "Nashik", Default: true}, next.SavedLocations[0])
+	assert.Equal(t, "Mumbai", next.SavedLocations[1].Location)
+	assert.Equal(t, "Pune", p.SavedLocations[0].Location)
+}
+
+// TestManageLocations checks the menu seeds Home from the existing location and persists additions.
+func TestManageLocations(t *testing.T) {
+	originalLoadUsers := storage.LoadUsers
+	originalUpdateUser := storage.UpdateUser
+	defer func() {
+		storage.LoadUsers = originalLoadUsers
+		storage.UpdateUser = originalUpdateUser
+	}()
+
+	storage.LoadUsers = func(context.Context) ([]models.User, error) {
+		return []models.User{{UserID: "u1", Preferences: models.Preferences{Location: "Pune"}}}, nil
+	}
+	var updatedUser models.User
+	storage.UpdateUser = func(_ context.Context, user models.User) error {
+		updatedUser = user
+		return nil
+	}
+
+	input := "a\nOffice\nMumbai\nd\n2\nb\n"
+	ManageLocations(bufio.NewReader(bytes.NewBufferString(input)), "u1")
+
+	assert.Equal(t, []string{"Home", "Office"}, names(updatedUser.Preferences))
+	assert.Equal(t, "Mumbai", updatedUser.Preferences.Location)
+}
@@ -0,0 +1,80 @@
+package user
+
+import (
+	"bufio"
+	"context"
+	"strings"
+	"time"
+	"weatherapp/internal/i18n"
+	"weatherapp/internal/notify"
+	"weatherapp/internal/storage"
+)
+
+// ChangeNotifications prompts for and updates where a User's alerts are delivered
+func ChangeNotifications(reader *bufio.Reader, userID string) {
+	ctx := context.Background()
+	users, err := storage.LoadUsers(ctx)
+	if err != nil {
+		i18n.Println("Error loading users: %v", err)
+		return
+	}
+	for _, u := range users {
+		if u.UserID == userID {
+			n := &u.Notifications
+			i18n.Println("Leave a field blank to disable that channel.")
+
+			i18n.Printf("Email address: ")
+			n.Email, _ = reader.ReadString('\n')
+			n.Email = strings.TrimSpace(n.Email)
+
+			i18n.Printf("Webhook URL (Slack-compatible): ")
+			n.WebhookURL, _ = reader.ReadString('\n')
+			n.WebhookURL = strings.TrimSpace(n.WebhookURL)
+			if n.WebhookURL != "" {
+				if err := notify.CheckWebhookURL(n.WebhookURL); err != nil {
+					i18n.Println("%v; webhook disabled", err)
+					n.WebhookURL = ""
+				}
+			}
+
+			i18n.Printf("Append to file: ")
+			n.File, _ = reader.ReadString('\n')
+			n.File = strings.TrimSpace(n.File)
+			if n.File != "" {
+				if err := notify.CheckFileName(n.File); err != nil {
+					i18n.Println("%v; file disabled", err)
+					n.File = ""
+				}
+			}
+
+			i18n.Printf("Also print to stdout (y/n): ")
+			answer, _ := reader.ReadString('\n')
+			n.Stdout = strings.EqualFold(strings.TrimSpace(answer), "y")
+
+			i18n.Printf("Daily digest time (HH:MM, blank to disable): ")
+			n.DigestTime, _ = reader.ReadString('\n')
+			n.DigestTime = strings.TrimSpace(n.DigestTime)
+			if n.DigestTime != "" {
+				if _, err := time.Parse("15:04", n.DigestTime); err != nil {
+					i18n.Println("Invalid time; digest disabled")
+					n.DigestTime = ""
+				}
+			}
+
+			i18n.Printf("Time zone (e.g. Asia/Kolkata, blank for UTC): ")
+			n.TimeZone, _ = reader.ReadString('\n')
+			n.TimeZone = strings.TrimSpace(n.TimeZone)
+			if _, err := time.LoadLocation(n.TimeZone); err != nil {
+				i18n.Println("Unknown time zone; using UTC")
+				n.TimeZone = ""
+			}
+
+			if err := storage.UpdateUser(ctx, u); err != nil {
+				i18n.Println("Error saving notification settings: %v", err)
+				return
+			}
+			i18n.Println("Notification settings updated")
+			return
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package user
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"weatherapp/internal/i18n"
+	"weatherapp/internal/rules"
+	"weatherapp/internal/storage"
+	"weatherapp/internal/units"
+	"weatherapp/models"
+)
+
+// ManageRules lets a User list, add and remove their alert rules via CLI
+func ManageRules(reader *bufio.Reader, userID string) {
+	ctx := context.Background()
+	var u *models.User
+	users, err := storage.LoadUsers(ctx)
+	if err != nil {
+		i18n.Println("Error loading users: %v", err)
+		return
+	}
+	for i := range users {
+		if users[i].UserID == userID {
+			u = &users[i]
+		}
+	}
+	if u == nil {
+		i18n.Println("User not found")
+		return
+	}
+	for {
+		fmt.Println()
+		i18n.Println("=== Alert Rules ===")
+		if len(u.Rules) == 0 {
+			i18n.Println("(no rules)")
+		}
+		for i, r := range u.Rules {
+			fmt.Printf("%d. %s\n", i+1, rules.Describe(r))
+		}
+		i18n.Printf("a = add, d = delete, b = back: ")
+		choice, _ := reader.ReadString('\n')
+		switch strings.TrimSpace(choice) {
+		case "a":
+			i18n.Printf("Rule (e.g. \"temp_max > 35 tomorrow\" or \"condition = thunder\"; metrics: %s): ", strings.Join(rules.Metrics, ", "))
+			line, _ := reader.ReadString('\n')
+			r, err := rules.Parse(line)
+			if err != nil {
+				i18n.Println("Invalid rule: %v", err)
+				continue
+			}
+			sys, err := units.FromPreferences(u.Preferences)
+			if err != nil {
+				i18n.Println("Error: %v", err)
+				continue
+			}
+			u.Rules = append(u.Rules, rules.InUnits(r, sys))
+		case "d":
+			i18n.Printf("Rule number to delete: ")
+			line, _ := reader.ReadString('\n')
+			n, err := strconv.Atoi(strings.TrimSpace(line))
+			if err != nil || n < 1 || n > len(u.Rules) {
+				i18n.Println("Invalid rule number")
+				continue
+			}
+			u.Rules = append(u.Rules[:n-1], u.Rules[n:]...)
+		case "b", "":
+			return
+		default:
+			i18n.Println("Invalid choice")
+			continue
+		}
+		if err := storage.UpdateUser(ctx, *u); err != nil {
+			i18n.Println("Error saving rules: %v", err)
+		}
+	}
+}
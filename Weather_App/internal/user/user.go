@@ -1,64 +1,249 @@
-package user
-
-import (
-	"bufio"
-	"fmt"
-	"strings"
-	"weatherapp/internal/storage"
-	"weatherapp/models"
-)
-
-// ChangePreferences prompts for and updates a User’s Preferences
-func ChangePreferences(reader *bufio.Reader, userID string) {
-	users := storage.LoadUsers()
-	for _, u := range users {
-		if u.UserID == userID {
-			promptPreferences(reader, &u)
-			storage.UpdateUser(u)
-			fmt.Println("Preferences updated")
-			return
-		}
-	}
-}
-
-// EnsurePreferences checks if a User has Preferences and prompts if they are empty
-func EnsurePreferences(reader *bufio.Reader, userID string) {
-	users := storage.LoadUsers()
-	for _, u := range users {
-		if u.UserID == userID {
-			if u.Preferences.Location == "" {
-				fmt.Println("\nPlease set your weather preferences:")
-				promptPreferences(reader, &u)
-				storage.UpdateUser(u)
-			}
-			return
-		}
-	}
-}
-
-// promptPreferences prompts the user to input and set weather preferences via CLI.
-func promptPreferences(reader *bufio.Reader, u *models.User) {
-	fmt.Print("Enter your location: ")
-	loc, _ := reader.ReadString('\n')
-	u.Preferences.Location = strings.TrimSpace(loc)
-
-	fmt.Print("Unit (celsius/fahrenheit): ")
-	u.Preferences.Unit, _ = reader.ReadString('\n')
-	u.Preferences.Unit = strings.TrimSpace(u.Preferences.Unit)
-
-	fmt.Print("Verbosity (brief/verbose): ")
-	u.Preferences.Verbosity, _ = reader.ReadString('\n')
-	u.Preferences.Verbosity = strings.TrimSpace(u.Preferences.Verbosity)
-
-	fmt.Print("Forecast (day/week/month): ")
-	u.Preferences.Forecast, _ = reader.ReadString('\n')
-	u.Preferences.Forecast = strings.TrimSpace(u.Preferences.Forecast)
-}
-
-// ListUsers prints all registered users to stdout
-func ListUsers() {
-	users := storage.LoadUsers()
-	for _, u := range users {
-		fmt.Printf("UserID: %s, Name: %s\n", u.UserID, u.Name)
-	}
-}
+package user
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"weatherapp/internal/geo"
+	"weatherapp/internal/i18n"
+	"weatherapp/internal/storage"
+	"weatherapp/internal/units"
+	"weatherapp/internal/weather"
+	"weatherapp/models"
+)
+
+// ChangePreferences prompts for and updates a User’s Preferences
+func ChangePreferences(reader *bufio.Reader, userID string) {
+	ctx := context.Background()
+	users, err := storage.LoadUsers(ctx)
+	if err != nil {
+		i18n.Println("Error loading users: %v", err)
+		return
+	}
+	for _, u := range users {
+		if u.UserID == userID {
+			promptPreferences(reader, &u)
+			if err := storage.UpdateUser(ctx, u); err != nil {
+				i18n.Println("Error saving preferences: %v", err)
+				return
+			}
+			i18n.Println("Preferences updated")
+			return
+		}
+	}
+}
+
+// EnsurePreferences checks if a User has Preferences and prompts if they are empty
+func EnsurePreferences(reader *bufio.Reader, userID string) {
+	ctx := context.Background()
+	users, err := storage.LoadUsers(ctx)
+	if err != nil {
+		i18n.Println("Error loading users: %v", err)
+		return
+	}
+	for _, u := range users {
+		if u.UserID == userID {
+			if u.Preferences.Location == "" {
+				fmt.Println()
+				i18n.Println("Please set your weather preferences:")
+				promptPreferences(reader, &u)
+				if err := storage.UpdateUser(ctx, u); err != nil {
+					i18n.Println("Error saving preferences: %v", err)
+				}
+			}
+			return
+		}
+	}
+}
+
+// promptPreferences prompts the user to input and set weather preferences via CLI.
+func promptPreferences(reader *bufio.Reader, u *models.User) {
+	i18n.Printf("Enter your location (city, \"lat,lon\", postal code with country, or auto): ")
+	line, _ := reader.ReadString('\n')
+	loc := geo.Suggest(reader, strings.TrimSpace(line))
+	var coords *models.Coordinates
+	if place, ok := geo.Resolve(reader, loc); ok {
+		loc, coords = place.String(), place.Coordinates()
+	}
+	SetLocation(&u.Preferences, loc, coords)
+
+	for {
+		i18n.Printf("Units (metric/imperial/uk/si, optionally followed by overrides like \", speed=kn\"): ")
+		line, err := reader.ReadString('\n')
+		profile, overrides, perr := units.ParsePreference(line)
+		if perr == nil {
+			u.Preferences.Unit, u.Preferences.UnitOverrides = profile, overrides
+			break
+		}
+		i18n.Println("Error: %v", perr)
+		if err != nil {
+			break
+		}
+	}
+
+	i18n.Printf("Verbosity (brief/verbose): ")
+	u.Preferences.Verbosity, _ = reader.ReadString('\n')
+	u.Preferences.Verbosity = strings.TrimSpace(u.Preferences.Verbosity)
+
+	i18n.Printf("Forecast (day/week/month): ")
+	u.Preferences.Forecast, _ = reader.ReadString('\n')
+	u.Preferences.Forecast = strings.TrimSpace(u.Preferences.Forecast)
+
+	for {
+		i18n.Printf("Output format (text/chart/json/yaml/csv) [text]: ")
+		line, err := reader.ReadString('\n')
+		format := strings.ToLower(strings.TrimSpace(line))
+		_, rerr := weather.NewRenderer(format)
+		if rerr == nil {
+			u.Preferences.Output = format
+			break
+		}
+		i18n.Println("Error: %v", rerr)
+		if err != nil {
+			break
+		}
+	}
+
+	for {
+		i18n.Printf("Language (%s, blank to follow the system): ", strings.Join(i18n.Languages, "/"))
+		line, err := reader.ReadString('\n')
+		lang, lerr := i18n.Parse(line)
+		if lerr == nil {
+			u.Preferences.Language = lang
+			break
+		}
+		i18n.Println("Error: %v", lerr)
+		if err != nil {
+			break
+		}
+	}
+}
+
+// ListUsers prints all registered users to stdout
+func ListUsers() {
+	ctx := context.Background()
+	users, err := storage.LoadUsers(ctx)
+	if err != nil {
+		i18n.Println("Error loading users: %v", err)
+		return
+	}
+	for _, u := range users {
+		i18n.Println("UserID: %s, Name: %s", u.UserID, u.Name)
+	}
+}
+
+// PreferenceChanges is a partial update to Preferences; nil fields are left
+// unchanged. Unit uses the "profile, quantity=unit" syntax of the prompt.
+// Coords pins a new Location to a place and is ignored without one.
+type PreferenceChanges struct {
+	Location  *string
+	Coords    *models.Coordinates
+	Unit      *string
+	Verbosity *string
+	Forecast  *string
+	Output    *string
+	Language  *string
+}
+
+// Empty reports whether c changes nothing.
+func (c PreferenceChanges) Empty() bool {
+	return c.Location == nil && c.Unit == nil && c.Verbosity == nil && c.Forecast == nil && c.Output == nil && c.Language == nil
+}
+
+// Apply validates every change and, only if all are valid, applies them to
+// p. A new Location replaces the resolved Coords with c.Coords and moves the
+// default saved location with it. p's SavedLocations are copied, not changed in place.
+func (c PreferenceChanges) Apply(p *models.Preferences) error {
+	next := *p
+	next.SavedLocations = slices.Clone(p.SavedLocations)
+	if c.Location != nil {
+		loc := strings.TrimSpace(*c.Location)
+		if loc == "" {
+			return fmt.Errorf("location must not be empty")
+		}
+		if c.Coords != nil && !validCoords(*c.Coords) {
+			return fmt.Errorf("latitude must be within ±90 and longitude within ±180")
+		}
+		SetLocation(&next, loc, c.Coords)
+	}
+	if c.Unit != nil {
+		profile, overrides, err := units.ParsePreference(*c.Unit)
+		if err != nil {
+			return err
+		}
+		next.Unit, next.UnitOverrides = profile, overrides
+	}
+	if c.Verbosity != nil {
+		v := strings.ToLower(strings.TrimSpace(*c.Verbosity))
+		if v != "brief" && v != "verbose" {
+			return fmt.Errorf("verbosity must be brief or verbose")
+		}
+		next.Verbosity = v
+	}
+	if c.Forecast != nil {
+		f := strings.ToLower(strings.TrimSpace(*c.Forecast))
+		if f != "day" && f != "week" && f != "month" {
+			return fmt.Errorf("forecast must be day, week or month")
+		}
+		next.Forecast = f
+	}
+	if c.Output != nil {
+		o := strings.ToLower(strings.TrimSpace(*c.Output))
+		if _, err := weather.NewRenderer(o); err != nil {
+			return err
+		}
+		next.Output = o
+	}
+	if c.Language != nil {
+		lang, err := i18n.Parse(*c.Language)
+		if err != nil {
+			return err
+		}
+		next.Language = lang
+	}
+	*p = next
+	return nil
+}
+
+func validCoords(c models.Coordinates) bool {
+	return c.Lat >= -90 && c.Lat <= 90 && c.Lon >= -180 && c.Lon <= 180
+}
+
+// AmbiguousLocationError is returned by ResolveLocation when a location
+// matches several places; Places lists them so the caller can pick one by
+// its coordinates.
+type AmbiguousLocationError struct {
+	Location string
+	Places   []geo.Place
+}
+
+func (e *AmbiguousLocationError) Error() string {
+	choices := make([]string, len(e.Places))
+	for i, p := range e.Places {
+		choices[i] = fmt.Sprintf("%s (%s)", p, geo.FormatCoords(p.Lat, p.Lon))
+	}
+	return fmt.Sprintf("location %q matches several places: %s", e.Location, strings.Join(choices, "; "))
+}
+
+// ResolveLocation pins a new Location without Coords to a place, as the web
+// form does: a place the geocoder is sure of is stored under its full name
+// with its coordinates, and a name matching several places fails with an
+// *AmbiguousLocationError. Coordinates, postal codes, "auto" and names that
+// do not resolve are kept as typed.
+func (c *PreferenceChanges) ResolveLocation(ctx context.Context) error {
+	if c.Location == nil || c.Coords != nil {
+		return nil
+	}
+	loc := strings.TrimSpace(*c.Location)
+	places, exact := geo.Candidates(ctx, loc)
+	switch {
+	case exact:
+		name := places[0].String()
+		c.Location, c.Coords = &name, places[0].Coordinates()
+	case len(places) > 0:
+		return &AmbiguousLocationError{Location: loc, Places: places}
+	}
+	return nil
+}
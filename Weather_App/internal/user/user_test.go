@@ -1,101 +1,176 @@
-package user
-
-import (
-	"bufio"
-	"bytes"
-	"testing"
-	"weatherapp/internal/storage"
-	"weatherapp/models"
-
-	"github.com/stretchr/testify/assert"
-)
-
-
-// TestChangePreferences checks if user preferences are correctly updated via CLI input.
-func TestChangePreferences(t *testing.T) {
-	originalLoadUsers := storage.LoadUsers
-	originalUpdateUser := storage.UpdateUser
-	defer func() {
-		storage.LoadUsers = originalLoadUsers
-		storage.UpdateUser = originalUpdateUser
-	}()
-
-	sampleUser := models.User{UserID: "u1", Preferences: models.Preferences{}}
-	storage.LoadUsers = func() []models.User {
-		return []models.User{sampleUser}
-	}
-
-	var updatedUser models.User
-	storage.UpdateUser = func(user models.User) error {
-		updatedUser = user
-		return nil
-	}
-
-	input := "Mumbai\ncelsius\nverbose\nweek\n"
-	reader := bufio.NewReader(bytes.NewBufferString(input))
-	ChangePreferences(reader, "u1")
-
-	assert.Equal(t, "Mumbai", updatedUser.Preferences.Location)
-	assert.Equal(t, "celsius", updatedUser.Preferences.Unit)
-	assert.Equal(t, "verbose", updatedUser.Preferences.Verbosity)
-	assert.Equal(t, "week", updatedUser.Preferences.Forecast)
-}
-
-// TestEnsurePreferences_PrefSet verifies EnsurePreferences does nothing if preferences already exist.
-func TestEnsurePreferences_PrefSet(t *testing.T) {
-	originalLoadUsers := storage.LoadUsers
-	originalUpdateUser := storage.UpdateUser
-	defer func() {
-		storage.LoadUsers = originalLoadUsers
-		storage.UpdateUser = originalUpdateUser
-	}()
-
-	existing := models.User{
-		UserID: "u1",
-		Preferences: models.Preferences{
-			Location: "SetAlready",
-		},
-	}
-
-	storage.LoadUsers = func() []models.User {
-		return []models.User{existing}
-	}
-
-	storage.UpdateUser = func(user models.User) error {
-		t.FailNow()
-		return nil
-	}
-
-	reader := bufio.NewReader(bytes.NewBuffer(nil))
-	EnsurePreferences(reader, "u1")
-}
-
-// TestEnsurePreferences_PrefNotSet checks if EnsurePreferences updates preferences when not already set.
-func TestEnsurePreferences_PrefNotSet(t *testing.T) {
-	originalLoadUsers := storage.LoadUsers
-	originalUpdateUser := storage.UpdateUser
-	defer func() {
-		storage.LoadUsers = originalLoadUsers
-		storage.UpdateUser = originalUpdateUser
-	}()
-
-	sampleUser := models.User{UserID: "u1", Preferences: models.Preferences{}}
-	storage.LoadUsers = func() []models.User {
-		return []models.User{sampleUser}
-	}
-
-	var updatedUser models.User
-	storage.UpdateUser = func(user models.User) error {
-		updatedUser = user
-		return nil
-	}
-
-	input := "Delhi\nfahrenheit\nbrief\nday\n"
-	reader := bufio.NewReader(bytes.NewBufferString(input))
-	EnsurePreferences(reader, "u1")
-
-	assert.Equal(t, "Delhi", updatedUser.Preferences.Location)
-	assert.Equal(t, "fahrenheit", updatedUser.Preferences.Unit)
-	assert.Equal(t, "brief", updatedUser.Preferences.Verbosity)
-	assert.Equal(t, "day", updatedUser.Preferences.Forecast)
-}
\ No newline at end of file
+package user
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"testing"
+	"weatherapp/internal/geo"
+	"weatherapp/internal/storage"
+	"weatherapp/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+
+// TestChangePreferences checks if user preferences are correctly updated via CLI input.
+func TestChangePreferences(t *testing.T) {
+	originalLoadUsers := storage.LoadUsers
+	originalUpdateUser := storage.UpdateUser
+	defer func() {
+		storage.LoadUsers = originalLoadUsers
+		storage.UpdateUser = originalUpdateUser
+	}()
+
+	sampleUser := models.User{UserID: "u1", Preferences: models.Preferences{}}
+	storage.LoadUsers = func(context.Context) ([]models.User, error) {
+		return []models.User{sampleUser}, nil
+	}
+
+	var updatedUser models.User
+	storage.UpdateUser = func(_ context.Context, user models.User) error {
+		updatedUser = user
+		return nil
+	}
+
+	input := "Mumbai\ncelsius\nverbose\nweek\ntext\nklingon\nfr\n"
+	reader := bufio.NewReader(bytes.NewBufferString(input))
+	ChangePreferences(reader, "u1")
+
+	assert.Equal(t, "Mumbai", updatedUser.Preferences.Location)
+	assert.Equal(t, "celsius", updatedUser.Preferences.Unit)
+	assert.Equal(t, "verbose", updatedUser.Preferences.Verbosity)
+	assert.Equal(t, "week", updatedUser.Preferences.Forecast)
+	assert.Equal(t, "fr", updatedUser.Preferences.Language)
+}
+
+// TestEnsurePreferences_PrefSet verifies EnsurePreferences does nothing if preferences already exist.
+func TestEnsurePreferences_PrefSet(t *testing.T) {
+	originalLoadUsers := storage.LoadUsers
+	originalUpdateUser := storage.UpdateUser
+	defer func() {
+		storage.LoadUsers = originalLoadUsers
+		storage.UpdateUser = originalUpdateUser
+	}()
+
+	existing := models.User{
+		UserID: "u1",
+		Preferences: models.Preferences{
+			Location: "SetAlready",
+		},
+	}
+
+	storage.LoadUsers = func(context.Context) ([]models.User, error) {
+		return []models.User{existing}, nil
+	}
+
+	storage.UpdateUser = func(_ context.Context, user models.User) error {
+		t.FailNow()
+		return nil
+	}
+
+	reader := bufio.NewReader(bytes.NewBuffer(nil))
+	EnsurePreferences(reader, "u1")
+}
+
+// TestEnsurePreferences_PrefNotSet checks if EnsurePreferences updates preferences when not already set.
+func TestEnsurePreferences_PrefNotSet(t *testing.T) {
+	originalLoadUsers := storage.LoadUsers
+	originalUpdateUser := storage.UpdateUser
+	defer func() {
+		storage.LoadUsers = originalLoadUsers
+		storage.UpdateUser = originalUpdateUser
+	}()
+
+	sampleUser := models.User{UserID: "u1", Preferences: models.Preferences{}}
+	storage.LoadUsers = func(context.Context) ([]models.User, error) {
+		return []models.User{sampleUser}, nil
+	}
+
+	var updatedUser models.User
+	storage.UpdateUser = func(_ context.Context, user models.User) error {
+		updatedUser = user
+		return nil
+	}
+
+	input := "Delhi\nfahrenheit\nbrief\nday\n"
+	reader := bufio.NewReader(bytes.NewBufferString(input))
+	EnsurePreferences(reader, "u1")
+
+	assert.Equal(t, "Delhi", updatedUser.Preferences.Location)
+	assert.Equal(t, "fahrenheit", updatedUser.Preferences.Unit)
+	assert.Equal(t, "brief", updatedUser.Preferences.Verbosity)
+	assert.Equal(t, "day", updatedUser.Preferences.Forecast)
+}
+
+// stubGeocoder returns fixed candidates for any query.
+type stubGeocoder struct{ places []geo.Place }
+
+func (s *stubGeocoder) Geocode(ctx context.Context, query string) ([]geo.Place, error) {
+	return s.places, nil
+}
+
+// TestChangePreferences_Disambiguates checks an ambiguous location is resolved
+// to the chosen place and stored with its coordinates.
+func TestChangePreferences_Disambiguates(t *testing.T) {
+	originalLoadUsers := storage.LoadUsers
+	originalUpdateUser := storage.UpdateUser
+	defer func() {
+		storage.LoadUsers = originalLoadUsers
+		storage.UpdateUser = originalUpdateUser
+		geo.InitGeocoder(nil)
+	}()
+
+	storage.LoadUsers = func(context.Context) ([]models.User, error) {
+		return []models.User{{UserID: "u1"}}, nil
+	}
+	var updatedUser models.User
+	storage.UpdateUser = func(_ context.Context, user models.User) error {
+		updatedUser = user
+		return nil
+	}
+	geo.InitGeocoder(&stubGeocoder{places: []geo.Place{
+		{Name: "Paris", Region: "Ile-de-France", Country: "France", Lat: 48.86, Lon: 2.35},
+		{Name: "Paris", Region: "Texas", Country: "United States", Lat: 33.66, Lon: -95.56},
+	}})
+
+	input := "Paris\n2\ncelsius\nbrief\nday\n"
+	ChangePreferences(bufio.NewReader(bytes.NewBufferString(input)), "u1")
+
+	assert.Equal(t, "Paris, Texas, United States", updatedUser.Preferences.Location)
+	assert.Equal(t, &models.Coordinates{Lat: 33.66, Lon: -95.56}, updatedUser.Preferences.Coords)
+	assert.Equal(t, "celsius", updatedUser.Preferences.Unit)
+}
+
+// TestPreferenceChanges_ResolveLocation checks a new location is pinned to
+// the place it resolves to and an ambiguous one lists the candidates.
+func TestPreferenceChanges_ResolveLocation(t *testing.T) {
+	defer geo.InitGeocoder(nil)
+	texas := geo.Place{Name: "Paris", Region: "Texas", Country: "United States", Lat: 33.66, Lon: -95.56}
+	loc := "Paris"
+
+	geo.InitGeocoder(&stubGeocoder{places: []geo.Place{texas}})
+	c := PreferenceChanges{Location: &loc}
+	assert.NoError(t, c.ResolveLocation(context.Background()))
+	var p models.Preferences
+	assert.NoError(t, c.Apply(&p))
+	assert.Equal(t, "Paris, Texas, United States", p.Location)
+	assert.Equal(t, &models.Coordinates{Lat: 33.66, Lon: -95.56}, p.Coords)
+
+	geo.InitGeocoder(&stubGeocoder{places: []geo.Place{
+		{Name: "Paris", Region: "Ile-de-France", Country: "France", Lat: 48.86, Lon: 2.35}, texas,
+	}})
+	c = PreferenceChanges{Location: &loc}
+	err := c.ResolveLocation(context.Background())
+	var ambiguous *AmbiguousLocationError
+	assert.ErrorAs(t, err, &ambiguous)
+	assert.Len(t, ambiguous.Places, 2)
+	assert.Contains(t, err.Error(), "Paris, Texas, United States (33.6600,-95.5600)")
+
+	c = PreferenceChanges{Location: &loc, Coords: &models.Coordinates{Lat: 48.86, Lon: 2.35}}
+	assert.NoError(t, c.ResolveLocation(context.Background()))
+	assert.Equal(t, "Paris", *c.Location)
+
+	c.Coords = &models.Coordinates{Lat: 91}
+	assert.Error(t, c.Apply(&p))
+}
@@ -1,155 +1,375 @@
-package weather
-
-import (
-	"encoding/json"
-	"fmt"
-	"net/http"
-	"os"
-)
-
-// AccuWeatherProvider implements WeatherProvider using AccuWeather APIs
-type AccuWeatherProvider struct {
-	apiKey string
-}
-
-// NewAccuWeatherProvider reads ACCUWEATHER_API_KEY from the environment
-func NewAccuWeatherProvider() *AccuWeatherProvider {
-	return &AccuWeatherProvider{apiKey: os.Getenv("ACCUWEATHER_API_KEY")}
-}
-
-// lookupLocationKey finds the AccuWeather location key for a city
-func (a *AccuWeatherProvider) lookupLocationKey(location string) (string, error) {
-	url := fmt.Sprintf(
-		"http://dataservice.accuweather.com/locations/v1/cities/search?apikey=%s&q=%s",
-		a.apiKey, location,
-	)
-	resp, err := http.Get(url)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	var locs []struct{ Key string }
-	if err := json.NewDecoder(resp.Body).Decode(&locs); err != nil {
-		return "", err
-	}
-	if len(locs) == 0 {
-		return "", fmt.Errorf("location not found: %s", location)
-	}
-	return locs[0].Key, nil
-}
-
-// Current fetches the current conditions for a location.
-func (a *AccuWeatherProvider) Current(location string) (*WeatherData, error) {
-	key, err := a.lookupLocationKey(location)
-	if err != nil {
-		return nil, err
-	}
-	condURL := fmt.Sprintf(
-		"http://dataservice.accuweather.com/currentconditions/v1/%s?apikey=%s&details=true",
-		key, a.apiKey,
-	)
-	resp, err := http.Get(condURL)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var cs []struct {
-		WeatherText string `json:"WeatherText"`
-		Temperature struct {
-			Metric struct{ Value float64 } `json:"Metric"`
-		} `json:"Temperature"`
-		RealFeelTemperature struct {
-			Minimum struct{ Value float64 } `json:"Minimum"`
-			Maximum struct{ Value float64 } `json:"Maximum"`
-		} `json:"RealFeelTemperature"`
-		RelativeHumidity float64 `json:"RelativeHumidity"`
-		Wind             struct {
-			Speed     struct{ Value float64 }    `json:"Speed"`
-			Direction struct{ Localized string } `json:"Direction"`
-		} `json:"Wind"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&cs); err != nil {
-		return nil, err
-	}
-	if len(cs) == 0 {
-		return nil, fmt.Errorf("no current conditions for %s", location)
-	}
-	c := cs[0]
-	return &WeatherData{
-		Description: c.WeatherText,
-		Temperature: c.Temperature.Metric.Value,
-		FeelsLike:   c.RealFeelTemperature.Maximum.Value,
-		Humidity:    c.RelativeHumidity,
-		WindSpeed:   c.Wind.Speed.Value,
-		WindDir:     c.Wind.Direction.Localized,
-	}, nil
-}
-
-// Forecast retrieves up to 5-day forecasts, padded to the requested days.
-func (a *AccuWeatherProvider) Forecast(location string, days int) ([]WeatherData, error) {
-	key, err := a.lookupLocationKey(location)
-	if err != nil {
-		return nil, err
-	}
-
-	// AccuWeather supports daily forecasts up to 5 days
-	requestDays := days
-	if requestDays > 5 {
-		requestDays = 5
-	}
-	url := fmt.Sprintf(
-		"http://dataservice.accuweather.com/forecasts/v1/daily/%dday/%s?apikey=%s&metric=true&details=true",
-		requestDays, key, a.apiKey,
-	)
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var r struct {
-		DailyForecasts []struct {
-			Temperature struct {
-				Minimum struct{ Value float64 } `json:"Minimum"`
-				Maximum struct{ Value float64 } `json:"Maximum"`
-			} `json:"Temperature"`
-			RealFeelTemperature struct {
-				Minimum struct{ Value float64 } `json:"Minimum"`
-				Maximum struct{ Value float64 } `json:"Maximum"`
-			} `json:"RealFeelTemperature"`
-			Day struct {
-				IconPhrase               string  `json:"IconPhrase"`
-				PrecipitationProbability float64 `json:"PrecipitationProbability"`
-				Wind                     struct {
-					Speed     struct{ Value float64 }    `json:"Speed"`
-					Direction struct{ Localized string } `json:"Direction"`
-				} `json:"Wind"`
-			} `json:"Day"`
-		} `json:"DailyForecasts"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
-		return nil, err
-	}
-
-	var out []WeatherData
-	for i, fc := range r.DailyForecasts {
-		if i >= requestDays {
-			break
-		}
-		out = append(out, WeatherData{
-			Description: fc.Day.IconPhrase,
-			Temperature: fc.Temperature.Maximum.Value,
-			FeelsLike:   fc.RealFeelTemperature.Maximum.Value,
-			Humidity:    fc.Day.PrecipitationProbability,
-			WindSpeed:   fc.Day.Wind.Speed.Value,
-			WindDir:     fc.Day.Wind.Direction.Localized,
-		})
-	}
-	// pad for days beyond those returned
-	for i := requestDays; i < days; i++ {
-		out = append(out, WeatherData{Description: "Forecast unavailable"})
-	}
-	return out, nil
-}
+package weather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"weatherapp/internal/config"
+	"weatherapp/internal/geo"
+	"weatherapp/internal/units"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// AccuWeatherProvider implements WeatherProvider using AccuWeather APIs
+type AccuWeatherProvider struct {
+	apiKey   string
+	baseURL  string
+	language string
+	client   *http.Client
+	// locations is shared by the copies WithLanguage makes.
+	locations *locationCache
+}
+
+// NewAccuWeatherProvider creates an AccuWeatherProvider with cfg's
+// account, location cache and provider timeout.
+func NewAccuWeatherProvider(cfg *config.Config) *AccuWeatherProvider {
+	a := &AccuWeatherProvider{
+		apiKey:  string(cfg.AccuWeather.APIKey),
+		baseURL: cfg.AccuWeather.BaseURL,
+		client:  &http.Client{Timeout: cfg.Timeouts.Provider.Duration},
+	}
+	if cfg.Cache.LocationSize > 0 && cfg.Cache.LocationTTL.Duration > 0 {
+		a.locations = newLocationCache("accuweather_location", cfg.Cache.LocationTTL.Duration, cfg.Cache.LocationSize)
+	}
+	return a
+}
+
+// CheckCredentials reports whether an API key is configured.
+func (a *AccuWeatherProvider) CheckCredentials() error {
+	if a.apiKey == "" {
+		return errors.New("no AccuWeather API key; set accuweather.api_key or ACCUWEATHER_API_KEY")
+	}
+	return nil
+}
+
+// WithLanguage returns a copy of the provider whose descriptions and
+// alerts are in lang.
+func (a *AccuWeatherProvider) WithLanguage(lang string) WeatherProvider {
+	c := *a
+	c.language = lang
+	return &c
+}
+
+// languageParam is the query parameter asking for localized text, if a
+// language is set.
+func (a *AccuWeatherProvider) languageParam() string {
+	if a.language == "" {
+		return ""
+	}
+	return "&language=" + url.QueryEscape(a.language)
+}
+
+// lookupLocationKey finds the AccuWeather location key for a Location
+func (a *AccuWeatherProvider) lookupLocationKey(ctx context.Context, loc geo.Location) (string, error) {
+	l, err := a.lookupLocation(ctx, loc)
+	return l.Key, err
+}
+
+// lookupLocation finds the AccuWeather location record for a Location, from
+// the cache or else the API. Names are localized, so entries are per
+// language.
+func (a *AccuWeatherProvider) lookupLocation(ctx context.Context, loc geo.Location) (_ accuLocation, err error) {
+	ctx, span := startSpan(ctx, "accuweather.lookup_location", locationAttr(loc))
+	defer func() { endSpan(span, err) }()
+	key := a.language + "|" + strings.ToLower(loc.QueryString())
+	l, ok := a.locations.get(key)
+	span.SetAttributes(attribute.Bool("cache.hit", ok))
+	if ok {
+		return l, nil
+	}
+	l, err = a.searchLocation(ctx, loc)
+	if err != nil {
+		return accuLocation{}, err
+	}
+	a.locations.put(key, l)
+	return l, nil
+}
+
+// searchLocation asks the API for a Location's record, using the exact
+// position or postal code when known and the city search otherwise
+func (a *AccuWeatherProvider) searchLocation(ctx context.Context, loc geo.Location) (accuLocation, error) {
+	var locs []accuLocation
+	var err error
+	switch {
+	case loc.Coords != nil:
+		return a.lookupGeoposition(ctx, loc.Coords.Lat, loc.Coords.Lon)
+	case loc.PostalCode != "":
+		locs, err = a.searchPostalCode(ctx, loc.PostalCode, loc.Country)
+	case loc.Query != "":
+		locs, err = a.searchCities(ctx, loc.Query)
+	default:
+		return accuLocation{}, fmt.Errorf("location %q must be resolved before lookup", loc)
+	}
+	if err != nil {
+		return accuLocation{}, err
+	}
+	if len(locs) == 0 {
+		return accuLocation{}, fmt.Errorf("location not found: %s", loc)
+	}
+	return locs[0], nil
+}
+
+// accuLocation is the subset of an AccuWeather location record we use.
+type accuLocation struct {
+	Key                string
+	LocalizedName      string
+	AdministrativeArea struct{ LocalizedName string }
+	Country            struct{ LocalizedName string }
+	GeoPosition        struct{ Latitude, Longitude float64 }
+	TimeZone           struct{ Name string }
+}
+
+// searchCities runs the AccuWeather city search and returns every match.
+func (a *AccuWeatherProvider) searchCities(ctx context.Context, location string) ([]accuLocation, error) {
+	searchURL := fmt.Sprintf(
+		"%s/locations/v1/cities/search?apikey=%s&q=%s",
+		a.baseURL, a.apiKey, url.QueryEscape(location),
+	)
+	var locs []accuLocation
+	if err := getJSON(ctx, a.client, "accuweather", "city_search", searchURL, &locs); err != nil {
+		return nil, err
+	}
+	return locs, nil
+}
+
+// searchPostalCode finds the locations for a postal code within a country.
+func (a *AccuWeatherProvider) searchPostalCode(ctx context.Context, code, country string) ([]accuLocation, error) {
+	searchURL := fmt.Sprintf(
+		"%s/locations/v1/postalcodes/%s/search?apikey=%s&q=%s",
+		a.baseURL, url.PathEscape(country), a.apiKey, url.QueryEscape(code),
+	)
+	var locs []accuLocation
+	if err := getJSON(ctx, a.client, "accuweather", "postalcode_search", searchURL, &locs); err != nil {
+		return nil, err
+	}
+	return locs, nil
+}
+
+// lookupGeoposition finds the AccuWeather location nearest a position.
+func (a *AccuWeatherProvider) lookupGeoposition(ctx context.Context, lat, lon float64) (accuLocation, error) {
+	searchURL := fmt.Sprintf(
+		"%s/locations/v1/cities/geoposition/search?apikey=%s&q=%s",
+		a.baseURL, a.apiKey, url.QueryEscape(geo.FormatCoords(lat, lon)),
+	)
+	var loc accuLocation
+	if err := getJSON(ctx, a.client, "accuweather", "geoposition_search", searchURL, &loc); err != nil {
+		return accuLocation{}, err
+	}
+	if loc.Key == "" {
+		return accuLocation{}, fmt.Errorf("no location near %s", geo.FormatCoords(lat, lon))
+	}
+	return loc, nil
+}
+
+// Geocode returns every AccuWeather city matching query, so callers can
+// disambiguate instead of taking the first match.
+func (a *AccuWeatherProvider) Geocode(ctx context.Context, query string) (_ []geo.Place, err error) {
+	ctx, span := startSpan(ctx, "accuweather.geocode", attribute.String("weather.query", query))
+	defer func() { endSpan(span, err) }()
+	locs, err := a.searchCities(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	var out []geo.Place
+	for _, l := range locs {
+		out = append(out, geo.Place{
+			Name:    l.LocalizedName,
+			Region:  l.AdministrativeArea.LocalizedName,
+			Country: l.Country.LocalizedName,
+			Lat:     l.GeoPosition.Latitude,
+			Lon:     l.GeoPosition.Longitude,
+
+			TimeZone: l.TimeZone.Name,
+		})
+	}
+	return out, nil
+}
+
+// Current fetches the current conditions for a location.
+func (a *AccuWeatherProvider) Current(ctx context.Context, loc geo.Location) (_ *WeatherData, err error) {
+	ctx, span := startSpan(ctx, "accuweather.current", locationAttr(loc))
+	defer func() { endSpan(span, err) }()
+	al, err := a.lookupLocation(ctx, loc)
+	if err != nil {
+		return nil, err
+	}
+	condURL := fmt.Sprintf(
+		"%s/currentconditions/v1/%s?apikey=%s&details=true%s",
+		a.baseURL, al.Key, a.apiKey, a.languageParam(),
+	)
+	// Current conditions report each quantity in both systems; we read Metric.
+	type metric struct {
+		Metric struct{ Value float64 } `json:"Metric"`
+	}
+	var cs []struct {
+		LocalObservationDateTime time.Time `json:"LocalObservationDateTime"`
+		WeatherText              string    `json:"WeatherText"`
+		WeatherIcon              int       `json:"WeatherIcon"`
+		Temperature              metric    `json:"Temperature"`
+		RealFeelTemperature      metric    `json:"RealFeelTemperature"`
+		RelativeHumidity         float64   `json:"RelativeHumidity"`
+		Wind                     struct {
+			Speed     metric                     `json:"Speed"`
+			Direction struct{ Localized string } `json:"Direction"`
+		} `json:"Wind"`
+		Pressure   metric `json:"Pressure"`
+		Precip1hr  metric `json:"Precip1hr"`
+		Visibility metric `json:"Visibility"`
+	}
+	if err := getJSON(ctx, a.client, "accuweather", "current", condURL, &cs); err != nil {
+		return nil, err
+	}
+	if len(cs) == 0 {
+		return nil, fmt.Errorf("no current conditions for %s", loc)
+	}
+	c := cs[0]
+	return &WeatherData{
+		Description:   c.WeatherText,
+		Condition:     AccuWeatherCondition(c.WeatherIcon, c.WeatherText),
+		Temperature:   units.Temperature(c.Temperature.Metric.Value),
+		FeelsLike:     units.Temperature(c.RealFeelTemperature.Metric.Value),
+		Humidity:      c.RelativeHumidity,
+		WindSpeed:     units.Speed(c.Wind.Speed.Metric.Value),
+		WindDir:       c.Wind.Direction.Localized,
+		Pressure:      units.Pressure(c.Pressure.Metric.Value),
+		Precipitation: units.Precipitation(c.Precip1hr.Metric.Value),
+		Visibility:    units.Distance(c.Visibility.Metric.Value),
+		Time:          c.LocalObservationDateTime,
+		TimeZone:      al.TimeZone.Name,
+	}, nil
+}
+
+// Forecast retrieves up to 5-day forecasts, padded to the requested days.
+func (a *AccuWeatherProvider) Forecast(ctx context.Context, loc geo.Location, days int) (_ []WeatherData, err error) {
+	ctx, span := startSpan(ctx, "accuweather.forecast", locationAttr(loc), attribute.Int("weather.days", days))
+	defer func() { endSpan(span, err) }()
+	al, err := a.lookupLocation(ctx, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	// AccuWeather supports daily forecasts up to 5 days
+	requestDays := days
+	if requestDays > 5 {
+		requestDays = 5
+	}
+	url := fmt.Sprintf(
+		"%s/forecasts/v1/daily/%dday/%s?apikey=%s&metric=true&details=true%s",
+		a.baseURL, requestDays, al.Key, a.apiKey, a.languageParam(),
+	)
+	var r struct {
+		DailyForecasts []struct {
+			Date time.Time `json:"Date"`
+			Sun  struct {
+				Rise time.Time `json:"Rise"`
+				Set  time.Time `json:"Set"`
+			} `json:"Sun"`
+			Temperature struct {
+				Minimum struct{ Value float64 } `json:"Minimum"`
+				Maximum struct{ Value float64 } `json:"Maximum"`
+			} `json:"Temperature"`
+			RealFeelTemperature struct {
+				Minimum struct{ Value float64 } `json:"Minimum"`
+				Maximum struct{ Value float64 } `json:"Maximum"`
+			} `json:"RealFeelTemperature"`
+			Day struct {
+				Icon                     int     `json:"Icon"`
+				IconPhrase               string  `json:"IconPhrase"`
+				PrecipitationProbability float64 `json:"PrecipitationProbability"`
+				Wind                     struct {
+					Speed     struct{ Value float64 }    `json:"Speed"`
+					Direction struct{ Localized string } `json:"Direction"`
+				} `json:"Wind"`
+				TotalLiquid struct{ Value float64 } `json:"TotalLiquid"`
+			} `json:"Day"`
+			Night struct {
+				TotalLiquid struct{ Value float64 } `json:"TotalLiquid"`
+			} `json:"Night"`
+		} `json:"DailyForecasts"`
+	}
+	if err := getJSON(ctx, a.client, "accuweather", "forecast", url, &r); err != nil {
+		return nil, err
+	}
+
+	var out []WeatherData
+	for i, fc := range r.DailyForecasts {
+		if i >= requestDays {
+			break
+		}
+		out = append(out, WeatherData{
+			Description: fc.Day.IconPhrase,
+			Condition:   AccuWeatherCondition(fc.Day.Icon, fc.Day.IconPhrase),
+			Temperature: units.Temperature(fc.Temperature.Maximum.Value),
+			FeelsLike:   units.Temperature(fc.RealFeelTemperature.Maximum.Value),
+			Humidity:    fc.Day.PrecipitationProbability,
+			WindSpeed:   units.Speed(fc.Day.Wind.Speed.Value),
+			WindDir:     fc.Day.Wind.Direction.Localized,
+
+			MinTemp:           units.Temperature(fc.Temperature.Minimum.Value),
+			MaxTemp:           units.Temperature(fc.Temperature.Maximum.Value),
+			PrecipProbability: fc.Day.PrecipitationProbability,
+			// the day's rain, snow and ice as water, over day and night
+			Precipitation: units.Precipitation(fc.Day.TotalLiquid.Value + fc.Night.TotalLiquid.Value),
+
+			Time:     fc.Date,
+			Sunrise:  fc.Sun.Rise,
+			Sunset:   fc.Sun.Set,
+			TimeZone: al.TimeZone.Name,
+		})
+	}
+	// pad for days beyond those returned
+	for len(out) < days {
+		out = append(out, WeatherData{Description: "Forecast unavailable", Unavailable: true})
+	}
+	return out, nil
+}
+
+// Alerts fetches the severe weather alerts issued for a location.
+func (a *AccuWeatherProvider) Alerts(ctx context.Context, loc geo.Location) (_ []Alert, err error) {
+	ctx, span := startSpan(ctx, "accuweather.alerts", locationAttr(loc))
+	defer func() { endSpan(span, err) }()
+	key, err := a.lookupLocationKey(ctx, loc)
+	if err != nil {
+		return nil, err
+	}
+	alertsURL := fmt.Sprintf("%s/alerts/v1/%s?apikey=%s&details=true%s", a.baseURL, key, a.apiKey, a.languageParam())
+	var as []struct {
+		Category    string `json:"Category"`
+		Level       string `json:"Level"`
+		Description struct {
+			Localized string `json:"Localized"`
+		} `json:"Description"`
+		Area []struct {
+			StartTime time.Time `json:"StartTime"`
+			EndTime   time.Time `json:"EndTime"`
+			Text      string    `json:"Text"`
+		} `json:"Area"`
+	}
+	if err := getJSON(ctx, a.client, "accuweather", "alerts", alertsURL, &as); err != nil {
+		return nil, err
+	}
+
+	var out []Alert
+	for _, al := range as {
+		alert := Alert{
+			Severity:    ParseSeverity(al.Level),
+			Category:    al.Category,
+			Description: al.Description.Localized,
+		}
+		if len(al.Area) > 0 {
+			alert.Start = al.Area[0].StartTime
+			alert.End = al.Area[0].EndTime
+			if alert.Description == "" {
+				alert.Description = al.Area[0].Text
+			}
+		}
+		out = append(out, alert)
+	}
+	return out, nil
+}
@@ -0,0 +1,358 @@
+package weather
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"weatherapp/internal/config"
+	"weatherapp/internal/geo"
+	"weatherapp/internal/logging"
+	"weatherapp/internal/metrics"
+	"weatherapp/internal/tracing/tracingtest"
+	"weatherapp/internal/units"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// TestAccuWeatherCurrent checks the metric values of current conditions are
+// read into typed quantities.
+func TestAccuWeatherCurrent(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/locations/v1/cities/search", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"Key":"328328","TimeZone":{"Name":"Europe/London"}}]`))
+	})
+	mux.HandleFunc("/currentconditions/v1/328328", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{
+			"WeatherText": "Light rain",
+			"WeatherIcon": 18,
+			"Temperature": {"Metric": {"Value": 11.1}, "Imperial": {"Value": 52}},
+			"RealFeelTemperature": {"Metric": {"Value": 9.4}, "Imperial": {"Value": 49}},
+			"RelativeHumidity": 87,
+			"Wind": {"Direction": {"Localized": "SW"}, "Speed": {"Metric": {"Value": 18.5}, "Imperial": {"Value": 11.5}}},
+			"Pressure": {"Metric": {"Value": 1008}, "Imperial": {"Value": 29.77}},
+			"Precip1hr": {"Metric": {"Value": 0.8}, "Imperial": {"Value": 0.03}},
+			"Visibility": {"Metric": {"Value": 9.7}, "Imperial": {"Value": 6}}
+		}]`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	a := &AccuWeatherProvider{apiKey: "k", baseURL: srv.URL}
+	d, err := a.Current(context.Background(), geo.ParseLocation("london"))
+	require.NoError(t, err)
+	assert.Equal(t, units.Temperature(11.1), d.Temperature)
+	assert.Equal(t, ConditionRain, d.Condition)
+	assert.Equal(t, units.Temperature(9.4), d.FeelsLike)
+	assert.Equal(t, units.Speed(18.5), d.WindSpeed)
+	assert.Equal(t, units.Pressure(1008), d.Pressure)
+	assert.Equal(t, units.Precipitation(0.8), d.Precipitation)
+	assert.Equal(t, units.Distance(9.7), d.Visibility)
+	assert.Equal(t, "Europe/London", d.TimeZone)
+}
+
+// TestAccuWeatherForecast checks each day's liquid total, day and night, is
+// read as its precipitation and that days past the fifth are unavailable.
+func TestAccuWeatherForecast(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/locations/v1/cities/search", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"Key":"328328","TimeZone":{"Name":"Europe/London"}}]`))
+	})
+	mux.HandleFunc("/forecasts/v1/daily/5day/328328", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"DailyForecasts": [
+			{
+				"Date": "2026-10-19T07:00:00+01:00",
+				"Temperature": {"Minimum": {"Value": 8.2}, "Maximum": {"Value": 14.6}},
+				"Day": {"Icon": 18, "IconPhrase": "Rain", "PrecipitationProbability": 80, "TotalLiquid": {"Value": 6.1, "Unit": "mm"}},
+				"Night": {"TotalLiquid": {"Value": 2.3, "Unit": "mm"}}
+			},
+			{
+				"Date": "2026-10-20T07:00:00+01:00",
+				"Temperature": {"Minimum": {"Value": 7.5}, "Maximum": {"Value": 15.1}},
+				"Day": {"Icon": 1, "IconPhrase": "Sunny", "TotalLiquid": {"Value": 0, "Unit": "mm"}}
+			}
+		]}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	a := &AccuWeatherProvider{apiKey: "k", baseURL: srv.URL}
+	data, err := a.Forecast(context.Background(), geo.ParseLocation("london"), 7)
+	require.NoError(t, err)
+	require.Len(t, data, 7)
+	assert.InDelta(t, 8.4, float64(data[0].Precipitation), 1e-9)
+	assert.Equal(t, 80.0, data[0].PrecipProbability)
+	assert.Equal(t, units.Temperature(8.2), data[0].MinTemp)
+	assert.Zero(t, data[1].Precipitation)
+	assert.False(t, data[1].Unavailable)
+	for _, d := range data[5:] {
+		assert.True(t, d.Unavailable)
+	}
+}
+
+// TestAccuWeatherAlerts checks that the alerts endpoint is parsed into typed alerts.
+func TestAccuWeatherAlerts(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/locations/v1/cities/search", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"Key":"349727"}]`))
+	})
+	mux.HandleFunc("/alerts/v1/349727", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{
+			"Category": "FLOOD",
+			"Level": "Severe",
+			"Description": {"Localized": "Flood Warning"},
+			"Area": [{"StartTime": "2026-10-19T06:00:00-04:00", "EndTime": "2026-10-20T06:00:00-04:00", "Text": "River flooding"}]
+		}]`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	a := &AccuWeatherProvider{apiKey: "k", baseURL: srv.URL}
+	alerts, err := a.Alerts(context.Background(), geo.ParseLocation("new york"))
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+
+	al := alerts[0]
+	assert.Equal(t, SeveritySevere, al.Severity)
+	assert.Equal(t, "FLOOD", al.Category)
+	assert.Equal(t, "Flood Warning", al.Description)
+	assert.True(t, al.Start.Equal(time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)))
+	assert.True(t, al.Active(time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)))
+	assert.False(t, al.Active(time.Date(2026, 10, 21, 0, 0, 0, 0, time.UTC)))
+}
+
+// TestAccuWeatherGeocode checks all matches are returned and that resolved
+// coordinates use the geoposition lookup instead of the text search.
+func TestAccuWeatherGeocode(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/locations/v1/cities/search", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"Key":"1","LocalizedName":"Paris","AdministrativeArea":{"LocalizedName":"Ile-de-France"},"Country":{"LocalizedName":"France"},"GeoPosition":{"Latitude":48.857,"Longitude":2.341}},
+			{"Key":"2","LocalizedName":"Paris","AdministrativeArea":{"LocalizedName":"Texas"},"Country":{"LocalizedName":"United States"},"GeoPosition":{"Latitude":33.661,"Longitude":-95.556}}]`))
+	})
+	mux.HandleFunc("/locations/v1/cities/geoposition/search", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "33.6610,-95.5560", r.URL.Query().Get("q"))
+		w.Write([]byte(`{"Key":"2"}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	a := &AccuWeatherProvider{apiKey: "k", baseURL: srv.URL}
+	places, err := a.Geocode(context.Background(), "paris")
+	require.NoError(t, err)
+	require.Len(t, places, 2)
+	assert.Equal(t, "Paris, Texas, United States", places[1].String())
+
+	key, err := a.lookupLocationKey(context.Background(), geo.ParseLocation("33.6610,-95.5560"))
+	require.NoError(t, err)
+	assert.Equal(t, "2", key)
+}
+
+// TestAccuWeatherPostalCode checks postal codes use the country-scoped search.
+func TestAccuWeatherPostalCode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/locations/v1/postalcodes/GB/search", r.URL.Path)
+		assert.Equal(t, "SW1A 1AA", r.URL.Query().Get("q"))
+		w.Write([]byte(`[{"Key":"328328"}]`))
+	}))
+	defer srv.Close()
+
+	a := &AccuWeatherProvider{apiKey: "k", baseURL: srv.URL}
+	key, err := a.lookupLocationKey(context.Background(), geo.ParseLocation("sw1a 1aa, gb"))
+	require.NoError(t, err)
+	assert.Equal(t, "328328", key)
+}
+
+// TestAccuWeatherCallLogging checks each HTTP call is logged with its
+// endpoint, status and the operation's correlation ID, and that failures
+// name the call without revealing the API key.
+func TestAccuWeatherCallLogging(t *testing.T) {
+	var buf bytes.Buffer
+	h, err := logging.NewHandler(&buf, slog.LevelDebug, "json")
+	require.NoError(t, err)
+	orig := slog.Default()
+	slog.SetDefault(slog.New(h))
+	t.Cleanup(func() { slog.SetDefault(orig) })
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/locations/v1/cities/search", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"Key":"328328"}]`))
+	})
+	mux.HandleFunc("/currentconditions/v1/328328", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"Code":"Unauthorized"}`, http.StatusUnauthorized)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	a := &AccuWeatherProvider{apiKey: "secret-key", baseURL: srv.URL}
+	ctx := logging.Start(context.Background(), "view weather")
+	_, err = a.Current(ctx, geo.ParseLocation("london"))
+	require.EqualError(t, err, "accuweather current: request failed: 401 Unauthorized")
+
+	logged := buf.String()
+	assert.NotContains(t, logged, "secret-key")
+	var recs []map[string]any
+	dec := json.NewDecoder(strings.NewReader(logged))
+	for dec.More() {
+		var r map[string]any
+		require.NoError(t, dec.Decode(&r))
+		recs = append(recs, r)
+	}
+	require.Len(t, recs, 2)
+	assert.Equal(t, "provider call", recs[0]["msg"])
+	assert.Equal(t, "city_search", recs[0]["endpoint"])
+	assert.Equal(t, float64(200), recs[0]["status"])
+	assert.Equal(t, "provider call failed", recs[1]["msg"])
+	assert.Equal(t, "accuweather", recs[1]["provider"])
+	assert.Equal(t, "current", recs[1]["endpoint"])
+	assert.Equal(t, float64(401), recs[1]["status"])
+	assert.Equal(t, logging.ID(ctx), recs[1]["correlation_id"])
+}
+
+// TestAccuWeatherLocationCache checks repeat requests for a place skip the
+// location search, per language, and count as cache hits.
+func TestAccuWeatherLocationCache(t *testing.T) {
+	searches := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/locations/v1/cities/search", func(w http.ResponseWriter, r *http.Request) {
+		searches++
+		w.Write([]byte(`[{"Key":"328328"}]`))
+	})
+	mux.HandleFunc("/currentconditions/v1/328328", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"WeatherText": "Sunny"}]`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	a := &AccuWeatherProvider{apiKey: "k", baseURL: srv.URL, locations: newLocationCache("test_location", time.Hour, 10)}
+	ctx := context.Background()
+	for _, q := range []string{"London", "london "} {
+		_, err := a.Current(ctx, geo.ParseLocation(q))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, searches)
+	assert.Equal(t, 1.0, counter(t, "weatherapp_cache_hits_total", "test_location"))
+
+	_, err := a.WithLanguage("de").Current(ctx, geo.ParseLocation("London"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, searches, "names are localized, so each language has its own entries")
+
+	a.locations.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	_, err = a.Current(ctx, geo.ParseLocation("London"))
+	require.NoError(t, err)
+	assert.Equal(t, 3, searches, "expired entries are looked up again")
+}
+
+// TestAccuWeatherTrace checks a Current call is one trace: the location
+// lookup and conditions request under the provider's span, each HTTP call
+// a client span whose context is sent to the API.
+func TestAccuWeatherTrace(t *testing.T) {
+	spans, restore := tracingtest.UseMemory()
+	defer restore()
+	var traceparents []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/locations/v1/cities/search", func(w http.ResponseWriter, r *http.Request) {
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		w.Write([]byte(`[{"Key":"328328"}]`))
+	})
+	mux.HandleFunc("/currentconditions/v1/328328", func(w http.ResponseWriter, r *http.Request) {
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		http.Error(w, "quota exceeded", http.StatusServiceUnavailable)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	a := &AccuWeatherProvider{apiKey: "k", baseURL: srv.URL}
+	ctx, root := otel.Tracer("test").Start(context.Background(), "view weather")
+	_, err := a.Current(ctx, geo.ParseLocation("London"))
+	root.End()
+	require.Error(t, err)
+
+	byName := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range spans.GetSpans().Snapshots() {
+		assert.Equal(t, root.SpanContext().TraceID(), s.SpanContext().TraceID(), s.Name())
+		byName[s.Name()] = s
+	}
+	require.Len(t, byName, 5)
+	parentOf := func(name string) string {
+		for n, s := range byName {
+			if s.SpanContext().SpanID() == byName[name].Parent().SpanID() {
+				return n
+			}
+		}
+		return ""
+	}
+	assert.Equal(t, "view weather", parentOf("accuweather.current"))
+	assert.Equal(t, "accuweather.current", parentOf("accuweather.lookup_location"))
+	assert.Equal(t, "accuweather.lookup_location", parentOf("GET accuweather city_search"))
+	assert.Equal(t, "accuweather.current", parentOf("GET accuweather current"))
+
+	failed := byName["GET accuweather current"]
+	assert.Equal(t, codes.Error, failed.Status().Code)
+	assert.Contains(t, failed.Attributes(), attribute.Int("http.response.status_code", 503))
+	assert.Equal(t, codes.Error, byName["accuweather.current"].Status().Code)
+	assert.Contains(t, byName["accuweather.lookup_location"].Attributes(), attribute.Bool("cache.hit", false))
+
+	require.Len(t, traceparents, 2)
+	for _, tp := range traceparents {
+		assert.Contains(t, tp, root.SpanContext().TraceID().String())
+	}
+}
+
+// TestNewProvider checks providers are built from the config's account,
+// cache and timeout settings.
+func TestNewProvider(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.WeatherProvider = "accuweather"
+	cfg.AccuWeather.APIKey = "k"
+	cfg.Timeouts.Provider.Duration = 3 * time.Second
+	p, err := NewProvider(&cfg)
+	require.NoError(t, err)
+	a := p.(*AccuWeatherProvider)
+	assert.Equal(t, "k", a.apiKey)
+	assert.Equal(t, 3*time.Second, a.client.Timeout)
+	assert.NotNil(t, a.locations)
+	assert.NoError(t, a.CheckCredentials())
+
+	cfg.Cache.LocationSize = 0
+	p, err = NewProvider(&cfg)
+	require.NoError(t, err)
+	assert.Nil(t, p.(*AccuWeatherProvider).locations, "a zero size disables the cache")
+
+	cfg.WeatherProvider = "weatherstack"
+	p, err = NewProvider(&cfg)
+	require.NoError(t, err)
+	assert.ErrorContains(t, p.(*WeatherstackProvider).CheckCredentials(), "WEATHERSTACK_API_KEY")
+
+	cfg.WeatherProvider = "metoffice"
+	_, err = NewProvider(&cfg)
+	assert.Error(t, err)
+}
+
+// counter reads the value of the counter name whose only label has value.
+func counter(t *testing.T, name, value string) float64 {
+	mfs, err := metrics.Registry.Gather()
+	require.NoError(t, err)
+	for _, mf := range mfs {
+		if mf.GetName() != name {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			if m.GetLabel()[0].GetValue() == value {
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,121 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"weatherapp/internal/geo"
+)
+
+// Severity ranks how dangerous an Alert is.
+type Severity int
+
+const (
+	SeverityUnknown Severity = iota
+	SeverityMinor
+	SeverityModerate
+	SeveritySevere
+	SeverityExtreme
+)
+
+// String returns the lower-case name of the severity.
+func (s Severity) String() string {
+	switch s {
+	case SeverityMinor:
+		return "minor"
+	case SeverityModerate:
+		return "moderate"
+	case SeveritySevere:
+		return "severe"
+	case SeverityExtreme:
+		return "extreme"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseSeverity maps a vendor severity or level name onto a Severity.
+func ParseSeverity(s string) Severity {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "minor", "low", "advisory", "statement", "yellow":
+		return SeverityMinor
+	case "moderate", "medium", "watch", "orange":
+		return SeverityModerate
+	case "severe", "high", "warning", "red":
+		return SeveritySevere
+	case "extreme", "emergency":
+		return SeverityExtreme
+	default:
+		return SeverityUnknown
+	}
+}
+
+// Alert is a severe weather warning issued for a location.
+type Alert struct {
+	Severity    Severity
+	Category    string
+	Start       time.Time
+	End         time.Time
+	Description string
+}
+
+// Active reports whether the alert is in effect at t. Zero start or end
+// times are treated as open-ended.
+func (a Alert) Active(t time.Time) bool {
+	if !a.Start.IsZero() && t.Before(a.Start) {
+		return false
+	}
+	if !a.End.IsZero() && !t.Before(a.End) {
+		return false
+	}
+	return true
+}
+
+// AlertProvider is implemented by providers that can report weather alerts.
+// Only AccuWeatherProvider does: Weatherstack has no alerts endpoint, and
+// Open-Meteo, used here for geocoding, publishes no warnings. With
+// Weatherstack, reports carry no alerts and FetchAlerts returns
+// ErrAlertsUnsupported.
+type AlertProvider interface {
+	Alerts(ctx context.Context, loc geo.Location) ([]Alert, error)
+}
+
+// ErrAlertsUnsupported is returned by FetchAlerts when the active provider
+// does not report alerts.
+var ErrAlertsUnsupported = errors.New("the weather provider does not report alerts; use AccuWeather for alerts")
+
+// activeAlerts fetches the alerts in effect now for a report, or nil if
+// the provider has no alerts support or the lookup fails. A failed lookup
+// is logged rather than failing the report it would have been shown with.
+func activeAlerts(ctx context.Context, p WeatherProvider, loc geo.Location) []Alert {
+	ap, ok := p.(AlertProvider)
+	if !ok {
+		return nil
+	}
+	alerts, err := fetchActiveAlerts(ctx, ap, loc)
+	if err != nil {
+		slog.WarnContext(ctx, "fetching alerts", "location", loc.String(), "error", err)
+		return nil
+	}
+	return alerts
+}
+
+// fetchActiveAlerts returns the alerts ap reports for loc that are in
+// effect now.
+func fetchActiveAlerts(ctx context.Context, ap AlertProvider, loc geo.Location) ([]Alert, error) {
+	all, err := ap.Alerts(ctx, loc)
+	if err != nil {
+		return nil, err
+	}
+	at := now()
+	var out []Alert
+	for _, a := range all {
+		if a.Active(at) {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
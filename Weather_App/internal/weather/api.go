@@ -1,129 +1,428 @@
-package weather
-
-import (
-	"bufio"
-	"fmt"
-	"io"
-	"os"
-	"strings"
-	"weatherapp/models"
-
-	"github.com/joho/godotenv"
-)
-
-var (
-	provider     WeatherProvider
-	outputWriter io.Writer
-)
-
-// InitProvider sets the active WeatherProvider.
-func InitProvider(p WeatherProvider) {
-	provider = p
-}
-
-// ShowWeather uses the configured provider to display either a one-day detailed view or a multi-day forecast
-func ShowWeather(user models.User) {
-	loc := user.Preferences.Location
-	unit := strings.ToLower(user.Preferences.Unit)
-	verbosity := strings.ToLower(user.Preferences.Verbosity)
-	forecast := strings.ToLower(user.Preferences.Forecast)
-
-	if forecast == "day" {
-		data, err := provider.Current(loc)
-		if err != nil {
-			fmt.Fprintf(getWriter(), "Error: %v\n", err)
-			return
-		}
-		renderDetailed(loc, data, verbosity, unit)
-	} else {
-		days := 7
-		if forecast == "month" {
-			days = 30
-		}
-		dataSlice, err := provider.Forecast(loc, days)
-		if err != nil {
-			fmt.Fprintf(getWriter(), "Error: %v\n", err)
-			return
-		}
-		renderForecast(loc, dataSlice, verbosity, unit, forecast)
-	}
-}
-
-// ShowOtherLocations prompts and then shows current weather for one city.
-func ShowOtherLocations(reader *bufio.Reader) {
-	fmt.Print("Enter location: ")
-	loc, _ := reader.ReadString('\n')
-	loc = strings.TrimSpace(loc)
-	if loc == "" {
-		fmt.Fprintln(getWriter(), "No location entered.")
-		return
-	}
-	data, err := provider.Current(loc)
-	if err != nil {
-		fmt.Fprintf(getWriter(), "Error: %v\n", err)
-		return
-	}
-	fmt.Fprintf(getWriter(), "Location: %s | %s | %.0f°C\n",
-		strings.Title(loc), data.Description, data.Temperature)
-}
-
-// to print detailed view
-func renderDetailed(loc string, d *WeatherData, verbosity, unit string) {
-	unitLabel := "°C"
-	temp := d.Temperature
-	feels := d.FeelsLike
-	if unit == "fahrenheit" {
-		temp = temp*9/5 + 32
-		feels = feels*9/5 + 32
-		unitLabel = "°F"
-	}
-	out := getWriter()
-	fmt.Fprintf(out, "\n Weather for %s\n", strings.Title(loc))
-	fmt.Fprintln(out, "------------------------")
-	fmt.Fprintf(out, "Description : %s\n", d.Description)
-	fmt.Fprintf(out, "Temperature : %.0f %s\n", temp, unitLabel)
-	if verbosity == "verbose" {
-		fmt.Fprintf(out, "Feels Like  : %.0f %s\n", feels, unitLabel)
-		fmt.Fprintf(out, "Humidity    : %.0f%%\n", d.Humidity)
-		fmt.Fprintf(out, "Wind        : %.0f km/h (%s)\n", d.WindSpeed, d.WindDir)
-	}
-}
-
-// to print multi-day forecast
-func renderForecast(loc string, data []WeatherData, verbosity, unit, label string) {
-	unitLabel := "°C"
-	if unit == "fahrenheit" {
-		unitLabel = "°F"
-	}
-	out := getWriter()
-	fmt.Fprintf(out, "\n Forecast for %s (%s)\n", strings.Title(loc), label)
-	fmt.Fprintln(out, "----------------------------")
-	for i, d := range data {
-		temp := d.Temperature
-		if unit == "fahrenheit" {
-			temp = temp*9/5 + 32
-		}
-		fmt.Fprintf(out, "Day %d: %s – %.0f%s\n", i+1, d.Description, temp, unitLabel)
-		if verbosity == "verbose" {
-			feels := d.FeelsLike
-			if unit == "fahrenheit" {
-				feels = feels*9/5 + 32
-			}
-			fmt.Fprintf(out, "  Feels like : %.0f%s\n", feels, unitLabel)
-			fmt.Fprintf(out, "  Humidity   : %.0f%%\n", d.Humidity)
-			fmt.Fprintf(out, "  Wind       : %.0f km/h\n", d.WindSpeed)
-		}
-	}
-}
-
-func init() {
-	_ = godotenv.Load()
-	_ = godotenv.Load("../.env")
-}
-
-func getWriter() io.Writer {
-	if outputWriter != nil {
-		return outputWriter
-	}
-	return io.Writer(os.Stdout)
-}
+package weather
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+	"weatherapp/internal/geo"
+	"weatherapp/internal/i18n"
+	"weatherapp/internal/units"
+	"weatherapp/models"
+
+	"github.com/joho/godotenv"
+	"golang.org/x/text/message"
+)
+
+var (
+	// active holds the WeatherProvider and the geocoder configured with it,
+	// so a config reload swaps both at once while requests run.
+	active       atomic.Pointer[backend]
+	outputWriter io.Writer
+
+	// now is the clock used for local dates; tests replace it.
+	now = time.Now
+)
+
+// backend is a WeatherProvider and the geocoder that goes with it.
+type backend struct {
+	p WeatherProvider
+	g geo.Geocoder
+}
+
+// InitProvider sets the active WeatherProvider, keeping the geocoder. It is
+// safe to call while other goroutines use the previous one, which finish
+// their calls with it.
+func InitProvider(p WeatherProvider) {
+	b := &backend{p: p}
+	if old := active.Load(); old != nil {
+		b.g = old.g
+	}
+	active.Store(b)
+}
+
+// Provider returns the active WeatherProvider, or nil if none is set.
+func Provider() WeatherProvider {
+	if b := active.Load(); b != nil {
+		return b.p
+	}
+	return nil
+}
+
+// Geocoder returns the geocoder Configure set up with the active provider,
+// or nil if none is set.
+func Geocoder() geo.Geocoder {
+	if b := active.Load(); b != nil {
+		return b.g
+	}
+	return nil
+}
+
+// activeGeocoder is installed as geo's Geocoder by Configure. It geocodes
+// with whatever backend is active at the time of each call.
+type activeGeocoder struct{}
+
+func (activeGeocoder) Geocode(ctx context.Context, query string) ([]geo.Place, error) {
+	g := Geocoder()
+	if g == nil {
+		return nil, nil
+	}
+	return g.Geocode(ctx, query)
+}
+
+// ShowWeather uses the configured provider to display either a one-day
+// detailed view or a multi-day forecast, in the User's preferred output format
+func ShowWeather(ctx context.Context, user models.User) {
+	r, err := NewRenderer(user.Preferences.Output)
+	if err == nil {
+		err = WriteReport(ctx, getWriter(), user, ForTerminal(r, getWriter()))
+	}
+	if err != nil {
+		fmt.Fprintln(getWriter(), i18n.Sprintf("Error: %v", err))
+	}
+}
+
+// WriteReport renders the report ShowWeather displays for a User to out.
+func WriteReport(ctx context.Context, out io.Writer, user models.User, r Renderer) error {
+	report, err := BuildReport(ctx, user)
+	if err != nil {
+		return err
+	}
+	return r.Render(out, report)
+}
+
+// BuildReport fetches current conditions or a forecast for a User's
+// preferred location, as their Preferences ask, in their language.
+func BuildReport(ctx context.Context, user models.User) (Report, error) {
+	sys, err := units.FromPreferences(user.Preferences)
+	if err != nil {
+		return Report{}, err
+	}
+	forecast := strings.ToLower(user.Preferences.Forecast)
+	days := ForecastDays(forecast)
+	report, err := FetchReport(ctx, geo.FromPreferences(user.Preferences), days, user.Preferences.Language)
+	if err != nil {
+		return Report{}, err
+	}
+	if days > 0 {
+		report.Period = forecast
+	}
+	report.Verbosity = strings.ToLower(user.Preferences.Verbosity)
+	report.Units = sys
+	return report, nil
+}
+
+// ForecastDays maps the Forecast preference to a number of days; "day"
+// means current conditions only.
+func ForecastDays(pref string) int {
+	switch strings.ToLower(pref) {
+	case "day":
+		return 0
+	case "month":
+		return 30
+	default:
+		return 7
+	}
+}
+
+// FetchReport fetches current conditions for loc when days is 0, or a
+// forecast of that many days, along with any active alerts. Descriptions
+// are in lang when the provider can localize them; empty means the
+// default language. The Report uses metric units until the caller sets
+// Units.
+func FetchReport(ctx context.Context, loc geo.Location, days int, lang string) (Report, error) {
+	loc, err := geo.Locate(ctx, loc)
+	if err != nil {
+		return Report{}, err
+	}
+	p := providerIn(lang)
+	kind, data := KindCurrent, []WeatherData(nil)
+	if days == 0 {
+		current, err := p.Current(ctx, loc)
+		if err != nil {
+			return Report{}, err
+		}
+		data = []WeatherData{*current}
+	} else {
+		kind = KindForecast
+		if data, err = p.Forecast(ctx, loc, days); err != nil {
+			return Report{}, err
+		}
+	}
+	report := NewReport(loc, kind, data, activeAlerts(ctx, p, loc))
+	report.Language = lang
+	report.Units = units.Metric
+	return report, nil
+}
+
+// Errors returned by RequestTarget.
+var (
+	ErrNoLocation   = errors.New("location is required when the user has no preferred location")
+	ErrAutoLocation = errors.New("the auto location is not supported here; pass a place or \"lat,lon\"")
+)
+
+// RequestTarget works out the location and units for an API request on
+// behalf of a User. Non-empty location and unit arguments override the
+// User's Preferences. The auto location is refused, since it would locate
+// the server rather than the caller.
+func RequestTarget(prefs models.Preferences, location, unit string) (geo.Location, units.System, error) {
+	if location = strings.TrimSpace(location); location != "" {
+		prefs.Location, prefs.Coords = location, nil
+	}
+	if prefs.Location == "" {
+		return geo.Location{}, units.System{}, ErrNoLocation
+	}
+	loc := geo.FromPreferences(prefs)
+	if loc.Auto {
+		return geo.Location{}, units.System{}, ErrAutoLocation
+	}
+	if unit != "" {
+		profile, overrides, err := units.ParsePreference(unit)
+		if err != nil {
+			return geo.Location{}, units.System{}, err
+		}
+		prefs.Unit, prefs.UnitOverrides = profile, overrides
+	}
+	sys, err := units.FromPreferences(prefs)
+	if err != nil {
+		return geo.Location{}, units.System{}, err
+	}
+	return loc, sys, nil
+}
+
+// FetchAlerts returns the alerts in effect now for loc, or
+// ErrAlertsUnsupported if the provider does not report alerts. Unlike a
+// report, it fails if the provider's alerts cannot be fetched.
+func FetchAlerts(ctx context.Context, loc geo.Location) ([]Alert, error) {
+	ap, ok := Provider().(AlertProvider)
+	if !ok {
+		return nil, ErrAlertsUnsupported
+	}
+	loc, err := geo.Locate(ctx, loc)
+	if err != nil {
+		return nil, err
+	}
+	return fetchActiveAlerts(ctx, ap, loc)
+}
+
+// ShowOtherLocations prompts and then shows current weather for one city,
+// using the units and output format in prefs.
+func ShowOtherLocations(ctx context.Context, reader *bufio.Reader, prefs models.Preferences) {
+	sys, err := units.FromPreferences(prefs)
+	if err != nil {
+		fmt.Fprintln(getWriter(), i18n.Sprintf("Error: %v", err))
+		return
+	}
+	r, err := NewRenderer(prefs.Output)
+	if err != nil {
+		fmt.Fprintln(getWriter(), i18n.Sprintf("Error: %v", err))
+		return
+	}
+	i18n.Printf("Enter location: ")
+	input, _ := reader.ReadString('\n')
+	input = strings.TrimSpace(input)
+	if input == "" {
+		fmt.Fprintln(getWriter(), i18n.Sprintf("No location entered."))
+		return
+	}
+	input = geo.Suggest(reader, input)
+	loc := geo.ParseLocation(input)
+	if place, ok := geo.Resolve(reader, input); ok {
+		loc = place.Location()
+	}
+	loc, err = geo.Locate(ctx, loc)
+	if err != nil {
+		fmt.Fprintln(getWriter(), i18n.Sprintf("Error: %v", err))
+		return
+	}
+	data, err := providerIn(prefs.Language).Current(ctx, loc)
+	if err != nil {
+		fmt.Fprintln(getWriter(), i18n.Sprintf("Error: %v", err))
+		return
+	}
+	report := NewReport(loc, KindCurrent, []WeatherData{*data}, nil)
+	report.Verbosity = VerbositySummary
+	report.Language = prefs.Language
+	report.Units = sys
+	if err := ForTerminal(r, getWriter()).Render(getWriter(), report); err != nil {
+		fmt.Fprintln(getWriter(), i18n.Sprintf("Error: %v", err))
+	}
+}
+
+// ShowSavedLocations prints current conditions for each saved location in a
+// compact table, with descriptions in lang.
+func ShowSavedLocations(ctx context.Context, locs []models.SavedLocation, sys units.System, lang string) {
+	if len(locs) == 0 {
+		fmt.Fprintln(getWriter(), i18n.Sprintf("No saved locations."))
+		return
+	}
+	p := providerIn(lang)
+	tw := tabwriter.NewWriter(getWriter(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\n\t"+i18n.Sprintf("NAME\tLOCATION\tCONDITIONS\tTEMP"))
+	for _, l := range locs {
+		mark := ""
+		if l.Default {
+			mark = "*"
+		}
+		loc, err := geo.Locate(ctx, geo.FromSaved(l))
+		if err != nil {
+			fmt.Fprintf(tw, "%s\t%s\t%s\terror: %v\t-\n", mark, l.Name, l.Location, err)
+			continue
+		}
+		data, err := p.Current(ctx, loc)
+		if err != nil {
+			fmt.Fprintf(tw, "%s\t%s\t%s\terror: %v\t-\n", mark, l.Name, l.Location, err)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", mark, l.Name, l.Location, data.Description, sys.FormatTemperature(data.Temperature))
+	}
+	tw.Flush()
+}
+
+// to print active alerts ahead of a report, with times in the location's zone
+func renderAlerts(out io.Writer, p *message.Printer, alerts []Alert, zone *time.Location) {
+	if len(alerts) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\n%s\n", p.Sprintf("!!! %d active weather alert(s) !!!", len(alerts)))
+	for _, a := range alerts {
+		fmt.Fprintf(out, "[%s] %s: %s", strings.ToUpper(a.Severity.String()), a.Category, a.Description)
+		if !a.End.IsZero() {
+			fmt.Fprint(out, p.Sprintf(" (until %s)", i18n.FormatDate(p, a.End.In(zone), "Mon 02 Jan 15:04")))
+		}
+		fmt.Fprintln(out)
+	}
+}
+
+// fieldWriter writes "Label : value" lines, with the translated labels
+// padded to the widest of a fixed set so they line up in any language
+// whichever fields are shown.
+type fieldWriter struct {
+	out    io.Writer
+	p      *message.Printer
+	indent string
+	width  int
+}
+
+func newFieldWriter(out io.Writer, p *message.Printer, indent string, labels ...string) fieldWriter {
+	w := fieldWriter{out: out, p: p, indent: indent}
+	for _, l := range labels {
+		w.width = max(w.width, utf8.RuneCountInString(p.Sprintf(l)))
+	}
+	return w
+}
+
+func (w fieldWriter) write(label, value string) {
+	l := w.p.Sprintf(label)
+	pad := strings.Repeat(" ", max(w.width-utf8.RuneCountInString(l), 0))
+	fmt.Fprintf(w.out, "%s%s%s : %s\n", w.indent, l, pad, value)
+}
+
+// to print detailed view, with the condition's picture beside the
+// description and temperature when art is on
+func (t TextRenderer) renderDetailed(out io.Writer, r Report) {
+	d, sys := r.Data[0], r.Units
+	p := i18n.Printer(r.Language)
+	renderAlerts(out, p, r.Alerts, r.Zone)
+	fmt.Fprintf(out, "\n %s\n", p.Sprintf("Weather for %s", i18n.Title(r.Language, r.Location.String())))
+	fmt.Fprintln(out, "------------------------")
+	if t.Art {
+		side := [5]string{
+			t.describe(d),
+			t.temperature(d.Temperature, sys),
+			strings.TrimSpace(sys.FormatSpeed(d.WindSpeed) + " " + d.WindDir),
+			p.Sprintf("%.0f%% humidity", d.Humidity),
+		}
+		for i, line := range t.art(d.Condition) {
+			fmt.Fprintln(out, strings.TrimRight(line+" "+side[i], " "))
+		}
+	}
+	f := newFieldWriter(out, p, "", "Local time", "Description", "Temperature", "Sunrise", "Sunset",
+		"Feels Like", "Humidity", "Wind", "Pressure", "Precip", "Visibility")
+	f.write("Local time", i18n.FormatDate(p, d.Time, "Mon 02 Jan 15:04 MST"))
+	if !t.Art {
+		f.write("Description", d.Description)
+		f.write("Temperature", sys.FormatTemperature(d.Temperature))
+	}
+	if !d.Sunrise.IsZero() && !d.Sunset.IsZero() {
+		f.write("Sunrise", d.Sunrise.Format("15:04"))
+		f.write("Sunset", d.Sunset.Format("15:04"))
+	}
+	if r.Verbosity == "verbose" {
+		f.write("Feels Like", t.temperature(d.FeelsLike, sys))
+		f.write("Humidity", fmt.Sprintf("%.0f%%", d.Humidity))
+		f.write("Wind", fmt.Sprintf("%s (%s)", sys.FormatSpeed(d.WindSpeed), d.WindDir))
+		if d.Pressure > 0 {
+			f.write("Pressure", sys.FormatPressure(d.Pressure))
+		}
+		if d.Precipitation > 0 {
+			f.write("Precip", sys.FormatPrecipitation(d.Precipitation))
+		}
+		if d.Visibility > 0 {
+			f.write("Visibility", sys.FormatDistance(d.Visibility))
+		}
+	}
+}
+
+// to print multi-day forecast, one row per local date
+func (t TextRenderer) renderForecast(out io.Writer, r Report) {
+	sys := r.Units
+	p := i18n.Printer(r.Language)
+	renderAlerts(out, p, r.Alerts, r.Zone)
+	renderForecastTitle(out, p, r)
+	f := newFieldWriter(out, p, "  ", "Feels like", "Humidity", "Wind", "Precip")
+	for _, d := range r.Data {
+		if d.Unavailable {
+			fmt.Fprintf(out, "%s: %s\n", i18n.FormatDate(p, d.Time, "Mon 02 Jan"), d.Description)
+			continue
+		}
+		fmt.Fprintf(out, "%s: %s – %s\n", i18n.FormatDate(p, d.Time, "Mon 02 Jan"), t.describe(d), t.temperature(d.Temperature, sys))
+		if r.Verbosity == "verbose" {
+			f.write("Feels like", t.temperature(d.FeelsLike, sys))
+			f.write("Humidity", fmt.Sprintf("%.0f%%", d.Humidity))
+			f.write("Wind", sys.FormatSpeed(d.WindSpeed))
+			if d.Precipitation > 0 {
+				f.write("Precip", sys.FormatPrecipitation(d.Precipitation))
+			}
+		}
+	}
+}
+
+// renderForecastTitle writes the heading shared by the forecast table and
+// charts.
+func renderForecastTitle(out io.Writer, p *message.Printer, r Report) {
+	fmt.Fprintf(out, "\n %s\n", p.Sprintf("Forecast for %s (%s)", i18n.Title(r.Language, r.Location.String()), p.Sprintf(r.Period)))
+	fmt.Fprintln(out, "----------------------------")
+}
+
+// sunTimes returns the provider's sunrise and sunset for d, or computes them
+// from the location's position for the day of observed.
+func sunTimes(loc geo.Location, d *WeatherData, observed time.Time) (rise, set time.Time, ok bool) {
+	if !d.Sunrise.IsZero() && !d.Sunset.IsZero() {
+		return d.Sunrise, d.Sunset, true
+	}
+	lat, lon, ok := geo.Position(loc)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	return geo.SunTimes(observed, lat, lon)
+}
+
+func init() {
+	_ = godotenv.Load()
+	_ = godotenv.Load("../.env")
+}
+
+func getWriter() io.Writer {
+	if outputWriter != nil {
+		return outputWriter
+	}
+	return io.Writer(os.Stdout)
+}
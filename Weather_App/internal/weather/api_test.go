@@ -1,116 +1,377 @@
-package weather
-
-import (
-	"bufio"
-	"bytes"
-	"strings"
-	"testing"
-	"weatherapp/models"
-
-	"github.com/stretchr/testify/assert"
-)
-
-// fakeProvider implements WeatherProvider for testing.
-type fakeProvider struct {
-	currentData  *WeatherData
-	forecastData []WeatherData
-}
-
-func (f *fakeProvider) Current(location string) (*WeatherData, error) {
-	return f.currentData, nil
-}
-
-func (f *fakeProvider) Forecast(location string, days int) ([]WeatherData, error) {
-	return f.forecastData, nil
-}
-
-// TestShowWeather_Day tests the "day" forecast path of ShowWeather.
-func TestShowWeather_Day(t *testing.T) {
-	// Setup fake provider with sample current data
-	f := &fakeProvider{
-		currentData: &WeatherData{
-			Description: "Sunny",
-			Temperature: 20,
-			FeelsLike:   18,
-			Humidity:    65,
-			WindSpeed:   12,
-			WindDir:     "NE",
-		},
-	}
-	InitProvider(f)
-
-	// Capture output
-	var outBuf bytes.Buffer
-	outputWriter = &outBuf
-
-	// Prepare user preferences for a single-day detailed view
-	user := models.User{
-		Preferences: models.Preferences{
-			Location:  "london",
-			Unit:      "celsius",
-			Verbosity: "verbose",
-			Forecast:  "day",
-		},
-	}
-
-	ShowWeather(user)
-	out := outBuf.String()
-
-	assert.Contains(t, out, "Weather for London")
-	assert.Contains(t, out, "Description : Sunny")
-	assert.Contains(t, out, "Temperature : 20 °C")
-	assert.Contains(t, out, "Feels Like  : 18 °C")
-	assert.Contains(t, out, "Humidity    : 65%")
-	assert.Contains(t, out, "Wind        : 12 km/h (NE)")
-}
-
-// TestShowWeather_Week tests the "week" forecast path of ShowWeather.
-func TestShowWeather_Week(t *testing.T) {
-	// Setup fake provider with sample forecast data for two days
-	f := &fakeProvider{
-		forecastData: []WeatherData{
-			{Description: "Partly Cloudy", Temperature: 15},
-			{Description: "Rainy", Temperature: 12},
-		},
-	}
-	InitProvider(f)
-
-	var outBuf bytes.Buffer
-	outputWriter = &outBuf
-
-	// Prepare user preferences for a weekly forecast
-	user := models.User{
-		Preferences: models.Preferences{
-			Location:  "london",
-			Unit:      "celsius",
-			Verbosity: "brief",
-			Forecast:  "week",
-		},
-	}
-
-	ShowWeather(user)
-	out := outBuf.String()
-
-	assert.Contains(t, out, "Forecast for London (week)")
-	assert.Contains(t, out, "Day 1: Partly Cloudy – 15°C")
-	assert.Contains(t, out, "Day 2: Rainy – 12°C")
-}
-
-// TestShowOtherLocations tests the interactive ShowOtherLocations function.
-func TestShowOtherLocations(t *testing.T) {
-	// Setup fake provider with sample current data
-	f := &fakeProvider{
-		currentData: &WeatherData{Description: "Cloudy", Temperature: 18},
-	}
-	InitProvider(f)
-
-	var outBuf bytes.Buffer
-	outputWriter = &outBuf
-
-	// Simulate user entering "paris" as the location
-	reader := bufio.NewReader(strings.NewReader("paris\n"))
-	ShowOtherLocations(reader)
-	out := outBuf.String()
-
-	assert.Contains(t, out, "Location: Paris | Cloudy | 18°C")
-}
+package weather
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"weatherapp/internal/geo"
+	"weatherapp/internal/units"
+	"weatherapp/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProvider implements WeatherProvider for testing.
+type fakeProvider struct {
+	currentData  *WeatherData
+	forecastData []WeatherData
+	lastLocation geo.Location
+}
+
+func (f *fakeProvider) Current(ctx context.Context, loc geo.Location) (*WeatherData, error) {
+	f.lastLocation = loc
+	return f.currentData, nil
+}
+
+func (f *fakeProvider) Forecast(ctx context.Context, loc geo.Location, days int) ([]WeatherData, error) {
+	f.lastLocation = loc
+	return f.forecastData, nil
+}
+
+// fixClock pins the package clock for the duration of a test.
+func fixClock(t *testing.T, at time.Time) {
+	now = func() time.Time { return at }
+	t.Cleanup(func() { now = time.Now })
+}
+
+// TestShowWeather_Day tests the "day" forecast path of ShowWeather.
+func TestShowWeather_Day(t *testing.T) {
+	fixClock(t, time.Date(2026, 6, 21, 11, 0, 0, 0, time.UTC))
+
+	// Setup fake provider with sample current data
+	f := &fakeProvider{
+		currentData: &WeatherData{
+			Description: "Sunny",
+			Temperature: 20,
+			FeelsLike:   18,
+			Humidity:    65,
+			WindSpeed:   12,
+			WindDir:     "NE",
+		},
+	}
+	InitProvider(f)
+
+	// Capture output
+	var outBuf bytes.Buffer
+	outputWriter = &outBuf
+
+	// Prepare user preferences for a single-day detailed view
+	user := models.User{
+		Preferences: models.Preferences{
+			Location:  "london",
+			Unit:      "celsius",
+			Verbosity: "verbose",
+			Forecast:  "day",
+		},
+	}
+
+	ShowWeather(context.Background(), user)
+	out := outBuf.String()
+
+	assert.Contains(t, out, "Weather for London")
+	assert.Contains(t, out, "Local time  : Sun 21 Jun 12:00 BST")
+	assert.Contains(t, out, "Sunrise     : 04:43")
+	assert.Contains(t, out, "Sunset      : 21:21")
+	assert.Contains(t, out, "Description : Sunny")
+	assert.Contains(t, out, "Temperature : 20°C")
+	assert.Contains(t, out, "Feels Like  : 18°C")
+	assert.Contains(t, out, "Humidity    : 65%")
+	assert.Contains(t, out, "Wind        : 12 km/h (NE)")
+}
+
+// TestShowWeather_Language checks a User's Language translates the labels,
+// keeping them aligned, and the dates of a report.
+func TestShowWeather_Language(t *testing.T) {
+	fixClock(t, time.Date(2026, 6, 21, 11, 0, 0, 0, time.UTC))
+	InitProvider(&fakeProvider{currentData: &WeatherData{Description: "Sonnig", Temperature: 20, Humidity: 65}})
+
+	var outBuf bytes.Buffer
+	outputWriter = &outBuf
+
+	user := models.User{
+		Preferences: models.Preferences{
+			Location:  "london",
+			Unit:      "celsius",
+			Verbosity: "verbose",
+			Forecast:  "day",
+			Language:  "de",
+		},
+	}
+	ShowWeather(context.Background(), user)
+	out := outBuf.String()
+
+	assert.Contains(t, out, "Wetter für London")
+	assert.Contains(t, out, "Ortszeit        : So. 21 Juni 12:00 BST")
+	assert.Contains(t, out, "Sonnenuntergang : 21:21")
+	assert.Contains(t, out, "Luftfeuchte     : 65%")
+}
+
+// TestShowWeather_UnitProfile checks every quantity follows the User's unit
+// profile and per-quantity overrides.
+func TestShowWeather_UnitProfile(t *testing.T) {
+	f := &fakeProvider{
+		currentData: &WeatherData{
+			Description:   "Showers",
+			Temperature:   10,
+			FeelsLike:     8,
+			WindSpeed:     37.04,
+			Pressure:      1013.25,
+			Precipitation: 2.54,
+			Visibility:    16.09,
+		},
+	}
+	InitProvider(f)
+
+	var outBuf bytes.Buffer
+	outputWriter = &outBuf
+
+	user := models.User{
+		Preferences: models.Preferences{
+			Location:      "london",
+			Unit:          "imperial",
+			UnitOverrides: map[string]string{"speed": "kn"},
+			Verbosity:     "verbose",
+			Forecast:      "day",
+		},
+	}
+	ShowWeather(context.Background(), user)
+	out := outBuf.String()
+
+	assert.Contains(t, out, "Temperature : 50°F")
+	assert.Contains(t, out, "Feels Like  : 46°F")
+	assert.Contains(t, out, "Wind        : 20 kn")
+	assert.Contains(t, out, "Pressure    : 29.92 inHg")
+	assert.Contains(t, out, "Precip      : 0.10 in")
+	assert.Contains(t, out, "Visibility  : 10 mi")
+
+	outBuf.Reset()
+	user.Preferences.Unit = "furlongs"
+	ShowWeather(context.Background(), user)
+	assert.Contains(t, outBuf.String(), `Error: unknown unit profile "furlongs"`)
+}
+
+// TestShowWeather_Week tests the "week" forecast path of ShowWeather: rows
+// carry the location's local dates, which differ from UTC near midnight.
+func TestShowWeather_Week(t *testing.T) {
+	fixClock(t, time.Date(2026, 10, 19, 23, 30, 0, 0, time.UTC))
+
+	// Setup fake provider with sample forecast data for two days
+	f := &fakeProvider{
+		forecastData: []WeatherData{
+			{Description: "Partly Cloudy", Temperature: 15},
+			{Description: "Rainy", Temperature: 12},
+		},
+	}
+	InitProvider(f)
+
+	var outBuf bytes.Buffer
+	outputWriter = &outBuf
+
+	// Prepare user preferences for a weekly forecast
+	user := models.User{
+		Preferences: models.Preferences{
+			Location:  "london",
+			Unit:      "celsius",
+			Verbosity: "brief",
+			Forecast:  "week",
+		},
+	}
+
+	ShowWeather(context.Background(), user)
+	out := outBuf.String()
+
+	assert.Contains(t, out, "Forecast for London (week)")
+	assert.Contains(t, out, "Tue 20 Oct: Partly Cloudy – 15°C")
+	assert.Contains(t, out, "Wed 21 Oct: Rainy – 12°C")
+}
+
+// TestShowOtherLocations tests the interactive ShowOtherLocations function.
+func TestShowOtherLocations(t *testing.T) {
+	// Setup fake provider with sample current data
+	f := &fakeProvider{
+		currentData: &WeatherData{Description: "Cloudy", Temperature: 18},
+	}
+	InitProvider(f)
+
+	var outBuf bytes.Buffer
+	outputWriter = &outBuf
+
+	// Simulate user entering "paris" as the location
+	reader := bufio.NewReader(strings.NewReader("paris\n"))
+	ShowOtherLocations(context.Background(), reader, models.Preferences{})
+	out := outBuf.String()
+
+	assert.Contains(t, out, "Location: Paris | Cloudy | 18°C")
+}
+
+// alertingProvider is a fakeProvider that also reports alerts.
+type alertingProvider struct {
+	fakeProvider
+	alerts []Alert
+	err    error
+}
+
+func (a *alertingProvider) Alerts(ctx context.Context, loc geo.Location) ([]Alert, error) {
+	return a.alerts, a.err
+}
+
+// TestShowWeather_Alerts checks that active alerts are printed above the report
+// and expired ones are dropped.
+func TestShowWeather_Alerts(t *testing.T) {
+	f := &alertingProvider{
+		fakeProvider: fakeProvider{
+			currentData: &WeatherData{Description: "Stormy", Temperature: 25},
+		},
+		alerts: []Alert{
+			{Severity: SeveritySevere, Category: "THUNDERSTORM", Description: "Severe Thunderstorm Warning"},
+			{Severity: SeverityMinor, Category: "WIND", Description: "Wind Advisory", End: time.Now().Add(-time.Hour)},
+		},
+	}
+	InitProvider(f)
+
+	var outBuf bytes.Buffer
+	outputWriter = &outBuf
+
+	user := models.User{
+		Preferences: models.Preferences{Location: "miami", Unit: "celsius", Verbosity: "brief", Forecast: "day"},
+	}
+
+	ShowWeather(context.Background(), user)
+	out := outBuf.String()
+
+	assert.Contains(t, out, "1 active weather alert(s)")
+	assert.Contains(t, out, "[SEVERE] THUNDERSTORM: Severe Thunderstorm Warning")
+	assert.NotContains(t, out, "Wind Advisory")
+	assert.Less(t, strings.Index(out, "SEVERE"), strings.Index(out, "Weather for Miami"))
+
+	alerts, err := FetchAlerts(context.Background(), geo.ParseLocation("miami"))
+	require.NoError(t, err)
+	assert.Len(t, alerts, 1)
+	fixClock(t, time.Now().Add(-2*time.Hour))
+	alerts, err = FetchAlerts(context.Background(), geo.ParseLocation("miami"))
+	require.NoError(t, err)
+	assert.Len(t, alerts, 2)
+
+	f.err = errors.New("alerts service down")
+	_, err = FetchAlerts(context.Background(), geo.ParseLocation("miami"))
+	assert.ErrorIs(t, err, f.err)
+	outBuf.Reset()
+	ShowWeather(context.Background(), user)
+	assert.Contains(t, outBuf.String(), "Weather for Miami")
+	assert.NotContains(t, outBuf.String(), "alert(s)")
+	InitProvider(&f.fakeProvider)
+	_, err = FetchAlerts(context.Background(), geo.ParseLocation("miami"))
+	assert.ErrorIs(t, err, ErrAlertsUnsupported)
+}
+
+// TestShowSavedLocations checks the compact table marks the default location.
+func TestShowSavedLocations(t *testing.T) {
+	f := &fakeProvider{
+		currentData: &WeatherData{Description: "Clear", Temperature: 10},
+	}
+	InitProvider(f)
+
+	var outBuf bytes.Buffer
+	outputWriter = &outBuf
+
+	ShowSavedLocations(context.Background(), []models.SavedLocation{
+		{Name: "Home", Location: "Oslo", Default: true},
+		{Name: "Cabin", Location: "Geilo"},
+	}, units.Imperial, "")
+	out := outBuf.String()
+
+	assert.Contains(t, out, "NAME")
+	assert.Regexp(t, `\*\s+Home\s+Oslo\s+Clear\s+50°F`, out)
+	assert.Regexp(t, `\n\s+Cabin\s+Geilo\s+Clear\s+50°F`, out)
+}
+
+// fakeIPLocator resolves "auto" to a fixed place.
+type fakeIPLocator struct{}
+
+func (fakeIPLocator) LocateIP(ctx context.Context) (geo.Location, error) {
+	return geo.Place{Name: "Lyon", Country: "France", Lat: 45.76, Lon: 4.84}.Location(), nil
+}
+
+// TestShowWeather_AutoLocation checks "auto" is resolved through the IP locator
+// and the provider receives exact coordinates.
+func TestShowWeather_AutoLocation(t *testing.T) {
+	f := &fakeProvider{currentData: &WeatherData{Description: "Fog", Temperature: 8}}
+	InitProvider(f)
+	geo.InitIPLocator(fakeIPLocator{})
+	defer geo.InitIPLocator(geo.NewIPAPILocator(10 * time.Second))
+
+	var outBuf bytes.Buffer
+	outputWriter = &outBuf
+
+	user := models.User{
+		Preferences: models.Preferences{Location: "auto", Unit: "celsius", Verbosity: "brief", Forecast: "day"},
+	}
+	ShowWeather(context.Background(), user)
+
+	assert.Contains(t, outBuf.String(), "Weather for Lyon, France")
+	assert.Equal(t, &models.Coordinates{Lat: 45.76, Lon: 4.84}, f.lastLocation.Coords)
+}
+
+// TestShowWeather_ProviderTimes checks provider-reported zone, observation
+// time and sun times take precedence over the gazetteer and the clock.
+func TestShowWeather_ProviderTimes(t *testing.T) {
+	fixClock(t, time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC))
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	f := &fakeProvider{
+		currentData: &WeatherData{
+			Description: "Clear",
+			Temperature: 17,
+			Time:        time.Date(2026, 10, 19, 21, 5, 0, 0, tokyo),
+			Sunrise:     time.Date(2026, 10, 19, 5, 48, 0, 0, tokyo),
+			Sunset:      time.Date(2026, 10, 19, 17, 4, 0, 0, tokyo),
+			TimeZone:    "Asia/Tokyo",
+		},
+	}
+	InitProvider(f)
+
+	var outBuf bytes.Buffer
+	outputWriter = &outBuf
+
+	user := models.User{
+		Preferences: models.Preferences{Location: "35.6895,139.6917", Unit: "celsius", Forecast: "day"},
+	}
+	ShowWeather(context.Background(), user)
+	out := outBuf.String()
+
+	assert.Contains(t, out, "Local time  : Mon 19 Oct 21:05 JST")
+	assert.Contains(t, out, "Sunrise     : 05:48")
+	assert.Contains(t, out, "Sunset      : 17:04")
+}
+
+// TestInitProvider_Concurrent checks the provider can be swapped while
+// other goroutines use it; run with -race.
+func TestInitProvider_Concurrent(t *testing.T) {
+	orig := Provider()
+	t.Cleanup(func() { InitProvider(orig) })
+	a, b := &fakeProvider{}, &fakeProvider{}
+	InitProvider(a)
+
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				if i%2 == 0 {
+					InitProvider(b)
+				} else {
+					p := Provider()
+					assert.True(t, p == a || p == b)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Same(t, b, Provider())
+}
@@ -0,0 +1,216 @@
+package weather
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/charmbracelet/x/term"
+
+	"weatherapp/internal/units"
+)
+
+// ForTerminal turns on TextRenderer's art and icons when out is a
+// terminal, and its ANSI colors unless NO_COLOR is set; a ChartRenderer
+// also gets the terminal's width. Other renderers and outputs are returned
+// unchanged.
+func ForTerminal(r Renderer, out io.Writer) Renderer {
+	f, ok := out.(*os.File)
+	if !ok || !term.IsTerminal(f.Fd()) {
+		return r
+	}
+	switch t := r.(type) {
+	case TextRenderer:
+		return t.forTerminal()
+	case ChartRenderer:
+		t.TextRenderer = t.TextRenderer.forTerminal()
+		if width, _, err := term.GetSize(f.Fd()); err == nil && width > 0 {
+			t.Width = width
+		}
+		return t
+	}
+	return r
+}
+
+func (t TextRenderer) forTerminal() TextRenderer {
+	t.Art = true
+	t.Color = os.Getenv("NO_COLOR") == ""
+	return t
+}
+
+// artWidth is the width of every picture in conditionArt.
+const artWidth = 13
+
+// conditionArt holds wttr.in-style five-line pictures, drawn only with
+// single-width characters so they line up in any terminal font.
+var conditionArt = map[Condition][5]string{
+	ConditionUnknown: {
+		"    .-.",
+		"     __)",
+		"    (",
+		"     `-’",
+		"      •",
+	},
+	ConditionClear: {
+		"    \\   /",
+		"     .-.",
+		"  ― (   ) ―",
+		"     `-’",
+		"    /   \\",
+	},
+	ConditionPartlyCloudy: {
+		"   \\  /",
+		" _ /\"\".-.",
+		"   \\_(   ).",
+		"   /(___(__)",
+		"",
+	},
+	ConditionCloudy: {
+		"",
+		"     .--.",
+		"  .-(    ).",
+		" (___.__)__)",
+		"",
+	},
+	ConditionOvercast: {
+		"",
+		"     .--.",
+		"  .-(    ).",
+		" (___.__)__)",
+		"",
+	},
+	ConditionFog: {
+		"",
+		" _ - _ - _ -",
+		"  _ - _ - _",
+		" _ - _ - _ -",
+		"",
+	},
+	ConditionDrizzle: {
+		"     .-.",
+		"    (   ).",
+		"   (___(__)",
+		"    ‘ ‘ ‘ ‘",
+		"   ‘ ‘ ‘ ‘",
+	},
+	ConditionRain: {
+		"     .-.",
+		"    (   ).",
+		"   (___(__)",
+		"    ‚‘‚‘‚‘‚‘",
+		"    ‚’‚’‚’‚’",
+	},
+	ConditionHeavyRain: {
+		"     .-.",
+		"    (   ).",
+		"   (___(__)",
+		"  ‚‘‚‘‚‘‚‘‚‘",
+		"  ‚’‚’‚’‚’‚’",
+	},
+	ConditionThunder: {
+		"     .-.",
+		"    (   ).",
+		"   (___(__)",
+		"    ‚‘ϟ‘‚ϟ‚‘",
+		"    ‚’‚’ϟ’‚’",
+	},
+	ConditionSleet: {
+		"     .-.",
+		"    (   ).",
+		"   (___(__)",
+		"    ‘ * ‘ *",
+		"   * ‘ * ‘",
+	},
+	ConditionSnow: {
+		"     .-.",
+		"    (   ).",
+		"   (___(__)",
+		"    *  *  *",
+		"   *  *  *",
+	},
+	ConditionHeavySnow: {
+		"     .-.",
+		"    (   ).",
+		"   (___(__)",
+		"   * * * * *",
+		"  * * * * *",
+	},
+	ConditionWind: {
+		"",
+		"  ~~~~~~",
+		" ~~~~~  ~~~",
+		"  ~~~~~~~",
+		"",
+	},
+}
+
+// conditionColors are the 256-color palette entries the art is drawn in.
+var conditionColors = map[Condition]int{
+	ConditionUnknown: 250, ConditionClear: 226, ConditionPartlyCloudy: 228,
+	ConditionCloudy: 250, ConditionOvercast: 244, ConditionFog: 251,
+	ConditionDrizzle: 111, ConditionRain: 111, ConditionHeavyRain: 27,
+	ConditionThunder: 228, ConditionSleet: 153, ConditionSnow: 255,
+	ConditionHeavySnow: 255, ConditionWind: 250,
+}
+
+var conditionIcons = map[Condition]string{
+	ConditionUnknown: "✨", ConditionClear: "☀️", ConditionPartlyCloudy: "⛅️",
+	ConditionCloudy: "☁️", ConditionOvercast: "☁️", ConditionFog: "🌫",
+	ConditionDrizzle: "🌦", ConditionRain: "🌧", ConditionHeavyRain: "🌧",
+	ConditionThunder: "⛈", ConditionSleet: "🌨", ConditionSnow: "❄️",
+	ConditionHeavySnow: "❄️", ConditionWind: "💨",
+}
+
+// Icon returns an emoji for the condition.
+func (c Condition) Icon() string {
+	if icon, ok := conditionIcons[c]; ok {
+		return icon
+	}
+	return conditionIcons[ConditionUnknown]
+}
+
+// temperatureColors picks a color by the first upper bound, in °C, that a
+// temperature is below; anything hotter is red.
+var temperatureColors = []struct {
+	below units.Temperature
+	color int
+}{
+	{-15, 21}, {-5, 27}, {0, 33}, {5, 39}, {10, 45},
+	{15, 118}, {20, 190}, {25, 220}, {30, 208}, {35, 202},
+}
+
+func temperatureColor(t units.Temperature) int {
+	for _, c := range temperatureColors {
+		if t < c.below {
+			return c.color
+		}
+	}
+	return 196
+}
+
+// paint wraps s in an ANSI 256-color escape when colors are on.
+func (t TextRenderer) paint(s string, color int) string {
+	if !t.Color || s == "" {
+		return s
+	}
+	return fmt.Sprintf("\x1b[38;5;%dm%s\x1b[0m", color, s)
+}
+
+// temperature formats v in sys, colored by how warm it is.
+func (t TextRenderer) temperature(v units.Temperature, sys units.System) string {
+	return t.paint(sys.FormatTemperature(v), temperatureColor(v))
+}
+
+// art returns the picture for c padded to artWidth, in the condition's
+// color.
+func (t TextRenderer) art(c Condition) [5]string {
+	pic, ok := conditionArt[c]
+	if !ok {
+		pic = conditionArt[ConditionUnknown]
+	}
+	var out [5]string
+	for i, line := range pic {
+		out[i] = t.paint(fmt.Sprintf("%-*s", artWidth, line), conditionColors[c])
+	}
+	return out
+}
@@ -0,0 +1,69 @@
+package weather
+
+import (
+	"sync"
+	"time"
+
+	"weatherapp/internal/metrics"
+)
+
+// locationCache remembers AccuWeather location lookups, the first step of
+// every AccuWeather call, so that repeat requests for a place cost one API
+// call instead of two. It is safe for concurrent use; a nil cache caches
+// nothing.
+type locationCache struct {
+	name string
+	ttl  time.Duration
+	max  int
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cachedLocation
+}
+
+type cachedLocation struct {
+	loc     accuLocation
+	expires time.Time
+}
+
+func newLocationCache(name string, ttl time.Duration, max int) *locationCache {
+	return &locationCache{name: name, ttl: ttl, max: max, now: time.Now, entries: map[string]cachedLocation{}}
+}
+
+// get returns the unexpired entry for key and records the hit or miss.
+func (c *locationCache) get(key string) (accuLocation, bool) {
+	if c == nil {
+		return accuLocation{}, false
+	}
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if ok && !c.now().Before(e.expires) {
+		delete(c.entries, key)
+		ok = false
+	}
+	c.mu.Unlock()
+	metrics.CacheLookup(c.name, ok)
+	return e.loc, ok
+}
+
+// put stores loc under key. When the cache is full, expired entries are
+// dropped, and if that frees nothing the cache starts over.
+func (c *locationCache) put(key string, loc accuLocation) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if len(c.entries) >= c.max {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= c.max {
+			clear(c.entries)
+		}
+	}
+	c.entries[key] = cachedLocation{loc: loc, expires: now.Add(c.ttl)}
+}
@@ -0,0 +1,256 @@
+package weather
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/message"
+
+	"weatherapp/internal/i18n"
+	"weatherapp/internal/units"
+)
+
+// DefaultChartWidth is the width ChartRenderer draws to when Width is not
+// set, as when writing to a file or pipe.
+const DefaultChartWidth = 80
+
+const (
+	// maxColumnWidth keeps a week's columns readable on wide terminals.
+	maxColumnWidth  = 10
+	temperatureRows = 8
+	barRows         = 4
+
+	// The precipitation and wind scales go at least this high, in mm and
+	// km/h, so a dry or calm week does not fill the chart.
+	minPrecipitationScale = 5
+	minWindScale          = 20
+
+	precipitationColor = 33
+	windColor          = 250
+)
+
+// ChartRenderer draws a forecast as Unicode charts fitted to Width
+// columns: each day's low-to-high temperature band, then precipitation
+// and wind speed bars, over a row of dates. Current conditions and
+// summaries are written by the embedded TextRenderer, whose Color also
+// colors the charts.
+type ChartRenderer struct {
+	TextRenderer
+	Width int
+}
+
+// Render writes the charts for a forecast and anything else as text.
+func (c ChartRenderer) Render(out io.Writer, r Report) error {
+	if len(r.Data) == 0 || r.Kind != KindForecast || r.Verbosity == VerbositySummary {
+		return c.TextRenderer.Render(out, r)
+	}
+	sys := r.Units
+	p := i18n.Printer(r.Language)
+	renderAlerts(out, p, r.Alerts, r.Zone)
+	renderForecastTitle(out, p, r)
+
+	charts := []chart{
+		temperatureChart(p.Sprintf("Temperature"), r.Data, sys),
+		barChart(p.Sprintf("Precipitation"), r.Data, minPrecipitationScale, precipitationColor,
+			func(d WeatherData) float64 { return float64(d.Precipitation) },
+			func(v float64) string { return sys.FormatPrecipitation(units.Precipitation(v)) }),
+		barChart(p.Sprintf("Wind"), r.Data, minWindScale, windColor,
+			func(d WeatherData) float64 { return float64(d.WindSpeed) },
+			func(v float64) string { return sys.FormatSpeed(units.Speed(v)) }),
+	}
+	gutter := 0
+	for _, ch := range charts {
+		gutter = max(gutter, len([]rune(ch.label(ch.top))), len([]rune(ch.label(ch.bottom))))
+	}
+	width := c.Width
+	if width <= 0 {
+		width = DefaultChartWidth
+	}
+	column := clampInt((width-gutter-2)/len(r.Data), 1, maxColumnWidth)
+
+	for _, ch := range charts {
+		fmt.Fprintln(out, ch.title)
+		for row := range ch.rows {
+			fmt.Fprintln(out, c.chartRow(ch, row, gutter, column))
+		}
+	}
+	fmt.Fprintf(out, "%*s └%s\n", gutter, "", strings.Repeat("─", column*len(r.Data)))
+	fmt.Fprintf(out, "%*s  %s\n", gutter, "", dateLabels(p, r.Data, column))
+	return nil
+}
+
+// chart is one panel: a range of values per day drawn as columns against
+// a vertical scale from bottom to top. In a band chart a day whose range
+// is a single value is still marked; in a bar chart it is left empty. A
+// day the provider has no forecast for has NaN for both and is left empty.
+type chart struct {
+	title       string
+	rows        int
+	band        bool
+	low, high   []float64
+	bottom, top float64
+	label       func(float64) string
+	color       func(float64) int
+}
+
+// temperatureChart draws each day's low to high temperature, colored by
+// how warm each row of the scale is. Unavailable days are left out of the
+// scale.
+func temperatureChart(title string, data []WeatherData, sys units.System) chart {
+	ch := chart{
+		title: title,
+		rows:  temperatureRows,
+		band:  true,
+		label: func(v float64) string { return sys.FormatTemperature(units.Temperature(v)) },
+		color: func(v float64) int { return temperatureColor(units.Temperature(v)) },
+	}
+	first := true
+	for _, d := range data {
+		if d.Unavailable {
+			ch.low, ch.high = append(ch.low, math.NaN()), append(ch.high, math.NaN())
+			continue
+		}
+		low, high := float64(d.MinTemp), float64(d.MaxTemp)
+		if low == 0 && high == 0 {
+			low, high = float64(d.Temperature), float64(d.Temperature)
+		}
+		if low > high {
+			low, high = high, low
+		}
+		ch.low, ch.high = append(ch.low, low), append(ch.high, high)
+		if first {
+			ch.bottom, ch.top, first = low, high, false
+		}
+		ch.bottom, ch.top = min(ch.bottom, low), max(ch.top, high)
+	}
+	ch.bottom, ch.top = math.Floor(ch.bottom), math.Ceil(ch.top)
+	if ch.top <= ch.bottom {
+		ch.top = ch.bottom + 1
+	}
+	return ch
+}
+
+// barChart draws value(d) for each day as a bar from zero, on a scale
+// reaching at least minScale.
+func barChart(title string, data []WeatherData, minScale float64, color int, value func(WeatherData) float64, label func(float64) string) chart {
+	ch := chart{
+		title: title,
+		rows:  barRows,
+		top:   minScale,
+		label: label,
+		color: func(float64) int { return color },
+	}
+	for _, d := range data {
+		if d.Unavailable {
+			ch.low, ch.high = append(ch.low, math.NaN()), append(ch.high, math.NaN())
+			continue
+		}
+		v := max(value(d), 0)
+		ch.low, ch.high = append(ch.low, 0), append(ch.high, v)
+		ch.top = max(ch.top, v)
+	}
+	return ch
+}
+
+// chartRow renders one row of ch, counting from the top, with the scale
+// labelled on the first and last rows.
+func (c ChartRenderer) chartRow(ch chart, row, gutter, column int) string {
+	step := (ch.top - ch.bottom) / float64(ch.rows)
+	cellLow := ch.bottom + float64(ch.rows-1-row)*step
+	cellHigh := cellLow + step
+
+	var b strings.Builder
+	switch row {
+	case 0:
+		fmt.Fprintf(&b, "%*s ┤", gutter, ch.label(ch.top))
+	case ch.rows - 1:
+		fmt.Fprintf(&b, "%*s ┤", gutter, ch.label(ch.bottom))
+	default:
+		fmt.Fprintf(&b, "%*s │", gutter, "")
+	}
+	bar := max(column-1, 1)
+	for i := range ch.low {
+		if math.IsNaN(ch.low[i]) {
+			b.WriteString(strings.Repeat(" ", column))
+			continue
+		}
+		cell := chartCell(ch.low[i], ch.high[i], cellLow, cellHigh)
+		if ch.band && ch.low[i] == ch.high[i] {
+			cell = ' '
+			if v := ch.low[i]; v >= cellLow && (v < cellHigh || row == 0) {
+				cell = '▬'
+			}
+		}
+		if cell == ' ' {
+			b.WriteString(strings.Repeat(" ", column))
+			continue
+		}
+		b.WriteString(c.paint(strings.Repeat(string(cell), bar), ch.color((cellLow+cellHigh)/2)))
+		b.WriteString(strings.Repeat(" ", column-bar))
+	}
+	return strings.TrimRight(b.String(), " ")
+}
+
+// lowerBlocks are the eighths used where a range ends inside a cell.
+var lowerBlocks = []rune(" ▁▂▃▄▅▆▇█")
+
+// chartCell picks the block for the part of low..high that falls in the
+// cell covering cellLow..cellHigh: eighths where the range ends in the
+// cell, upper blocks where it starts there.
+func chartCell(low, high, cellLow, cellHigh float64) rune {
+	if high <= cellLow || low >= cellHigh {
+		return ' '
+	}
+	step := cellHigh - cellLow
+	top, bottom := min(high, cellHigh), max(low, cellLow)
+	switch {
+	case bottom == cellLow:
+		return lowerBlocks[clampInt(int(math.Round((top-cellLow)/step*8)), 1, 8)]
+	case top == cellHigh:
+		switch f := (cellHigh - bottom) / step; {
+		case f >= 0.75:
+			return '█'
+		case f >= 0.375:
+			return '▀'
+		default:
+			return '▔'
+		}
+	}
+	return '▬'
+}
+
+// dateLabels writes each day's date under its column, as much of
+// "Mon 02" as fits and skipping days that would overlap.
+func dateLabels(p *message.Printer, data []WeatherData, column int) string {
+	layout := "2"
+	switch {
+	case column >= 7:
+		layout = "Mon 02"
+	case column >= 4:
+		layout = "Mon"
+	}
+	var b strings.Builder
+	written, next := 0, 0
+	for i, d := range data {
+		start := i * column
+		if start < next {
+			continue
+		}
+		label := "?"
+		if !d.Time.IsZero() {
+			label = i18n.FormatDate(p, d.Time, layout)
+		}
+		b.WriteString(strings.Repeat(" ", start-written))
+		b.WriteString(label)
+		written = start + utf8.RuneCountInString(label)
+		next = written + 1
+	}
+	return b.String()
+}
+
+func clampInt(v, lo, hi int) int {
+	return max(lo, min(v, hi))
+}
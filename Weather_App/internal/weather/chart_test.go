@@ -0,0 +1,130 @@
+package weather
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"weatherapp/internal/geo"
+	"weatherapp/internal/units"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// assertGolden compares got with testdata/name, or rewrites the file when
+// the tests are run with -update.
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		require.NoError(t, os.MkdirAll("testdata", 0o755))
+		require.NoError(t, os.WriteFile(path, got, 0o644))
+	}
+	want, err := os.ReadFile(path)
+	require.NoError(t, err, "run go test -update to create it")
+	assert.Equal(t, string(want), string(got))
+}
+
+// chartForecast returns a forecast report for London with days of
+// temperatures rising and falling, rain every third day and wind picking
+// up towards the end.
+func chartForecast(t *testing.T, days int, sys units.System, period string) Report {
+	fixClock(t, time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC))
+	data := make([]WeatherData, days)
+	for i := range data {
+		mid := 12 + 6*math.Sin(float64(i)/3)
+		data[i] = WeatherData{
+			Description: "Cloudy",
+			MinTemp:     units.Temperature(math.Round(mid - 4)),
+			MaxTemp:     units.Temperature(math.Round(mid + 3)),
+			WindSpeed:   units.Speed(8 + 2*i),
+		}
+		if i%3 == 1 {
+			data[i].Precipitation = units.Precipitation(2 * (i%4 + 1))
+		}
+	}
+	InitProvider(&fakeProvider{forecastData: data})
+	report, err := FetchReport(context.Background(), geo.ParseLocation("london"), days, "")
+	require.NoError(t, err)
+	report.Units, report.Period = sys, period
+	return report
+}
+
+// TestChartRenderer_Golden locks in the week and month charts at a few
+// terminal widths.
+func TestChartRenderer_Golden(t *testing.T) {
+	cases := map[string]struct {
+		days   int
+		period string
+		sys    units.System
+		width  int
+	}{
+		"chart_week_80.golden":       {7, "week", units.Metric, 80},
+		"chart_week_imperial.golden": {7, "week", units.Imperial, 0},
+		"chart_week_40.golden":       {7, "week", units.Metric, 40},
+		"chart_month_120.golden":     {30, "month", units.Metric, 120},
+		"chart_month_60.golden":      {30, "month", units.Metric, 60},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			require.NoError(t, ChartRenderer{Width: c.width}.Render(&out, chartForecast(t, c.days, c.sys, c.period)))
+			assertGolden(t, name, out.Bytes())
+		})
+	}
+}
+
+// TestChartRenderer checks colors, single-value days, and that current
+// conditions and summaries are left to the text renderer.
+func TestChartRenderer(t *testing.T) {
+	report := chartForecast(t, 3, units.Metric, "week")
+	for i := range report.Data {
+		report.Data[i].MinTemp, report.Data[i].MaxTemp = 0, 0
+		report.Data[i].Temperature = units.Temperature(10 + 10*i)
+	}
+	var out bytes.Buffer
+	require.NoError(t, ChartRenderer{}.Render(&out, report))
+	assert.Contains(t, out.String(), "30°C ┤                    ▬▬▬▬▬▬▬▬▬\n")
+	assert.Contains(t, out.String(), "10°C ┤▬▬▬▬▬▬▬▬▬\n")
+
+	out.Reset()
+	require.NoError(t, ChartRenderer{TextRenderer: TextRenderer{Color: true}}.Render(&out, report))
+	assert.Contains(t, out.String(), "\x1b[38;5;33m")
+
+	InitProvider(&fakeProvider{currentData: &WeatherData{Description: "Sunny", Temperature: 21}})
+	current, err := FetchReport(context.Background(), geo.ParseLocation("london"), 0, "")
+	require.NoError(t, err)
+	current.Units = units.Metric
+	out.Reset()
+	require.NoError(t, ChartRenderer{}.Render(&out, current))
+	assert.Contains(t, out.String(), "Description : Sunny")
+
+	var plain bytes.Buffer
+	assert.Equal(t, ChartRenderer{}, ForTerminal(ChartRenderer{}, &plain))
+}
+
+// TestChartRenderer_Unavailable checks days the provider has no forecast
+// for are left blank rather than charted as zero.
+func TestChartRenderer_Unavailable(t *testing.T) {
+	report := chartForecast(t, 4, units.Metric, "week")
+	for i := range report.Data {
+		report.Data[i].MinTemp, report.Data[i].MaxTemp = 10, 20
+		report.Data[i].Precipitation = 5
+	}
+	report.Data[3] = WeatherData{Time: report.Data[3].Time, Description: "Forecast unavailable", Unavailable: true}
+
+	var out bytes.Buffer
+	require.NoError(t, ChartRenderer{Width: 40}.Render(&out, report))
+	assert.Contains(t, out.String(), "   20°C ┤██████ ██████ ██████\n")
+	assert.Contains(t, out.String(), "   10°C ┤██████ ██████ ██████\n")
+	assert.NotContains(t, out.String(), " 0°C ┤")
+	assert.Contains(t, out.String(), " 5.0 mm ┤██████ ██████ ██████\n")
+}
@@ -0,0 +1,153 @@
+package weather
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Condition is a provider-independent classification of the weather.
+type Condition int
+
+const (
+	ConditionUnknown Condition = iota
+	ConditionClear
+	ConditionPartlyCloudy
+	ConditionCloudy
+	ConditionOvercast
+	ConditionFog
+	ConditionDrizzle
+	ConditionRain
+	ConditionHeavyRain
+	ConditionThunder
+	ConditionSleet
+	ConditionSnow
+	ConditionHeavySnow
+	ConditionWind
+)
+
+var conditionNames = []string{
+	ConditionUnknown:      "unknown",
+	ConditionClear:        "clear",
+	ConditionPartlyCloudy: "partly-cloudy",
+	ConditionCloudy:       "cloudy",
+	ConditionOvercast:     "overcast",
+	ConditionFog:          "fog",
+	ConditionDrizzle:      "drizzle",
+	ConditionRain:         "rain",
+	ConditionHeavyRain:    "heavy-rain",
+	ConditionThunder:      "thunder",
+	ConditionSleet:        "sleet",
+	ConditionSnow:         "snow",
+	ConditionHeavySnow:    "heavy-snow",
+	ConditionWind:         "wind",
+}
+
+// String returns the lower-case, hyphenated name of the condition.
+func (c Condition) String() string {
+	if c < 0 || int(c) >= len(conditionNames) {
+		return conditionNames[ConditionUnknown]
+	}
+	return conditionNames[c]
+}
+
+// Conditions lists every condition name, for help text and validation.
+var Conditions = conditionNames
+
+// ParseCondition reads a condition name such as "partly-cloudy"; spaces
+// and underscores may stand in for the hyphen.
+func ParseCondition(s string) (Condition, error) {
+	name := strings.NewReplacer(" ", "-", "_", "-").Replace(strings.ToLower(strings.TrimSpace(s)))
+	for i, n := range conditionNames {
+		if n == name {
+			return Condition(i), nil
+		}
+	}
+	return ConditionUnknown, fmt.Errorf("unknown condition %q (want one of %s)", s, strings.Join(conditionNames, ", "))
+}
+
+// accuWeatherIcons maps AccuWeather icon numbers to conditions; see
+// https://developer.accuweather.com/weather-icons. Night icons (33-44)
+// map like their daytime counterparts.
+var accuWeatherIcons = map[int]Condition{
+	1: ConditionClear, 2: ConditionClear, 3: ConditionPartlyCloudy, 4: ConditionPartlyCloudy,
+	5: ConditionFog, 6: ConditionCloudy, 7: ConditionCloudy, 8: ConditionOvercast,
+	11: ConditionFog, 12: ConditionRain, 13: ConditionRain, 14: ConditionRain,
+	15: ConditionThunder, 16: ConditionThunder, 17: ConditionThunder, 18: ConditionRain,
+	19: ConditionSnow, 20: ConditionSnow, 21: ConditionSnow, 22: ConditionHeavySnow,
+	23: ConditionSnow, 24: ConditionSleet, 25: ConditionSleet, 26: ConditionSleet,
+	29: ConditionSleet, 30: ConditionClear, 31: ConditionClear, 32: ConditionWind,
+	33: ConditionClear, 34: ConditionClear, 35: ConditionPartlyCloudy, 36: ConditionPartlyCloudy,
+	37: ConditionFog, 38: ConditionCloudy, 39: ConditionRain, 40: ConditionRain,
+	41: ConditionThunder, 42: ConditionThunder, 43: ConditionSnow, 44: ConditionSnow,
+}
+
+// weatherstackCodes maps Weatherstack weather codes to conditions; see
+// https://weatherstack.com/site_resources/weatherstack-weather-condition-codes.zip.
+var weatherstackCodes = map[int]Condition{
+	113: ConditionClear, 116: ConditionPartlyCloudy, 119: ConditionCloudy, 122: ConditionOvercast,
+	143: ConditionFog, 248: ConditionFog, 260: ConditionFog,
+	176: ConditionRain, 179: ConditionSnow, 182: ConditionSleet, 185: ConditionSleet,
+	200: ConditionThunder, 227: ConditionSnow, 230: ConditionHeavySnow,
+	263: ConditionDrizzle, 266: ConditionDrizzle, 281: ConditionSleet, 284: ConditionSleet,
+	293: ConditionRain, 296: ConditionRain, 299: ConditionRain, 302: ConditionRain,
+	305: ConditionHeavyRain, 308: ConditionHeavyRain, 311: ConditionSleet, 314: ConditionSleet,
+	317: ConditionSleet, 320: ConditionSleet, 323: ConditionSnow, 326: ConditionSnow,
+	329: ConditionSnow, 332: ConditionSnow, 335: ConditionHeavySnow, 338: ConditionHeavySnow,
+	350: ConditionSleet, 353: ConditionRain, 356: ConditionHeavyRain, 359: ConditionHeavyRain,
+	362: ConditionSleet, 365: ConditionSleet, 368: ConditionSnow, 371: ConditionHeavySnow,
+	374: ConditionSleet, 377: ConditionSleet, 386: ConditionThunder, 389: ConditionThunder,
+	392: ConditionThunder, 395: ConditionThunder,
+}
+
+// AccuWeatherCondition classifies an AccuWeather icon number, falling
+// back to the icon phrase for numbers it does not know.
+func AccuWeatherCondition(icon int, text string) Condition {
+	if c, ok := accuWeatherIcons[icon]; ok {
+		return c
+	}
+	return ConditionFromText(text)
+}
+
+// WeatherstackCondition classifies a Weatherstack weather code, falling
+// back to the description for codes it does not know.
+func WeatherstackCondition(code int, text string) Condition {
+	if c, ok := weatherstackCodes[code]; ok {
+		return c
+	}
+	return ConditionFromText(text)
+}
+
+// conditionKeywords is checked in order, so more specific phrases such as
+// "heavy rain" and "thunder" win over "rain" or "cloud".
+var conditionKeywords = []struct {
+	words     []string
+	condition Condition
+}{
+	{[]string{"thunder", "t-storm", "storm"}, ConditionThunder},
+	{[]string{"sleet", "freezing", "ice", "hail"}, ConditionSleet},
+	{[]string{"blizzard", "heavy snow"}, ConditionHeavySnow},
+	{[]string{"snow", "flurr"}, ConditionSnow},
+	{[]string{"heavy rain", "torrential", "downpour"}, ConditionHeavyRain},
+	{[]string{"drizzle"}, ConditionDrizzle},
+	{[]string{"rain", "shower"}, ConditionRain},
+	{[]string{"fog", "mist", "haz"}, ConditionFog},
+	{[]string{"overcast", "dreary"}, ConditionOvercast},
+	{[]string{"partly", "intermittent", "few clouds"}, ConditionPartlyCloudy},
+	{[]string{"cloud"}, ConditionCloudy},
+	{[]string{"sunny", "clear", "fair", "hot", "cold"}, ConditionClear},
+	{[]string{"wind", "breez"}, ConditionWind},
+}
+
+// ConditionFromText classifies a free-text description by keyword, for
+// providers or forecasts without a condition code.
+func ConditionFromText(text string) Condition {
+	text = strings.ToLower(text)
+	for _, k := range conditionKeywords {
+		for _, w := range k.words {
+			if strings.Contains(text, w) {
+				return k.condition
+			}
+		}
+	}
+	return ConditionUnknown
+}
@@ -0,0 +1,48 @@
+package weather
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestProviderConditions checks vendor codes map through the tables and
+// unknown codes fall back to the text.
+func TestProviderConditions(t *testing.T) {
+	assert.Equal(t, ConditionPartlyCloudy, AccuWeatherCondition(4, "Intermittent clouds"))
+	assert.Equal(t, ConditionThunder, AccuWeatherCondition(42, ""))
+	assert.Equal(t, ConditionSnow, AccuWeatherCondition(0, "Flurries"))
+	assert.Equal(t, ConditionRain, WeatherstackCondition(176, "Patchy rain possible"))
+	assert.Equal(t, ConditionHeavySnow, WeatherstackCondition(338, ""))
+	assert.Equal(t, ConditionFog, WeatherstackCondition(0, "Mist"))
+}
+
+// TestConditionFromText covers vendor phrasings and the keyword order.
+func TestConditionFromText(t *testing.T) {
+	for text, want := range map[string]Condition{
+		"Partly Cloudy":                       ConditionPartlyCloudy,
+		"Intermittent clouds":                 ConditionPartlyCloudy,
+		"Mostly cloudy":                       ConditionCloudy,
+		"Overcast":                            ConditionOvercast,
+		"Sunny":                               ConditionClear,
+		"Mostly Cloudy w/ T-Storms":           ConditionThunder,
+		"Moderate or heavy rain shower":       ConditionHeavyRain,
+		"Light drizzle":                       ConditionDrizzle,
+		"Light freezing rain":                 ConditionSleet,
+		"Moderate or heavy snow with thunder": ConditionThunder,
+		"Blizzard":                            ConditionHeavySnow,
+		"Windy":                               ConditionWind,
+		"Forecast unavailable":                ConditionUnknown,
+	} {
+		assert.Equal(t, want, ConditionFromText(text), text)
+	}
+	assert.Equal(t, "partly-cloudy", ConditionPartlyCloudy.String())
+	for _, name := range []string{"partly-cloudy", "Partly Cloudy", "partly_cloudy"} {
+		c, err := ParseCondition(name)
+		assert.NoError(t, err, name)
+		assert.Equal(t, ConditionPartlyCloudy, c, name)
+	}
+	_, err := ParseCondition("sunny")
+	assert.Error(t, err)
+	assert.Equal(t, "unknown", Condition(99).String())
+}
@@ -0,0 +1,78 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"weatherapp/internal/metrics"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// getJSON fetches rawURL and decodes its JSON body into v. provider and
+// endpoint name the call in its span, the log and metrics, which record its
+// latency and outcome, the log under ctx's correlation ID; the URL is left
+// out as it holds the API key.
+func getJSON(ctx context.Context, client *http.Client, provider, endpoint, rawURL string, v any) error {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "GET "+provider+" "+endpoint,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("weather.provider", provider),
+			attribute.String("weather.endpoint", endpoint),
+			semconv.HTTPRequestMethodGet,
+		))
+	start := time.Now()
+	status, err := fetchJSON(ctx, client, rawURL, v)
+	elapsed := time.Since(start)
+	metrics.ProviderRequest(provider, endpoint, status, elapsed)
+	if status != 0 {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+	}
+	attrs := []any{"provider", provider, "endpoint", endpoint, "status", status, "duration", elapsed}
+	if err != nil {
+		err = fmt.Errorf("%s %s: %w", provider, endpoint, err)
+		slog.WarnContext(ctx, "provider call failed", append(attrs, "error", err)...)
+	} else {
+		slog.DebugContext(ctx, "provider call", attrs...)
+	}
+	endSpan(span, err)
+	return err
+}
+
+// fetchJSON does the request for getJSON with client, or the default client
+// if it is nil, returning the response status, or 0 if there was no
+// response.
+func fetchJSON(ctx context.Context, client *http.Client, rawURL string, v any) (int, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	resp, err := client.Do(req)
+	if err != nil {
+		// The url.Error would repeat the URL, API key and all.
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("request failed: %s", resp.Status)
+	}
+	return resp.StatusCode, json.NewDecoder(resp.Body).Decode(v)
+}
@@ -1,17 +1,125 @@
-package weather
-
-// WeatherData holds common weather fields.
-type WeatherData struct {
-	Description string
-	Temperature float64
-	FeelsLike   float64
-	Humidity    float64
-	WindSpeed   float64
-	WindDir     string
-}
-
-// WeatherProvider defines the interface for any weather source.
-type WeatherProvider interface {
-	Current(location string) (*WeatherData, error)
-	Forecast(location string, days int) ([]WeatherData, error)
-}
+package weather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"weatherapp/internal/config"
+	"weatherapp/internal/geo"
+	"weatherapp/internal/i18n"
+	"weatherapp/internal/units"
+)
+
+// WeatherData holds common weather fields. Quantities are in the units
+// package's base units; Humidity and PrecipProbability are percentages.
+// Pressure, Precipitation and Visibility are zero when not reported.
+// Description is the provider's own wording, kept verbatim; Condition is
+// its provider-independent classification, for comparisons and icons.
+type WeatherData struct {
+	Description       string
+	Condition         Condition
+	Temperature       units.Temperature
+	FeelsLike         units.Temperature
+	Humidity          float64
+	WindSpeed         units.Speed
+	WindDir           string
+	MinTemp           units.Temperature
+	MaxTemp           units.Temperature
+	PrecipProbability float64
+	Pressure          units.Pressure
+	Precipitation     units.Precipitation
+	Visibility        units.Distance
+
+	// Time is the observation time for current conditions or the date of
+	// a forecast day; zero when the provider does not say.
+	Time     time.Time
+	Sunrise  time.Time
+	Sunset   time.Time
+	TimeZone string
+
+	// Unavailable marks a forecast day beyond those the provider returns,
+	// kept so the forecast has the days asked for. Only its Time and
+	// Description are set.
+	Unavailable bool
+}
+
+// WeatherProvider defines the interface for any weather source. The
+// context carries the operation's deadline and correlation ID.
+type WeatherProvider interface {
+	Current(ctx context.Context, loc geo.Location) (*WeatherData, error)
+	Forecast(ctx context.Context, loc geo.Location, days int) ([]WeatherData, error)
+}
+
+// NewProvider creates the provider cfg.WeatherProvider names.
+func NewProvider(cfg *config.Config) (WeatherProvider, error) {
+	switch cfg.WeatherProvider {
+	case "accuweather":
+		return NewAccuWeatherProvider(cfg), nil
+	case "weatherstack":
+		return NewWeatherstackProvider(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown weather provider %q", cfg.WeatherProvider)
+	}
+}
+
+// Configure makes the provider cfg names active, along with its own
+// geocoder if it has one and Open-Meteo's otherwise, and the IP locator
+// used for "auto" locations. The provider and geocoder are published
+// together, so no caller sees one without the other.
+func Configure(cfg *config.Config) error {
+	p, err := NewProvider(cfg)
+	if err != nil {
+		return err
+	}
+	if _, ok := p.(AlertProvider); !ok {
+		slog.Info("weather alerts are not available from this provider", "provider", cfg.WeatherProvider)
+	}
+	g, ok := p.(geo.Geocoder)
+	if !ok {
+		g = geo.NewOpenMeteoGeocoder(cfg.Timeouts.Provider.Duration)
+	}
+	active.Store(&backend{p: p, g: g})
+	geo.InitGeocoder(activeGeocoder{})
+	geo.InitIPLocator(geo.NewIPAPILocator(cfg.Timeouts.Provider.Duration))
+	return nil
+}
+
+// Localizer is implemented by providers that can describe the weather in
+// other languages. WithLanguage returns a copy of the provider asking for
+// descriptions in lang, a two-letter code such as "de".
+type Localizer interface {
+	WithLanguage(lang string) WeatherProvider
+}
+
+// CredentialChecker is implemented by providers that can tell whether they
+// are configured to call their API without making a request, which would
+// spend quota.
+type CredentialChecker interface {
+	CheckCredentials() error
+}
+
+// CheckCredentials checks the active provider has credentials, if it can
+// tell.
+func CheckCredentials() error {
+	provider := Provider()
+	if provider == nil {
+		return errors.New("no weather provider configured")
+	}
+	if c, ok := provider.(CredentialChecker); ok {
+		return c.CheckCredentials()
+	}
+	return nil
+}
+
+// providerIn returns the active provider set up for lang, or the provider
+// itself if it cannot localize its descriptions.
+func providerIn(lang string) WeatherProvider {
+	provider := Provider()
+	if l, ok := provider.(Localizer); ok {
+		return l.WithLanguage(i18n.Code(lang))
+	}
+	return provider
+}
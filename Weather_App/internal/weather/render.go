@@ -0,0 +1,334 @@
+package weather
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"weatherapp/internal/geo"
+	"weatherapp/internal/i18n"
+	"weatherapp/internal/units"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Report kinds.
+const (
+	KindCurrent  = "current"
+	KindForecast = "forecast"
+)
+
+// VerbositySummary asks the text renderer for a single line per report,
+// as used by ShowOtherLocations.
+const VerbositySummary = "summary"
+
+// Formats lists the output formats accepted by NewRenderer.
+var Formats = []string{"text", "chart", "json", "yaml", "csv"}
+
+// Report is everything needed to render one weather view. Data holds one
+// entry for current conditions or one per day for a forecast, with Time,
+// TimeZone, Sunrise and Sunset filled in when the provider left them out.
+// Language is the one labels are written in; empty means i18n's default.
+type Report struct {
+	Location  geo.Location
+	Kind      string
+	Period    string
+	Verbosity string
+	Language  string
+	Units     units.System
+	Zone      *time.Location
+	Generated time.Time
+	Data      []WeatherData
+	Alerts    []Alert
+}
+
+// NewReport builds a Report for data fetched for loc, working out the
+// local zone and filling in missing dates and sun times.
+func NewReport(loc geo.Location, kind string, data []WeatherData, alerts []Alert) Report {
+	zoneName := ""
+	if len(data) > 0 {
+		zoneName = data[0].TimeZone
+	}
+	zone := geo.Zone(loc, zoneName)
+	generated := now().In(zone)
+	today := time.Date(generated.Year(), generated.Month(), generated.Day(), 0, 0, 0, 0, zone)
+
+	filled := make([]WeatherData, len(data))
+	for i, d := range data {
+		switch {
+		case !d.Time.IsZero():
+		case kind == KindCurrent:
+			d.Time = generated
+		default:
+			d.Time = today.AddDate(0, 0, i)
+		}
+		d.Time = d.Time.In(zone)
+		if d.TimeZone == "" {
+			d.TimeZone = zone.String()
+		}
+		if d.Condition == ConditionUnknown {
+			d.Condition = ConditionFromText(d.Description)
+		}
+		if rise, set, ok := sunTimes(loc, &d, d.Time); ok {
+			d.Sunrise, d.Sunset = rise.In(zone), set.In(zone)
+		}
+		filled[i] = d
+	}
+	return Report{
+		Location:  loc,
+		Kind:      kind,
+		Zone:      zone,
+		Generated: generated,
+		Data:      filled,
+		Alerts:    alerts,
+	}
+}
+
+// Renderer writes a Report in some output format.
+type Renderer interface {
+	Render(out io.Writer, r Report) error
+}
+
+// NewRenderer returns the Renderer for a format name; empty means text.
+func NewRenderer(format string) (Renderer, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "text":
+		return TextRenderer{}, nil
+	case "chart":
+		return ChartRenderer{}, nil
+	case "json":
+		return JSONRenderer{}, nil
+	case "yaml", "yml":
+		return YAMLRenderer{}, nil
+	case "csv":
+		return CSVRenderer{}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q (want one of %s)", format, strings.Join(Formats, ", "))
+}
+
+// TextRenderer writes the human-readable reports shown in the CLI. Art
+// adds weather pictures and condition icons and Color adds ANSI colors;
+// ForTerminal turns them on for terminals.
+type TextRenderer struct {
+	Art   bool
+	Color bool
+}
+
+// Render writes a detailed view, a forecast table or a one-line summary.
+func (t TextRenderer) Render(out io.Writer, r Report) error {
+	switch {
+	case len(r.Data) == 0:
+		return fmt.Errorf("no weather data for %s", r.Location)
+	case r.Verbosity == VerbositySummary:
+		d := r.Data[0]
+		_, err := fmt.Fprintln(out, i18n.Printer(r.Language).Sprintf("Location: %s | %s | %s",
+			i18n.Title(r.Language, r.Location.String()), t.describe(d), t.temperature(d.Temperature, r.Units)))
+		return err
+	case r.Kind == KindCurrent:
+		t.renderDetailed(out, r)
+	default:
+		t.renderForecast(out, r)
+	}
+	return nil
+}
+
+// describe is d's description, led by its condition icon when art is on.
+func (t TextRenderer) describe(d WeatherData) string {
+	if t.Art {
+		return d.Condition.Icon() + " " + d.Description
+	}
+	return d.Description
+}
+
+// JSONRenderer writes a Report as an indented JSON document.
+type JSONRenderer struct{}
+
+// Render writes r as JSON.
+func (JSONRenderer) Render(out io.Writer, r Report) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(newReportDoc(r))
+}
+
+// YAMLRenderer writes a Report as a YAML document.
+type YAMLRenderer struct{}
+
+// Render writes r as YAML.
+func (YAMLRenderer) Render(out io.Writer, r Report) error {
+	enc := yaml.NewEncoder(out)
+	enc.SetIndent(2)
+	if err := enc.Encode(newReportDoc(r)); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// CSVRenderer writes one row per Data entry, with the location and units
+// repeated on every row so each stands alone. Alerts are not included.
+type CSVRenderer struct{}
+
+// csvHeader names the CSVRenderer columns.
+var csvHeader = []string{
+	"location", "latitude", "longitude", "time_zone", "kind", "time",
+	"condition", "description", "temperature", "feels_like", "min_temp", "max_temp",
+	"humidity", "wind_speed", "wind_dir", "precip_probability",
+	"pressure", "precipitation", "visibility", "sunrise", "sunset",
+	"temperature_unit", "speed_unit", "pressure_unit", "precipitation_unit", "distance_unit",
+}
+
+// Render writes r as CSV with a header row.
+func (CSVRenderer) Render(out io.Writer, r Report) error {
+	doc := newReportDoc(r)
+	w := csv.NewWriter(out)
+	if err := w.Write(csvHeader); err != nil {
+		return err
+	}
+	lat, lon := "", ""
+	if doc.Location.Latitude != nil {
+		lat = formatNumber(*doc.Location.Latitude)
+		lon = formatNumber(*doc.Location.Longitude)
+	}
+	for _, d := range doc.Data {
+		err := w.Write([]string{
+			doc.Location.Name, lat, lon, doc.Location.TimeZone, doc.Kind, d.Time,
+			d.Condition, d.Description, formatNumber(d.Temperature), formatNumber(d.FeelsLike),
+			formatNumber(d.MinTemp), formatNumber(d.MaxTemp),
+			formatNumber(d.Humidity), formatNumber(d.WindSpeed), d.WindDir, formatNumber(d.PrecipProbability),
+			formatNumber(d.Pressure), formatNumber(d.Precipitation), formatNumber(d.Visibility),
+			d.Sunrise, d.Sunset,
+			doc.Units.Temperature, doc.Units.Speed, doc.Units.Pressure, doc.Units.Precipitation, doc.Units.Distance,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// reportDoc is the structured form of a Report shared by the JSON, YAML
+// and CSV renderers. Quantities are converted to the Report's units.
+type reportDoc struct {
+	Location  locationDoc `json:"location" yaml:"location"`
+	Kind      string      `json:"kind" yaml:"kind"`
+	Period    string      `json:"period,omitempty" yaml:"period,omitempty"`
+	Generated string      `json:"generated" yaml:"generated"`
+	Units     unitsDoc    `json:"units" yaml:"units"`
+	Alerts    []alertDoc  `json:"alerts,omitempty" yaml:"alerts,omitempty"`
+	Data      []dataDoc   `json:"data" yaml:"data"`
+}
+
+type locationDoc struct {
+	Name      string   `json:"name" yaml:"name"`
+	Latitude  *float64 `json:"latitude,omitempty" yaml:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty" yaml:"longitude,omitempty"`
+	TimeZone  string   `json:"time_zone" yaml:"time_zone"`
+}
+
+type unitsDoc struct {
+	Temperature   string `json:"temperature" yaml:"temperature"`
+	Speed         string `json:"speed" yaml:"speed"`
+	Pressure      string `json:"pressure" yaml:"pressure"`
+	Precipitation string `json:"precipitation" yaml:"precipitation"`
+	Distance      string `json:"distance" yaml:"distance"`
+}
+
+type alertDoc struct {
+	Severity    string `json:"severity" yaml:"severity"`
+	Category    string `json:"category" yaml:"category"`
+	Start       string `json:"start,omitempty" yaml:"start,omitempty"`
+	End         string `json:"end,omitempty" yaml:"end,omitempty"`
+	Description string `json:"description" yaml:"description"`
+}
+
+type dataDoc struct {
+	Time              string  `json:"time" yaml:"time"`
+	Condition         string  `json:"condition" yaml:"condition"`
+	Description       string  `json:"description" yaml:"description"`
+	Temperature       float64 `json:"temperature" yaml:"temperature"`
+	FeelsLike         float64 `json:"feels_like" yaml:"feels_like"`
+	MinTemp           float64 `json:"min_temp" yaml:"min_temp"`
+	MaxTemp           float64 `json:"max_temp" yaml:"max_temp"`
+	Humidity          float64 `json:"humidity" yaml:"humidity"`
+	WindSpeed         float64 `json:"wind_speed" yaml:"wind_speed"`
+	WindDir           string  `json:"wind_dir" yaml:"wind_dir"`
+	PrecipProbability float64 `json:"precip_probability" yaml:"precip_probability"`
+	Pressure          float64 `json:"pressure" yaml:"pressure"`
+	Precipitation     float64 `json:"precipitation" yaml:"precipitation"`
+	Visibility        float64 `json:"visibility" yaml:"visibility"`
+	Sunrise           string  `json:"sunrise,omitempty" yaml:"sunrise,omitempty"`
+	Sunset            string  `json:"sunset,omitempty" yaml:"sunset,omitempty"`
+}
+
+func newReportDoc(r Report) reportDoc {
+	sys := r.Units
+	doc := reportDoc{
+		Location:  locationDoc{Name: r.Location.String(), TimeZone: r.Zone.String()},
+		Kind:      r.Kind,
+		Period:    r.Period,
+		Generated: formatTime(r.Generated),
+		Units: unitsDoc{
+			Temperature:   string(sys.Temperature),
+			Speed:         string(sys.Speed),
+			Pressure:      string(sys.Pressure),
+			Precipitation: string(sys.Precipitation),
+			Distance:      string(sys.Distance),
+		},
+		Data: []dataDoc{},
+	}
+	if lat, lon, ok := geo.Position(r.Location); ok {
+		doc.Location.Latitude, doc.Location.Longitude = &lat, &lon
+	}
+	for _, a := range r.Alerts {
+		doc.Alerts = append(doc.Alerts, alertDoc{
+			Severity:    a.Severity.String(),
+			Category:    a.Category,
+			Start:       formatTime(a.Start),
+			End:         formatTime(a.End),
+			Description: a.Description,
+		})
+	}
+	for _, d := range r.Data {
+		doc.Data = append(doc.Data, dataDoc{
+			Time:              formatTime(d.Time),
+			Condition:         d.Condition.String(),
+			Description:       d.Description,
+			Temperature:       round(d.Temperature.In(sys.Temperature)),
+			FeelsLike:         round(d.FeelsLike.In(sys.Temperature)),
+			MinTemp:           round(d.MinTemp.In(sys.Temperature)),
+			MaxTemp:           round(d.MaxTemp.In(sys.Temperature)),
+			Humidity:          d.Humidity,
+			WindSpeed:         round(d.WindSpeed.In(sys.Speed)),
+			WindDir:           d.WindDir,
+			PrecipProbability: d.PrecipProbability,
+			Pressure:          round(d.Pressure.In(sys.Pressure)),
+			Precipitation:     round(d.Precipitation.In(sys.Precipitation)),
+			Visibility:        round(d.Visibility.In(sys.Distance)),
+			Sunrise:           formatTime(d.Sunrise),
+			Sunset:            formatTime(d.Sunset),
+		})
+	}
+	return doc
+}
+
+// formatTime renders t as RFC 3339, or "" for the zero time.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// round trims conversion noise such as 68.00000000000001.
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,162 @@
+package weather
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"weatherapp/internal/geo"
+	"weatherapp/internal/units"
+	"weatherapp/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// reportUser returns a User whose forecast the render tests share.
+func reportUser(output string) models.User {
+	return models.User{
+		Preferences: models.Preferences{
+			Location: "london",
+			Unit:     "imperial",
+			Forecast: "week",
+			Output:   output,
+		},
+	}
+}
+
+func initReportProvider(t *testing.T) {
+	fixClock(t, time.Date(2026, 10, 19, 23, 30, 0, 0, time.UTC))
+	InitProvider(&fakeProvider{
+		forecastData: []WeatherData{
+			{Description: "Partly Cloudy", Temperature: 20, MinTemp: 12, MaxTemp: 20, WindSpeed: 16.09344, Humidity: 60},
+			{Description: "Rainy", Temperature: 10, Precipitation: 25.4, PrecipProbability: 90},
+		},
+	})
+}
+
+// TestJSONRenderer checks the JSON document carries converted values,
+// local dates, location and unit metadata.
+func TestJSONRenderer(t *testing.T) {
+	initReportProvider(t)
+	var out bytes.Buffer
+	require.NoError(t, WriteReport(context.Background(), &out, reportUser("json"), JSONRenderer{}))
+
+	var doc reportDoc
+	require.NoError(t, json.Unmarshal(out.Bytes(), &doc))
+	assert.Equal(t, "london", doc.Location.Name)
+	assert.Equal(t, "Europe/London", doc.Location.TimeZone)
+	require.NotNil(t, doc.Location.Latitude)
+	assert.InDelta(t, 51.5, *doc.Location.Latitude, 0.1)
+	assert.Equal(t, KindForecast, doc.Kind)
+	assert.Equal(t, "week", doc.Period)
+	assert.Equal(t, unitsDoc{"fahrenheit", "mph", "inHg", "in", "mi"}, doc.Units)
+
+	require.Len(t, doc.Data, 2)
+	assert.Equal(t, "2026-10-20T00:00:00+01:00", doc.Data[0].Time)
+	assert.Equal(t, 68.0, doc.Data[0].Temperature)
+	assert.Equal(t, 53.6, doc.Data[0].MinTemp)
+	assert.Equal(t, 10.0, doc.Data[0].WindSpeed)
+	assert.Equal(t, "partly-cloudy", doc.Data[0].Condition)
+	assert.Equal(t, "Partly Cloudy", doc.Data[0].Description)
+	assert.NotEmpty(t, doc.Data[0].Sunrise)
+	assert.Equal(t, "2026-10-21T00:00:00+01:00", doc.Data[1].Time)
+	assert.Equal(t, 1.0, doc.Data[1].Precipitation)
+}
+
+// TestYAMLRenderer checks the YAML document uses the same field names.
+func TestYAMLRenderer(t *testing.T) {
+	initReportProvider(t)
+	var out bytes.Buffer
+	require.NoError(t, WriteReport(context.Background(), &out, reportUser("yaml"), YAMLRenderer{}))
+
+	var doc map[string]any
+	require.NoError(t, yaml.Unmarshal(out.Bytes(), &doc))
+	assert.Equal(t, "forecast", doc["kind"])
+	assert.Equal(t, "mph", doc["units"].(map[string]any)["speed"])
+	data := doc["data"].([]any)
+	require.Len(t, data, 2)
+	assert.Equal(t, "Rainy", data[1].(map[string]any)["description"])
+	assert.Equal(t, "rain", data[1].(map[string]any)["condition"])
+	assert.Equal(t, 90, data[1].(map[string]any)["precip_probability"])
+}
+
+// TestCSVRenderer checks there is a header and one self-contained row per day.
+func TestCSVRenderer(t *testing.T) {
+	initReportProvider(t)
+	var out bytes.Buffer
+	require.NoError(t, WriteReport(context.Background(), &out, reportUser("csv"), CSVRenderer{}))
+
+	rows, err := csv.NewReader(&out).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, csvHeader, rows[0])
+	row := map[string]string{}
+	for i, col := range rows[0] {
+		row[col] = rows[2][i]
+	}
+	assert.Equal(t, "london", row["location"])
+	assert.Equal(t, "Rainy", row["description"])
+	assert.Equal(t, "rain", row["condition"])
+	assert.Equal(t, "50", row["temperature"])
+	assert.Equal(t, "fahrenheit", row["temperature_unit"])
+	assert.Equal(t, "2026-10-21T00:00:00+01:00", row["time"])
+}
+
+// TestShowWeather_OutputPreference checks ShowWeather honours the User's
+// output format and rejects unknown ones.
+func TestShowWeather_OutputPreference(t *testing.T) {
+	initReportProvider(t)
+	var outBuf bytes.Buffer
+	outputWriter = &outBuf
+
+	ShowWeather(context.Background(), reportUser("json"))
+	assert.True(t, json.Valid(outBuf.Bytes()), outBuf.String())
+
+	outBuf.Reset()
+	ShowWeather(context.Background(), reportUser("xml"))
+	assert.Equal(t, "Error: unknown output format \"xml\" (want one of text, chart, json, yaml, csv)\n", outBuf.String())
+}
+
+// TestNewRenderer covers the accepted format names.
+func TestNewRenderer(t *testing.T) {
+	for format, want := range map[string]Renderer{
+		"": TextRenderer{}, "TEXT": TextRenderer{}, "json": JSONRenderer{},
+		"chart": ChartRenderer{}, "yml": YAMLRenderer{}, "csv": CSVRenderer{},
+	} {
+		r, err := NewRenderer(format)
+		require.NoError(t, err, format)
+		assert.Equal(t, want, r, format)
+	}
+}
+
+// TestTextRenderer_Art checks pictures, icons and temperature colors are
+// added only when asked for, and that plain writers get plain text.
+func TestTextRenderer_Art(t *testing.T) {
+	fixClock(t, time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC))
+	InitProvider(&fakeProvider{currentData: &WeatherData{Description: "Sunny", Temperature: 21, WindSpeed: 10, WindDir: "W"}})
+	report, err := FetchReport(context.Background(), geo.ParseLocation("london"), 0, "")
+	require.NoError(t, err)
+	report.Units = units.Metric
+
+	var plain bytes.Buffer
+	require.NoError(t, ForTerminal(TextRenderer{}, &plain).Render(&plain, report))
+	assert.Contains(t, plain.String(), "Description : Sunny")
+	assert.NotContains(t, plain.String(), "\x1b[")
+
+	var art bytes.Buffer
+	require.NoError(t, TextRenderer{Art: true}.Render(&art, report))
+	assert.Contains(t, art.String(), "    \\   /     ☀️ Sunny\n     .-.      21°C\n  ― (   ) ―   10 km/h W\n")
+	assert.NotContains(t, art.String(), "Description :")
+	assert.NotContains(t, art.String(), "\x1b[")
+
+	var color bytes.Buffer
+	require.NoError(t, TextRenderer{Art: true, Color: true}.Render(&color, report))
+	assert.Contains(t, color.String(), "\x1b[38;5;220m21°C\x1b[0m")
+
+	assert.Equal(t, JSONRenderer{}, ForTerminal(JSONRenderer{}, &plain))
+}
@@ -0,0 +1,34 @@
+package weather
+
+import (
+	"context"
+
+	"weatherapp/internal/geo"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the package's spans.
+const tracerName = "weatherapp/internal/weather"
+
+// startSpan starts the span name as a child of any span in ctx.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan marks span failed if err is set, and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// locationAttr identifies the place a span asked about.
+func locationAttr(loc geo.Location) attribute.KeyValue {
+	return attribute.String("weather.location", loc.QueryString())
+}
@@ -1,66 +1,133 @@
-package weather
-
-import (
-	"encoding/json"
-	"fmt"
-	"net/http"
-	"os"
-)
-
-type WeatherstackProvider struct {
-	apiKey string
-}
-
-// NewWeatherstackProvider creates a WeatherstackProvider.
-func NewWeatherstackProvider() *WeatherstackProvider {
-	return &WeatherstackProvider{apiKey: os.Getenv("WEATHERSTACK_API_KEY")}
-}
-
-// Current fetches current weather from Weatherstack.
-func (w *WeatherstackProvider) Current(location string) (*WeatherData, error) {
-	url := fmt.Sprintf("http://api.weatherstack.com/current?access_key=%s&query=%s", w.apiKey, location)
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var r struct {
-		Current struct {
-			Temperature  float64  `json:"temperature"`
-			FeelsLike    float64  `json:"feelslike"`
-			Humidity     float64  `json:"humidity"`
-			WindSpeed    float64  `json:"wind_speed"`
-			WindDir      string   `json:"wind_dir"`
-			Descriptions []string `json:"weather_descriptions"`
-		} `json:"current"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
-		return nil, err
-	}
-	cd := r.Current
-	return &WeatherData{
-		Description: cd.Descriptions[0],
-		Temperature: cd.Temperature,
-		FeelsLike:   cd.FeelsLike,
-		Humidity:    cd.Humidity,
-		WindSpeed:   cd.WindSpeed,
-		WindDir:     cd.WindDir,
-	}, nil
-}
-
-// Forecast simulates a multi-day forecast (Weatherstack free tier lack real forecast)
-func (w *WeatherstackProvider) Forecast(location string, days int) ([]WeatherData, error) {
-	var out []WeatherData
-	for i := 1; i <= days; i++ {
-		out = append(out, WeatherData{
-			Description: "Partly Cloudy",
-			Temperature: 20 + float64(i%5),
-			FeelsLike:   20 + float64(i%3),
-			Humidity:    70,
-			WindSpeed:   10,
-			WindDir:     "NW",
-		})
-	}
-	return out, nil
-}
+package weather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"weatherapp/internal/config"
+	"weatherapp/internal/geo"
+	"weatherapp/internal/units"
+)
+
+type WeatherstackProvider struct {
+	apiKey   string
+	baseURL  string
+	language string
+	client   *http.Client
+}
+
+// NewWeatherstackProvider creates a WeatherstackProvider with cfg's account
+// and provider timeout.
+func NewWeatherstackProvider(cfg *config.Config) *WeatherstackProvider {
+	return &WeatherstackProvider{
+		apiKey:  string(cfg.Weatherstack.APIKey),
+		baseURL: cfg.Weatherstack.BaseURL,
+		client:  &http.Client{Timeout: cfg.Timeouts.Provider.Duration},
+	}
+}
+
+// CheckCredentials reports whether an API key is configured.
+func (w *WeatherstackProvider) CheckCredentials() error {
+	if w.apiKey == "" {
+		return errors.New("no Weatherstack API key; set weatherstack.api_key or WEATHERSTACK_API_KEY")
+	}
+	return nil
+}
+
+// WithLanguage returns a copy of the provider whose descriptions are in
+// lang. Weatherstack only honors this on paid plans and otherwise answers
+// in English.
+func (w *WeatherstackProvider) WithLanguage(lang string) WeatherProvider {
+	c := *w
+	c.language = lang
+	return &c
+}
+
+// Current fetches current weather from Weatherstack, which accepts names,
+// "lat,lon" and postal codes in the same query parameter.
+func (w *WeatherstackProvider) Current(ctx context.Context, loc geo.Location) (_ *WeatherData, err error) {
+	ctx, span := startSpan(ctx, "weatherstack.current", locationAttr(loc))
+	defer func() { endSpan(span, err) }()
+	currentURL := fmt.Sprintf("%s/current?access_key=%s&query=%s",
+		w.baseURL, w.apiKey, url.QueryEscape(loc.QueryString()))
+	if w.language != "" {
+		currentURL += "&language=" + url.QueryEscape(w.language)
+	}
+
+	var r struct {
+		Current struct {
+			Temperature  float64  `json:"temperature"`
+			FeelsLike    float64  `json:"feelslike"`
+			Humidity     float64  `json:"humidity"`
+			WindSpeed    float64  `json:"wind_speed"`
+			WindDir      string   `json:"wind_dir"`
+			Pressure     float64  `json:"pressure"`
+			Precip       float64  `json:"precip"`
+			Visibility   float64  `json:"visibility"`
+			WeatherCode  int      `json:"weather_code"`
+			Descriptions []string `json:"weather_descriptions"`
+		} `json:"current"`
+		Location struct {
+			TimezoneID     string `json:"timezone_id"`
+			LocaltimeEpoch int64  `json:"localtime_epoch"`
+		} `json:"location"`
+		Error struct {
+			Info string `json:"info"`
+		} `json:"error"`
+	}
+	if err := getJSON(ctx, w.client, "weatherstack", "current", currentURL, &r); err != nil {
+		return nil, err
+	}
+	if r.Error.Info != "" {
+		return nil, fmt.Errorf("weatherstack: %s", r.Error.Info)
+	}
+	cd := r.Current
+	description := ""
+	if len(cd.Descriptions) > 0 {
+		description = cd.Descriptions[0]
+	}
+	var observed time.Time
+	if r.Location.LocaltimeEpoch > 0 {
+		observed = time.Unix(r.Location.LocaltimeEpoch, 0)
+	}
+	// Weatherstack defaults to metric units: °C, km/h, mb, mm and km.
+	return &WeatherData{
+		Description:   description,
+		Condition:     WeatherstackCondition(cd.WeatherCode, description),
+		Temperature:   units.Temperature(cd.Temperature),
+		FeelsLike:     units.Temperature(cd.FeelsLike),
+		Humidity:      cd.Humidity,
+		WindSpeed:     units.Speed(cd.WindSpeed),
+		WindDir:       cd.WindDir,
+		Pressure:      units.Pressure(cd.Pressure),
+		Precipitation: units.Precipitation(cd.Precip),
+		Visibility:    units.Distance(cd.Visibility),
+		Time:          observed,
+		TimeZone:      r.Location.TimezoneID,
+	}, nil
+}
+
+// Forecast simulates a multi-day forecast (Weatherstack free tier lack real forecast)
+func (w *WeatherstackProvider) Forecast(ctx context.Context, loc geo.Location, days int) ([]WeatherData, error) {
+	var out []WeatherData
+	for i := 1; i <= days; i++ {
+		temp := units.Temperature(20 + i%5)
+		out = append(out, WeatherData{
+			Description:       "Partly Cloudy",
+			Condition:         ConditionPartlyCloudy,
+			Temperature:       temp,
+			FeelsLike:         units.Temperature(20 + i%3),
+			Humidity:          70,
+			WindSpeed:         10,
+			WindDir:           "NW",
+			MinTemp:           temp - 6,
+			MaxTemp:           temp,
+			PrecipProbability: 20,
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,66 @@
+package weather
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"weatherapp/internal/geo"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWeatherstackCurrent checks each Location kind is sent as the query and
+// that API errors are surfaced.
+func TestWeatherstackCurrent(t *testing.T) {
+	var gotQuery string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query().Get("query")
+		if gotQuery == "nowhere" {
+			w.Write([]byte(`{"success":false,"error":{"code":615,"info":"Your API request failed."}}`))
+			return
+		}
+		w.Write([]byte(`{"current":{"temperature":21,"feelslike":20,"humidity":40,"wind_speed":7,"wind_dir":"W","weather_code":113,"weather_descriptions":["Sunny"]}}`))
+	}))
+	defer srv.Close()
+	ws := &WeatherstackProvider{apiKey: "k", baseURL: srv.URL}
+
+	cases := map[string]string{
+		"New York":     "New York",
+		"40.71,-74.01": "40.7100,-74.0100",
+		"10001 us":     "10001,US",
+	}
+	for input, want := range cases {
+		d, err := ws.Current(context.Background(), geo.ParseLocation(input))
+		require.NoError(t, err, input)
+		assert.Equal(t, want, gotQuery)
+		assert.Equal(t, "Sunny", d.Description)
+		assert.Equal(t, ConditionClear, d.Condition)
+	}
+
+	_, err := ws.Current(context.Background(), geo.ParseLocation("nowhere"))
+	assert.EqualError(t, err, "weatherstack: Your API request failed.")
+}
+
+// TestWeatherstackLanguage checks a localized copy asks for descriptions in
+// its language and leaves the original untouched.
+func TestWeatherstackLanguage(t *testing.T) {
+	var gotLanguage string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotLanguage = r.URL.Query().Get("language")
+		w.Write([]byte(`{"current":{"temperature":21,"weather_code":113,"weather_descriptions":["Sonnig"]}}`))
+	}))
+	defer srv.Close()
+	ws := &WeatherstackProvider{apiKey: "k", baseURL: srv.URL}
+
+	d, err := ws.WithLanguage("de").Current(context.Background(), geo.ParseLocation("Berlin"))
+	require.NoError(t, err)
+	assert.Equal(t, "de", gotLanguage)
+	assert.Equal(t, "Sonnig", d.Description)
+
+	_, err = ws.Current(context.Background(), geo.ParseLocation("Berlin"))
+	require.NoError(t, err)
+	assert.Empty(t, gotLanguage)
+}
@@ -0,0 +1,394 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"weatherapp/internal/auth"
+	"weatherapp/internal/geo"
+	"weatherapp/internal/i18n"
+	"weatherapp/internal/storage"
+	"weatherapp/internal/units"
+	"weatherapp/internal/user"
+	"weatherapp/internal/weather"
+	"weatherapp/models"
+)
+
+func (s *Server) index(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.sessionUser(r); ok {
+		http.Redirect(w, r, "/weather", http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// loginView is the data of the login and register forms.
+type loginView struct {
+	Next   string
+	UserID string
+	Name   string
+}
+
+func (s *Server) loginForm(w http.ResponseWriter, r *http.Request) {
+	s.render(w, r, http.StatusOK, "login", page{Title: "Log in", Data: loginView{Next: r.URL.Query().Get("next")}})
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	name, password := strings.TrimSpace(r.FormValue("name")), r.FormValue("password")
+	next := r.FormValue("next")
+	u, err := auth.Authenticate(r.Context(), name, password)
+	if err != nil && !errors.Is(err, auth.ErrInvalidCredentials) {
+		slog.ErrorContext(r.Context(), "loading users", "error", err)
+		s.render(w, r, http.StatusInternalServerError, "login", page{
+			Title: "Log in", Error: "Logging in failed; please try again.",
+			Data: loginView{Next: next, Name: name},
+		})
+		return
+	}
+	if err != nil {
+		s.render(w, r, http.StatusUnauthorized, "login", page{
+			Title: "Log in", Error: "Invalid name or password.",
+			Data: loginView{Next: next, Name: name},
+		})
+		return
+	}
+	s.startSession(w, r, u.UserID)
+	dest := "/weather"
+	if u.Preferences.Location == "" {
+		dest = "/preferences"
+	}
+	http.Redirect(w, r, localPath(next, dest), http.StatusSeeOther)
+}
+
+func (s *Server) registerForm(w http.ResponseWriter, r *http.Request) {
+	s.render(w, r, http.StatusOK, "register", page{Title: "Register", Data: loginView{}})
+}
+
+func (s *Server) register(w http.ResponseWriter, r *http.Request) {
+	userID := strings.TrimSpace(r.FormValue("user_id"))
+	name := strings.TrimSpace(r.FormValue("name"))
+	password := r.FormValue("password")
+	fail := func(status int, msg string) {
+		s.render(w, r, status, "register", page{Title: "Register", Error: msg, Data: loginView{UserID: userID, Name: name}})
+	}
+	if err := auth.ValidateRegistration(userID, name, password); err != nil {
+		fail(http.StatusBadRequest, err.Error())
+		return
+	}
+	if password != r.FormValue("confirm") {
+		fail(http.StatusBadRequest, "The passwords do not match.")
+		return
+	}
+	err := auth.RegisterUser(r.Context(), userID, name, password)
+	switch {
+	case errors.Is(err, auth.ErrUserExists):
+		fail(http.StatusConflict, "That user ID or name is already taken.")
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "saving user", "user", userID, "error", err)
+		fail(http.StatusInternalServerError, "Saving your account failed; please try again.")
+		return
+	}
+	s.startSession(w, r, userID)
+	http.Redirect(w, r, "/preferences", http.StatusSeeOther)
+}
+
+func (s *Server) logout(w http.ResponseWriter, r *http.Request) {
+	endSession(w)
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// weatherView is the current-weather card, the forecast table and any
+// alerts for one location.
+type weatherView struct {
+	Query    string
+	Location string
+	Current  *cardView
+	Forecast *forecastView
+	Alerts   []weather.Alert
+}
+
+// cardView is one set of conditions formatted in the user's units; the
+// web counterpart of the CLI's detailed view.
+type cardView struct {
+	Time          string
+	Condition     string
+	Icon          string
+	Description   string
+	Temperature   string
+	FeelsLike     string
+	Humidity      string
+	Wind          string
+	Pressure      string
+	Precipitation string
+	Visibility    string
+	Sunrise       string
+	Sunset        string
+}
+
+// forecastView is the forecast table, one row per local date.
+type forecastView struct {
+	Period string
+	Days   []dayView
+}
+
+type dayView struct {
+	Date          string
+	Condition     string
+	Icon          string
+	Description   string
+	Temperature   string
+	Low           string
+	High          string
+	Precipitation string
+	Wind          string
+}
+
+func (s *Server) weather(w http.ResponseWriter, r *http.Request, u models.User) {
+	query := strings.TrimSpace(r.URL.Query().Get("location"))
+	prefs := u.Preferences
+	if i, err := strconv.Atoi(r.URL.Query().Get("saved")); err == nil && i >= 0 && i < len(prefs.SavedLocations) {
+		saved := prefs.SavedLocations[i]
+		prefs.Location, prefs.Coords = saved.Location, saved.Coords
+	}
+	p := page{Title: "Weather", User: &u}
+	loc, sys, err := weather.RequestTarget(prefs, query, "")
+	if err != nil {
+		if errors.Is(err, weather.ErrNoLocation) {
+			http.Redirect(w, r, "/preferences", http.StatusSeeOther)
+			return
+		}
+		p.Error = err.Error()
+		p.Data = weatherView{Query: query}
+		s.render(w, r, http.StatusBadRequest, "weather", p)
+		return
+	}
+
+	current, err := weather.FetchReport(r.Context(), loc, 0, u.Preferences.Language)
+	if err != nil {
+		p.Error = fmt.Sprintf("Could not get the weather for %s: %v", loc, err)
+		p.Data = weatherView{Query: query}
+		s.render(w, r, http.StatusBadGateway, "weather", p)
+		return
+	}
+	view := weatherView{
+		Query:    query,
+		Location: current.Location.String(),
+		Current:  newCard(current.Data[0], sys),
+		Alerts:   current.Alerts,
+	}
+	if days := weather.ForecastDays(u.Preferences.Forecast); days > 0 {
+		forecast, err := weather.FetchReport(r.Context(), loc, days, u.Preferences.Language)
+		if err != nil {
+			p.Error = fmt.Sprintf("Could not get the forecast: %v", err)
+		} else {
+			view.Forecast = newForecast(forecast, sys, days)
+		}
+	}
+	p.Data = view
+	s.render(w, r, http.StatusOK, "weather", p)
+}
+
+func newCard(d weather.WeatherData, sys units.System) *cardView {
+	c := &cardView{
+		Time:        d.Time.Format("Mon 02 Jan 15:04 MST"),
+		Condition:   d.Condition.String(),
+		Icon:        d.Condition.Icon(),
+		Description: d.Description,
+		Temperature: sys.FormatTemperature(d.Temperature),
+		FeelsLike:   sys.FormatTemperature(d.FeelsLike),
+		Humidity:    fmt.Sprintf("%.0f%%", d.Humidity),
+		Wind:        strings.TrimSpace(sys.FormatSpeed(d.WindSpeed) + " " + d.WindDir),
+	}
+	if d.Pressure > 0 {
+		c.Pressure = sys.FormatPressure(d.Pressure)
+	}
+	if d.Precipitation > 0 {
+		c.Precipitation = sys.FormatPrecipitation(d.Precipitation)
+	}
+	if d.Visibility > 0 {
+		c.Visibility = sys.FormatDistance(d.Visibility)
+	}
+	if !d.Sunrise.IsZero() && !d.Sunset.IsZero() {
+		c.Sunrise, c.Sunset = d.Sunrise.Format("15:04"), d.Sunset.Format("15:04")
+	}
+	return c
+}
+
+func newForecast(r weather.Report, sys units.System, days int) *forecastView {
+	f := &forecastView{Period: fmt.Sprintf("%d days", days)}
+	for _, d := range r.Data {
+		row := dayView{
+			Date:        d.Time.Format("Mon 02 Jan"),
+			Condition:   d.Condition.String(),
+			Icon:        d.Condition.Icon(),
+			Description: d.Description,
+			Temperature: sys.FormatTemperature(d.Temperature),
+			Low:         sys.FormatTemperature(d.MinTemp),
+			High:        sys.FormatTemperature(d.MaxTemp),
+			Wind:        sys.FormatSpeed(d.WindSpeed),
+		}
+		if d.Precipitation > 0 {
+			row.Precipitation = sys.FormatPrecipitation(d.Precipitation)
+		}
+		f.Days = append(f.Days, row)
+	}
+	return f
+}
+
+// preferencesView is the preferences form. Choices lists candidate places
+// when the location was ambiguous or not recognized.
+type preferencesView struct {
+	Location  string
+	Profile   string
+	Overrides string
+	Verbosity string
+	Forecast  string
+	Output    string
+	Language  string
+	Profiles  []string
+	Formats   []string
+	Languages []string
+	Choices   []choiceView
+	Saved     bool
+}
+
+// choiceView is one candidate place; Value encodes "lat,lon;name".
+type choiceView struct {
+	Label string
+	Value string
+}
+
+func newPreferencesView(p models.Preferences) preferencesView {
+	var overrides []string
+	for _, q := range units.Quantities {
+		if unit, ok := p.UnitOverrides[q]; ok {
+			overrides = append(overrides, q+"="+unit)
+		}
+	}
+	return preferencesView{
+		Location:  p.Location,
+		Profile:   p.Unit,
+		Overrides: strings.Join(overrides, ", "),
+		Verbosity: p.Verbosity,
+		Forecast:  p.Forecast,
+		Output:    p.Output,
+		Language:  p.Language,
+		Profiles:  []string{"metric", "imperial", "uk", "si"},
+		Formats:   weather.Formats,
+		Languages: i18n.Languages,
+	}
+}
+
+func (s *Server) preferencesForm(w http.ResponseWriter, r *http.Request, u models.User) {
+	view := newPreferencesView(u.Preferences)
+	view.Saved = r.URL.Query().Get("saved") == "1"
+	s.render(w, r, http.StatusOK, "preferences", page{Title: "Preferences", User: &u, Data: view})
+}
+
+// updatePreferences validates the form like the CLI prompts do, then
+// resolves the location: a chosen candidate is stored with its
+// coordinates, a single geocoder match is taken as is, and several matches
+// or none are offered back as choices.
+func (s *Server) updatePreferences(w http.ResponseWriter, r *http.Request, u models.User) {
+	location := strings.TrimSpace(r.FormValue("location"))
+	unit := r.FormValue("unit")
+	if o := strings.TrimSpace(r.FormValue("overrides")); o != "" {
+		unit += ", " + o
+	}
+	verbosity, forecast, output, language := r.FormValue("verbosity"), r.FormValue("forecast"), r.FormValue("output"), r.FormValue("language")
+	changes := user.PreferenceChanges{Location: &location, Unit: &unit, Verbosity: &verbosity, Forecast: &forecast, Output: &output, Language: &language}
+
+	next := u.Preferences
+	err := changes.Apply(&next)
+	view := newPreferencesView(next)
+	if err != nil {
+		view.Location, view.Profile, view.Overrides = location, r.FormValue("unit"), r.FormValue("overrides")
+		view.Verbosity, view.Forecast, view.Output, view.Language = verbosity, forecast, output, language
+		s.render(w, r, http.StatusBadRequest, "preferences", page{Title: "Preferences", User: &u, Error: err.Error(), Data: view})
+		return
+	}
+
+	if choice := r.FormValue("place"); choice != "" {
+		if coords, name, ok := parseChoice(choice); ok {
+			user.SetLocation(&next, name, coords)
+		}
+	} else if location == u.Preferences.Location {
+		user.SetLocation(&next, location, u.Preferences.Coords)
+	} else if choices, place, ok := resolve(r.Context(), location); ok {
+		user.SetLocation(&next, place.String(), place.Coordinates())
+	} else if len(choices) > 0 {
+		view.Choices = choices
+		s.render(w, r, http.StatusOK, "preferences", page{Title: "Preferences", User: &u, Data: view})
+		return
+	}
+
+	u.Preferences = next
+	if err := storage.UpdateUser(r.Context(), u); err != nil {
+		slog.ErrorContext(r.Context(), "saving preferences", "user", u.UserID, "error", err)
+		s.render(w, r, http.StatusInternalServerError, "preferences", page{Title: "Preferences", User: &u, Error: "Saving your preferences failed.", Data: view})
+		return
+	}
+	http.Redirect(w, r, "/preferences?saved=1", http.StatusSeeOther)
+}
+
+// resolve returns the place a location means when geo.Candidates is sure
+// of it, or the candidates to choose from.
+func resolve(ctx context.Context, location string) ([]choiceView, geo.Place, bool) {
+	places, exact := geo.Candidates(ctx, location)
+	if exact {
+		return nil, places[0], true
+	}
+	var choices []choiceView
+	for _, p := range places {
+		choices = append(choices, choiceView{
+			Label: fmt.Sprintf("%s (%.2f, %.2f)", p, p.Lat, p.Lon),
+			Value: geo.FormatCoords(p.Lat, p.Lon) + ";" + p.String(),
+		})
+	}
+	return choices, geo.Place{}, false
+}
+
+func parseChoice(v string) (*models.Coordinates, string, bool) {
+	coords, name, ok := strings.Cut(v, ";")
+	lat, lon, valid := geo.ParseCoords(coords)
+	if !ok || !valid || strings.TrimSpace(name) == "" {
+		return nil, "", false
+	}
+	return &models.Coordinates{Lat: lat, Lon: lon}, strings.TrimSpace(name), true
+}
+
+// savedView is one row of the saved-locations overview.
+type savedView struct {
+	Index       int
+	Name        string
+	Location    string
+	Default     bool
+	Description string
+	Temperature string
+	Error       string
+}
+
+func (s *Server) locations(w http.ResponseWriter, r *http.Request, u models.User) {
+	sys, err := units.FromPreferences(u.Preferences)
+	if err != nil {
+		sys = units.Metric
+	}
+	rows := []savedView{}
+	for i, l := range u.Preferences.SavedLocations {
+		row := savedView{Index: i, Name: l.Name, Location: l.Location, Default: l.Default}
+		if report, err := weather.FetchReport(r.Context(), geo.FromSaved(l), 0, u.Preferences.Language); err != nil {
+			row.Error = err.Error()
+		} else {
+			row.Description = report.Data[0].Description
+			row.Temperature = sys.FormatTemperature(report.Data[0].Temperature)
+		}
+		rows = append(rows, row)
+	}
+	s.render(w, r, http.StatusOK, "locations", page{Title: "Saved locations", User: &u, Data: rows})
+}
@@ -0,0 +1,177 @@
+// Package web serves a small server-rendered dashboard: login and
+// registration, the preferences form, the current-weather card and
+// forecast table, and the saved-locations overview. Templates and static
+// assets are embedded, so the dashboard ships inside the binary.
+package web
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"weatherapp/internal/server"
+	"weatherapp/models"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+//go:embed static
+var staticFS embed.FS
+
+// sessionCookie holds the bearer token of a logged-in browser.
+const sessionCookie = "weatherapp_session"
+
+// pages are the templates rendered inside layout.html.
+var pages = []string{"login", "register", "weather", "preferences", "locations"}
+
+// Server holds the dependencies of the dashboard handlers.
+type Server struct {
+	Tokens    *server.Tokens
+	templates map[string]*template.Template
+}
+
+// New creates a Server whose sessions are tokens issued by tokens, so a
+// browser session is interchangeable with an API login.
+func New(tokens *server.Tokens) *Server {
+	s := &Server{Tokens: tokens, templates: map[string]*template.Template{}}
+	for _, name := range pages {
+		s.templates[name] = template.Must(template.New(name).Funcs(funcs).
+			ParseFS(templateFS, "templates/layout.html", "templates/"+name+".html"))
+	}
+	return s
+}
+
+// Handler returns the HTTP handler serving every dashboard route.
+func (s *Server) Handler() http.Handler {
+	static, _ := fs.Sub(staticFS, "static")
+	mux := http.NewServeMux()
+	mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServerFS(static)))
+	mux.HandleFunc("GET /{$}", s.index)
+	mux.HandleFunc("GET /login", s.loginForm)
+	mux.HandleFunc("POST /login", s.login)
+	mux.HandleFunc("GET /register", s.registerForm)
+	mux.HandleFunc("POST /register", s.register)
+	mux.HandleFunc("POST /logout", s.logout)
+	mux.Handle("GET /weather", s.authenticated(s.weather))
+	mux.Handle("GET /preferences", s.authenticated(s.preferencesForm))
+	mux.Handle("POST /preferences", s.authenticated(s.updatePreferences))
+	mux.Handle("GET /locations", s.authenticated(s.locations))
+	return sameOrigin(mux)
+}
+
+var funcs = template.FuncMap{
+	"date": func(t time.Time, layout string) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(layout)
+	},
+}
+
+// page is the data every template gets; Data holds the page's own view.
+type page struct {
+	Title string
+	User  *models.User
+	Error string
+	Data  any
+}
+
+// render executes a page template into a buffer first, so a template
+// error becomes a 500 rather than a half-written page.
+func (s *Server) render(w http.ResponseWriter, r *http.Request, status int, name string, p page) {
+	var buf bytes.Buffer
+	if err := s.templates[name].ExecuteTemplate(&buf, "layout", p); err != nil {
+		slog.ErrorContext(r.Context(), "rendering page", "page", name, "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if _, err := buf.WriteTo(w); err != nil {
+		slog.DebugContext(r.Context(), "writing response", "error", err)
+	}
+}
+
+// authedHandler is a handler for a request from a logged-in browser.
+type authedHandler func(w http.ResponseWriter, r *http.Request, u models.User)
+
+// authenticated sends browsers without a valid session to the login page,
+// remembering where they were going.
+func (s *Server) authenticated(next authedHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, ok := s.sessionUser(r)
+		if !ok {
+			http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+			return
+		}
+		next(w, r, u)
+	})
+}
+
+// sessionUser returns the User whose token is in the session cookie.
+func (s *Server) sessionUser(r *http.Request) (models.User, bool) {
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return models.User{}, false
+	}
+	userID, err := s.Tokens.Verify(c.Value)
+	if err != nil {
+		return models.User{}, false
+	}
+	u, ok, err := server.FindUser(r.Context(), userID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "loading user", "user", userID, "error", err)
+		return models.User{}, false
+	}
+	return u, ok
+}
+
+// startSession logs the browser in as userID.
+func (s *Server) startSession(w http.ResponseWriter, r *http.Request, userID string) {
+	token, expires := s.Tokens.Issue(userID)
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func endSession(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: "", Path: "/", MaxAge: -1, HttpOnly: true})
+}
+
+// sameOrigin rejects form posts sent from other sites. Together with the
+// SameSite session cookie this guards against cross-site request forgery.
+func sameOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			if origin := r.Header.Get("Origin"); origin != "" {
+				u, err := url.Parse(origin)
+				if err != nil || u.Host != r.Host {
+					http.Error(w, "cross-origin request refused", http.StatusForbidden)
+					return
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// localPath returns next if it is a path on this site, or fallback, so the
+// login redirect cannot be pointed elsewhere.
+func localPath(next, fallback string) string {
+	if next == "/" || len(next) > 1 && next[0] == '/' && next[1] != '/' && next[1] != '\\' {
+		return next
+	}
+	return fallback
+}
@@ -0,0 +1,281 @@
+package web
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"weatherapp/internal/geo"
+	"weatherapp/internal/server"
+	"weatherapp/internal/storage"
+	"weatherapp/internal/weather"
+	"weatherapp/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProvider returns fixed conditions named after the location.
+type fakeProvider struct{}
+
+func (fakeProvider) Current(ctx context.Context, loc geo.Location) (*weather.WeatherData, error) {
+	return &weather.WeatherData{Description: "Sunny <b>" + loc.String() + "</b>", Temperature: 20, Humidity: 40, WindSpeed: 18.52}, nil
+}
+
+func (fakeProvider) Forecast(ctx context.Context, loc geo.Location, days int) ([]weather.WeatherData, error) {
+	data := make([]weather.WeatherData, days)
+	for i := range data {
+		data[i] = weather.WeatherData{Description: "Cloudy", Temperature: 15, MinTemp: 10, MaxTemp: 18}
+	}
+	return data, nil
+}
+
+// browser is a cookie-keeping client for a test dashboard.
+type browser struct {
+	t      *testing.T
+	srv    *httptest.Server
+	client *http.Client
+	users  []models.User
+}
+
+func newBrowser(t *testing.T) *browser {
+	b := &browser{t: t}
+	origLoad, origGet, origSave, origUpdate := storage.LoadUsers, storage.GetUserByID, storage.CreateUser, storage.UpdateUser
+	origGeocoder := geo.Active()
+	t.Cleanup(func() {
+		storage.LoadUsers, storage.GetUserByID, storage.CreateUser, storage.UpdateUser = origLoad, origGet, origSave, origUpdate
+		geo.InitGeocoder(origGeocoder)
+	})
+	storage.LoadUsers = func(context.Context) ([]models.User, error) { return b.users, nil }
+	storage.GetUserByID = func(ctx context.Context, userID string) (*models.User, error) {
+		users, err := storage.LoadUsers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			if u.UserID == userID {
+				return &u, nil
+			}
+		}
+		return nil, storage.ErrUserNotFound
+	}
+	storage.CreateUser = func(_ context.Context, u models.User) error {
+		for _, existing := range b.users {
+			if existing.UserID == u.UserID || existing.Name == u.Name {
+				return storage.ErrUserExists
+			}
+		}
+		b.users = append(b.users, u)
+		return nil
+	}
+	storage.UpdateUser = func(_ context.Context, u models.User) error {
+		for i := range b.users {
+			if b.users[i].UserID == u.UserID {
+				b.users[i] = u
+			}
+		}
+		return nil
+	}
+	weather.InitProvider(fakeProvider{})
+	geo.InitGeocoder(nil)
+
+	b.srv = httptest.NewServer(New(server.NewTokens([]byte("test-secret"), time.Hour)).Handler())
+	t.Cleanup(b.srv.Close)
+	jar, _ := cookiejar.New(nil)
+	b.client = &http.Client{Jar: jar}
+	return b
+}
+
+// get fetches path, following redirects, and returns the final path,
+// status and body.
+func (b *browser) get(path string) (string, int, string) {
+	b.t.Helper()
+	resp, err := b.client.Get(b.srv.URL + path)
+	require.NoError(b.t, err)
+	return b.read(resp)
+}
+
+// post submits a form to path, following redirects.
+func (b *browser) post(path string, form url.Values) (string, int, string) {
+	b.t.Helper()
+	resp, err := b.client.PostForm(b.srv.URL+path, form)
+	require.NoError(b.t, err)
+	return b.read(resp)
+}
+
+func (b *browser) read(resp *http.Response) (string, int, string) {
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(b.t, err)
+	return resp.Request.URL.RequestURI(), resp.StatusCode, string(body)
+}
+
+func (b *browser) register() {
+	b.t.Helper()
+	path, status, _ := b.post("/register", url.Values{
+		"user_id": {"u1"}, "name": {"asha"}, "password": {"correct horse"}, "confirm": {"correct horse"},
+	})
+	require.Equal(b.t, http.StatusOK, status)
+	require.Equal(b.t, "/preferences", path)
+}
+
+// TestLoginFlow covers redirects to the login page, registration, logout
+// and logging back in to the page first asked for.
+func TestLoginFlow(t *testing.T) {
+	b := newBrowser(t)
+	path, status, body := b.get("/weather")
+	assert.Equal(t, "/login?next=%2Fweather", path)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `name="next" value="/weather"`)
+
+	_, status, body = b.post("/register", url.Values{
+		"user_id": {"u1"}, "name": {"asha"}, "password": {"correct horse"}, "confirm": {"different"},
+	})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, body, "The passwords do not match.")
+	assert.Contains(t, body, `value="asha"`)
+
+	b.register()
+	assert.Len(t, b.users, 1)
+	_, status, body = b.post("/register", url.Values{
+		"user_id": {"u2"}, "name": {"asha"}, "password": {"correct horse"}, "confirm": {"correct horse"},
+	})
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Contains(t, body, "already taken")
+
+	path, _, _ = b.post("/logout", nil)
+	assert.Equal(t, "/login", path)
+	path, _, _ = b.get("/preferences")
+	assert.True(t, strings.HasPrefix(path, "/login"), path)
+
+	_, status, body = b.post("/login", url.Values{"name": {"asha"}, "password": {"wrong"}})
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Contains(t, body, "Invalid name or password.")
+
+	b.users[0].Preferences.Location = "Leeds"
+	path, status, _ = b.post("/login", url.Values{"name": {"asha"}, "password": {"correct horse"}, "next": {"/locations"}})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "/locations", path)
+
+	path, _, _ = b.post("/login", url.Values{"name": {"asha"}, "password": {"correct horse"}, "next": {"//evil.example"}})
+	assert.Equal(t, "/weather", path)
+}
+
+// TestPreferencesForm covers validation, typo suggestions, choosing a
+// candidate place and the saved notice.
+func TestPreferencesForm(t *testing.T) {
+	b := newBrowser(t)
+	b.register()
+
+	form := url.Values{"location": {"Leeds"}, "unit": {"uk"}, "overrides": {"speed=furlongs"}, "verbosity": {"brief"}, "forecast": {"week"}, "output": {"text"}}
+	_, status, body := b.post("/preferences", form)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, body, "furlongs")
+	assert.Empty(t, b.users[0].Preferences.Location)
+
+	form.Set("overrides", "speed=kn")
+	form.Set("location", "Pariss")
+	_, status, body = b.post("/preferences", form)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "Which place did you mean?")
+	assert.Contains(t, body, "Paris, Ile-de-France, FR")
+	assert.Empty(t, b.users[0].Preferences.Location)
+
+	form.Set("place", "48.8566,2.3522;Paris, Ile-de-France, FR")
+	path, status, body := b.post("/preferences", form)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "/preferences?saved=1", path)
+	assert.Contains(t, body, "Preferences saved.")
+	prefs := b.users[0].Preferences
+	assert.Equal(t, "Paris, Ile-de-France, FR", prefs.Location)
+	require.NotNil(t, prefs.Coords)
+	assert.Equal(t, 48.8566, prefs.Coords.Lat)
+	assert.Equal(t, "uk", prefs.Unit)
+	assert.Equal(t, map[string]string{"speed": "kn"}, prefs.UnitOverrides)
+	assert.Contains(t, body, `value="speed=kn"`)
+	assert.Contains(t, body, "<option selected>uk</option>")
+
+	form.Del("place")
+	form.Set("location", prefs.Location)
+	form.Set("forecast", "day")
+	b.post("/preferences", form)
+	assert.Equal(t, "day", b.users[0].Preferences.Forecast)
+	assert.NotNil(t, b.users[0].Preferences.Coords, "unchanged location keeps its coordinates")
+}
+
+// TestWeatherPage checks the card and forecast table use the user's units
+// and that provider text is escaped.
+func TestWeatherPage(t *testing.T) {
+	b := newBrowser(t)
+	b.register()
+	path, _, _ := b.get("/weather")
+	assert.Equal(t, "/preferences", path, "no location yet")
+
+	b.users[0].Preferences = models.Preferences{Location: "Leeds", Unit: "imperial", Forecast: "week"}
+	_, status, body := b.get("/weather")
+	require.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "68°F")
+	assert.Contains(t, body, "12 mph")
+	assert.Contains(t, body, "Sunny &lt;b&gt;Leeds&lt;/b&gt;")
+	assert.Contains(t, body, "Forecast (7 days)")
+	assert.Equal(t, 7, strings.Count(body, `<span class="icon" title="cloudy">☁️</span> Cloudy</td>`))
+
+	_, _, body = b.get("/weather?location=Oslo")
+	assert.Contains(t, body, "Sunny &lt;b&gt;Oslo&lt;/b&gt;")
+
+	_, status, body = b.get("/weather?location=auto")
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, body, "auto location is not supported")
+}
+
+// TestLocationsPage checks saved locations are listed with conditions.
+func TestLocationsPage(t *testing.T) {
+	b := newBrowser(t)
+	b.register()
+	_, _, body := b.get("/locations")
+	assert.Contains(t, body, "No saved locations yet.")
+
+	b.users[0].Preferences = models.Preferences{
+		Location: "Leeds",
+		SavedLocations: []models.SavedLocation{
+			{Name: "Home", Location: "Leeds", Default: true},
+			{Name: "Work", Location: "York"},
+		},
+	}
+	_, status, body := b.get("/locations")
+	require.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `<a href="/weather?saved=1">Work</a>`)
+	assert.Contains(t, body, "Sunny &lt;b&gt;York&lt;/b&gt;")
+	assert.Contains(t, body, "20°C")
+
+	_, _, body = b.get("/weather?saved=1")
+	assert.Contains(t, body, "Sunny &lt;b&gt;York&lt;/b&gt;")
+}
+
+// TestSameOrigin checks cross-site form posts are refused.
+func TestSameOrigin(t *testing.T) {
+	b := newBrowser(t)
+	req, _ := http.NewRequest("POST", b.srv.URL+"/login", strings.NewReader("name=a&password=b"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Origin", "https://evil.example")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+// TestStatic checks the embedded stylesheet is served.
+func TestStatic(t *testing.T) {
+	b := newBrowser(t)
+	resp, err := http.Get(b.srv.URL + "/static/style.css")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/css")
+}
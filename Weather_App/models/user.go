@@ -1,17 +1,72 @@
-package models
-
-// Preferences holds a User’s weather settings (location, unit, verbosity, forecast)
-type Preferences struct {
-	Location  string
-	Unit      string
-	Verbosity string
-	Forecast  string
-}
-
-// User represents an application user with credentials and Preferences
-type User struct {
-	UserID      string
-	Name        string
-	Password    string
-	Preferences Preferences
-}
+package models
+
+// Preferences holds a User’s weather settings (location, unit, verbosity, forecast).
+// Location is always the Default entry of SavedLocations when there are any
+// (user.SetLocation keeps the two in step), and Coords is set once Location
+// has been resolved to a single place. Unit is
+// a unit profile (metric, imperial, uk or si) and UnitOverrides replaces its
+// unit for single quantities, e.g. {"speed": "kn"}. Output is the report
+// format (text, chart, json, yaml or csv); empty means text. Language is
+// the code of the language prompts, labels and provider descriptions are
+// in, such as "de"; empty follows the environment.
+type Preferences struct {
+	Location       string
+	Coords         *Coordinates
+	Unit           string
+	UnitOverrides  map[string]string
+	Verbosity      string
+	Forecast       string
+	Output         string
+	Language       string
+	SavedLocations []SavedLocation
+}
+
+// Coordinates is a resolved latitude/longitude in decimal degrees
+type Coordinates struct {
+	Lat float64
+	Lon float64
+}
+
+// SavedLocation is one of a User's named favorite locations, e.g. "Home"
+type SavedLocation struct {
+	Name     string
+	Location string
+	Coords   *Coordinates
+	Default  bool
+}
+
+// AlertRule is a user-defined threshold checked against the forecast,
+// e.g. "temp_max > 35" for tomorrow. Temperature and wind thresholds are in
+// Unit, such as "celsius" or "km/h", the User's unit when the rule was
+// saved; rules saved without one use the User's current units. Rules on
+// the "condition" metric compare Condition, a normalized condition name
+// such as "thunder", with = or != instead of using Threshold.
+type AlertRule struct {
+	Metric    string
+	Operator  string
+	Threshold float64
+	Unit      string
+	Condition string
+	Day       int
+}
+
+// NotificationSettings holds the channels a User wants alerts delivered on,
+// and when (HH:MM in TimeZone) their daily digest is sent
+type NotificationSettings struct {
+	Email      string
+	WebhookURL string
+	File       string
+	Stdout     bool
+	DigestTime string
+	TimeZone   string
+}
+
+// User represents an application user with credentials and Preferences
+type User struct {
+	UserID        string
+	Name          string
+	Password      string
+	Preferences   Preferences
+	Notifications NotificationSettings
+	Rules         []AlertRule
+}